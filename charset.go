@@ -0,0 +1,193 @@
+package shapefile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// dbfCharsetSampleSize is the maximum number of bytes of records sampled to
+// detect a DBF's charset.
+const dbfCharsetSampleSize = 64 * 1024
+
+// dbfCharsetCandidates are the single-byte charsets considered when detecting
+// a DBF's charset, in order of preference.
+var dbfCharsetCandidates = []string{
+	"windows-1252",
+	"windows-1250",
+	"IBM850",
+	"windows-1251",
+	"IBM866",
+}
+
+// dbfCharsetSampleLength returns the number of bytes of records sampled to
+// detect the charset of the DBF with header. It is always a whole number of
+// records.
+func dbfCharsetSampleLength(header *DBFHeader) int {
+	if header.RecordSize == 0 {
+		return 0
+	}
+	records := min(header.Records, max(dbfCharsetSampleSize/header.RecordSize, 1))
+	return records * header.RecordSize
+}
+
+// detectDBFCharset returns the charset of the character fields of the records
+// in data, or the empty string if the character fields are plain ASCII.
+//
+// If all non-ASCII character fields are valid UTF-8 then the charset is
+// UTF-8. Otherwise, the character fields are decoded with each single-byte
+// candidate charset and the charset that produces the most plausible text is
+// returned.
+func detectDBFCharset(data []byte, recordSize int, fieldDescriptors []*DBFFieldDescriptor) string {
+	var fields [][]byte
+	for offset := 0; offset+recordSize <= len(data); offset += recordSize {
+		recordData := data[offset : offset+recordSize]
+		if recordData[0] != ' ' {
+			continue
+		}
+		fieldOffset := 1
+		for _, fieldDescriptor := range fieldDescriptors {
+			fieldData := recordData[fieldOffset : fieldOffset+fieldDescriptor.Length]
+			fieldOffset += fieldDescriptor.Length
+			if fieldDescriptor.Type != 'C' {
+				continue
+			}
+			if fieldData = bytes.TrimRight(fieldData, " \x00"); !isASCII(fieldData) {
+				fields = append(fields, fieldData)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	validUTF8 := true
+	for _, field := range fields {
+		if !isValidUTF8Field(field) {
+			validUTF8 = false
+			break
+		}
+	}
+	if validUTF8 {
+		return "utf-8"
+	}
+
+	bestScore := math.MinInt
+	var bestCharset string
+	for _, candidate := range dbfCharsetCandidates {
+		enc, name := lookupCharset(candidate)
+		decoder := enc.NewDecoder()
+		score := 0
+		for _, field := range fields {
+			text, err := decoder.Bytes(field)
+			if err != nil {
+				score -= len(field)
+				continue
+			}
+			score += scoreText(text)
+		}
+		if score > bestScore {
+			bestScore = score
+			bestCharset = name
+		}
+	}
+	return bestCharset
+}
+
+// isASCII returns if data contains only ASCII characters.
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidUTF8Field returns if data is valid UTF-8, allowing for a final rune
+// that was truncated by the field length.
+func isValidUTF8Field(data []byte) bool {
+	if utf8.Valid(data) {
+		return true
+	}
+	for i := len(data) - 1; i >= 0 && i > len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			return !utf8.FullRune(data[i:]) && utf8.Valid(data[:i])
+		}
+	}
+	return false
+}
+
+// scoreText returns a score of how plausible the non-ASCII characters in text
+// are. Letters score points for neighbors that are likely in the same word
+// and lose points for neighbors that are not. Control characters, invalid
+// characters, and symbols lose points.
+func scoreText(text []byte) int {
+	runes := []rune(string(text))
+	score := 0
+	for i, r := range runes {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		switch {
+		case r == utf8.RuneError || unicode.IsControl(r):
+			score -= 4
+		case unicode.IsLetter(r):
+			if i > 0 {
+				score += scoreNeighbor(r, runes[i-1])
+				if unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+					score--
+				}
+			}
+			if i < len(runes)-1 {
+				score += scoreNeighbor(r, runes[i+1])
+			}
+		case unicode.IsPunct(r) || unicode.IsSpace(r):
+			// Punctuation is plausible but not evidence for any charset.
+		default:
+			score--
+		}
+	}
+	return score
+}
+
+// scoreNeighbor returns a score of how plausible it is that the letter r is
+// next to neighbor. Non-ASCII Latin letters are usually surrounded by ASCII
+// letters, whereas letters in other scripts are usually surrounded by letters
+// in the same script.
+func scoreNeighbor(r, neighbor rune) int {
+	switch {
+	case !unicode.IsLetter(neighbor):
+		return 0
+	case unicode.Is(unicode.Latin, r):
+		if neighbor < utf8.RuneSelf {
+			return 1
+		}
+		return -1
+	case neighbor < utf8.RuneSelf:
+		return -1
+	}
+	for _, script := range []*unicode.RangeTable{unicode.Cyrillic, unicode.Greek, unicode.Hebrew, unicode.Arabic} {
+		if unicode.Is(script, r) {
+			if unicode.Is(script, neighbor) {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// peekFull returns the next n bytes of r without advancing r, or fewer bytes
+// if r ends first.
+func peekFull(r *bufio.Reader, n int) ([]byte, error) {
+	data, err := r.Peek(n)
+	if errors.Is(err, io.EOF) {
+		return data, nil
+	}
+	return data, err
+}
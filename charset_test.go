@@ -0,0 +1,152 @@
+package shapefile
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestDetectDBFCharset(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		charset         string
+		values          []string
+		expectedCharset string
+	}{
+		{
+			name:            "ascii",
+			values:          []string{"Zurich", "Geneva"},
+			expectedCharset: defaultCharset,
+		},
+		{
+			name:            "utf-8",
+			charset:         "utf-8",
+			values:          []string{"Zürich", "Genève", "Москва"},
+			expectedCharset: "utf-8",
+		},
+		{
+			name:            "windows-1252",
+			charset:         "windows-1252",
+			values:          []string{"Zürich", "Genève", "Bäretswil", "São Paulo"},
+			expectedCharset: "windows-1252",
+		},
+		{
+			name:            "windows-1250",
+			charset:         "windows-1250",
+			values:          []string{"Gdańsk", "Wrocław", "Kraków", "Źródło"},
+			expectedCharset: "windows-1250",
+		},
+		{
+			name:            "windows-1251",
+			charset:         "windows-1251",
+			values:          []string{"Москва", "Санкт-Петербург", "Новосибирск"},
+			expectedCharset: "windows-1251",
+		},
+		{
+			name:            "ibm866",
+			charset:         "IBM866",
+			values:          []string{"Москва", "Санкт-Петербург", "Новосибирск"},
+			expectedCharset: "ibm866",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldDescriptors := []*DBFFieldDescriptor{
+				{Name: "NAME", Type: 'C', Length: 32},
+				{Name: "COUNT", Type: 'N', Length: 4},
+			}
+			records := make([][]any, 0, len(tc.values))
+			for i, value := range tc.values {
+				records = append(records, []any{value, i})
+			}
+			buffer := &bytes.Buffer{}
+			assert.NoError(t, WriteDBF(buffer, fieldDescriptors, records, &WriteDBFOptions{
+				Charset: tc.charset,
+			}))
+			data := buffer.Bytes()
+			options := &ReadDBFOptions{
+				CharsetPriority: []DBFCharsetSource{DBFCharsetSourceDetect},
+			}
+
+			dbf, err := ReadDBF(bytes.NewReader(data), int64(len(data)), options)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedCharset, dbf.Charset)
+			assert.Equal(t, records, dbf.Records)
+
+			scannerDBF, err := NewScannerDBF(io.NopCloser(bytes.NewReader(data)), options)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedCharset, scannerDBF.Charset())
+			for _, expectedRecord := range records {
+				record, err := scannerDBF.Scan()
+				assert.NoError(t, err)
+				assert.Equal(t, expectedRecord, record)
+			}
+
+			reader, err := NewReader(map[string]io.ReaderAt{
+				".dbf": bytes.NewReader(data),
+			}, map[string]int64{
+				".dbf": int64(len(data)),
+			}, &ReadShapefileOptions{
+				DBF: options,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedCharset, reader.DBFCharset())
+			record, err := reader.DBFRecord(0)
+			assert.NoError(t, err)
+			assert.Equal(t, records[0], record)
+		})
+	}
+}
+
+func TestDetectDBFCharsetFallback(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, WriteDBF(buffer, []*DBFFieldDescriptor{
+		{Name: "NAME", Type: 'C', Length: 8},
+	}, [][]any{{"ASCII"}}, &WriteDBFOptions{
+		LanguageDriverID: 0xc9,
+	}))
+	data := buffer.Bytes()
+
+	dbf, err := ReadDBF(bytes.NewReader(data), int64(len(data)), &ReadDBFOptions{
+		CharsetPriority: []DBFCharsetSource{DBFCharsetSourceDetect, DBFCharsetSourceLDID},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "windows-1251", dbf.Charset)
+}
+
+func TestWriteZipWriterDetectedCharset(t *testing.T) {
+	records := [][]any{{"Москва"}, {"Новосибирск"}}
+	buffer := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buffer)
+	assert.NoError(t, WriteZipWriter(zipWriter, "cities", &Shapefile{
+		DBF: &DBF{
+			FieldDescriptors: []*DBFFieldDescriptor{
+				{Name: "NAME", Type: 'C', Length: 32},
+			},
+			Records: records,
+			Charset: "windows-1251",
+		},
+	}))
+	assert.NoError(t, zipWriter.Close())
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	shapefile, err := ReadZipReader(zipReader, &ReadShapefileOptions{
+		DBF: &ReadDBFOptions{
+			CharsetPriority: []DBFCharsetSource{DBFCharsetSourceCPG},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &CPG{Charset: "windows-1251"}, shapefile.CPG)
+	assert.Equal(t, "windows-1251", shapefile.DBF.Charset)
+	assert.Equal(t, records, shapefile.DBF.Records)
+}
+
+func TestIsValidUTF8Field(t *testing.T) {
+	assert.True(t, isValidUTF8Field([]byte("Zürich")))
+	assert.True(t, isValidUTF8Field([]byte("Zürich")[:2]))
+	assert.False(t, isValidUTF8Field([]byte("Z\xfcrich")))
+	assert.False(t, isValidUTF8Field([]byte("Z\xfc")))
+}
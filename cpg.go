@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
 )
 
 // CPG a CPG is a .cpg file.
@@ -20,7 +22,7 @@ func ReadCPG(r io.Reader, _ int64) (*CPG, error) {
 	if err != nil {
 		return nil, err
 	}
-	enc, name := charset.Lookup(strings.ToLower(string(data)))
+	enc, name := lookupCharset(strings.ToLower(string(data)))
 	if enc == nil {
 		return nil, fmt.Errorf("unknown charset '%s'", (string(data)))
 	}
@@ -42,3 +44,28 @@ func ReadCPGZipFile(zipFile *zip.File) (*CPG, error) {
 	}
 	return cpg, nil
 }
+
+// WriteCPG writes cpg to w.
+func WriteCPG(w io.Writer, cpg *CPG) error {
+	_, err := io.WriteString(w, cpg.Charset)
+	return err
+}
+
+// lookupCharset returns the encoding and canonical name of the charset with
+// label. It understands the WHATWG labels used by web browsers and the IANA
+// names of the IBM PC code pages used by DOS-era DBFs. It returns nil if the
+// charset is unknown.
+func lookupCharset(label string) (encoding.Encoding, string) {
+	if enc, name := charset.Lookup(label); enc != nil {
+		return enc, name
+	}
+	enc, err := ianaindex.IANA.Encoding(label)
+	if err != nil || enc == nil {
+		return nil, ""
+	}
+	name, err := ianaindex.IANA.Name(enc)
+	if err != nil {
+		return nil, ""
+	}
+	return enc, name
+}
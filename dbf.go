@@ -1,23 +1,21 @@
 package shapefile
 
-// FIXME support dBase version 7 files if needed, see https://www.dbase.com/Knowledgebase/INT/db7_file_fmt.htm
 // FIXME work through https://www.clicketyclick.dk/databases/xbase/format/dbf.html and add any missing features
-// FIXME add unmarshaller that unmarshalls a record into a Go struct with `dbf:"..."` tags?s
 // FIXME validate logical implementation
-// FIXME add support for memos
 
 import (
 	"archive/zip"
+	"bufio"
 	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 	"time"
 
-	"golang.org/x/net/html/charset"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
 )
@@ -25,16 +23,34 @@ import (
 const (
 	dbfHeaderLength        = 32
 	dbfFieldDescriptorSize = 32
+
+	dbf7HeaderLength        = 68
+	dbf7FieldDescriptorSize = 48
+
+	julianDayUnixEpoch = 2440588
+	millisecondsPerDay = 24 * 60 * 60 * 1000
 )
 
 var (
 	knownFieldTypes = map[byte]struct{}{
+		'+': {}, // Autoincrement
+		'0': {}, // Null flags
+		'@': {}, // Timestamp
+		'B': {}, // Double or binary memo
 		'C': {}, // Character
 		'D': {}, // Date
 		'F': {}, // Floating point binary numeric
+		'G': {}, // General
+		'I': {}, // Integer
 		'L': {}, // Binary coded decimal numeric
 		'M': {}, // Memo
 		'N': {}, // Numeric
+		'O': {}, // Double
+		'P': {}, // Picture
+		'Q': {}, // Varbinary
+		'T': {}, // DateTime
+		'V': {}, // Varchar
+		'Y': {}, // Currency
 	}
 
 	knownLogicalValues = map[byte]any{
@@ -50,15 +66,27 @@ var (
 	}
 )
 
+// DBF field flags, used by Visual FoxPro.
+const (
+	DBFFieldFlagSystem        = 0x01
+	DBFFieldFlagNullable      = 0x02
+	DBFFieldFlagBinary        = 0x04
+	DBFFieldFlagAutoIncrement = 0x0c
+)
+
 // A DBFHeader is a DBF header.
 type DBFHeader struct {
-	Version    int
-	Memo       bool
-	DBT        bool
-	LastUpdate time.Time
-	Records    int
-	HeaderSize int
-	RecordSize int
+	Version            int
+	Memo               bool
+	DBT                bool
+	FPT                bool
+	VisualFoxPro       bool
+	LastUpdate         time.Time
+	Records            int
+	HeaderSize         int
+	RecordSize         int
+	LanguageDriverID   byte
+	LanguageDriverName string // dBase level 7 only.
 }
 
 // A DBFFieldDescriptor describes a DBF field.
@@ -67,19 +95,26 @@ type DBFFieldDescriptor struct {
 	Type         byte
 	Length       int
 	DecimalCount int
+	Flags        byte
 	WorkAreaID   byte
 	SetFields    byte
+	Level7       bool // Whether the field is in a dBase level 7 table.
+	VisualFoxPro bool // Whether the field is in a Visual FoxPro table.
 }
 
-// A DBF is a dBase III PLUS table.
+// A DBF is a dBase III PLUS, dBase level 7, FoxPro, or Visual FoxPro table.
 //
 // See http://web.archive.org/web/20150323061445/http://ulisse.elettra.trieste.it/services/doc/dbase/DBFstruct.htm.
 // See https://www.clicketyclick.dk/databases/xbase/format/dbf.html.
+// See https://www.dbase.com/Knowledgebase/INT/db7_file_fmt.htm.
 type DBF struct {
 	DBFHeader
 
 	FieldDescriptors []*DBFFieldDescriptor
 	Records          [][]any
+	Charset          string // The charset used to decode character fields.
+
+	deleted []bool
 }
 
 // ReadDBFOptions are options to ReadDBF.
@@ -87,102 +122,179 @@ type ReadDBFOptions struct {
 	MaxHeaderSize    int
 	MaxRecordSize    int
 	MaxRecords       int
+	MaxMemoSize      int
 	SkipBrokenFields bool
+	ReadDeleted      bool // Read the values of deleted records instead of returning nil.
 	Charset          string
+	CharsetPriority  []DBFCharsetSource // Defaults to DefaultDBFCharsetPriority.
+	Fields           []string           // Names of the fields to read, in order. All fields are read if nil.
+	CPG              *CPG               // Used to determine the charset, if not nil.
+	DBT              *DBT               // Used to resolve memo fields of dBase tables, if not nil.
+	FPT              *FPT               // Used to resolve memo fields of FoxPro tables, if not nil.
+
+	// skipped, if not nil, contains the indexes of the records whose values
+	// are not parsed. Their values are nil.
+	skipped map[int]struct{}
 }
 
-// A DBFMemo is a DBF memo.
+// withSiblingFiles returns a shallow copy of o with CPG, DBT, and FPT set to
+// cpg, dbt, and fpt, if they are not nil. o may be nil and is not modified.
+func (o *ReadDBFOptions) withSiblingFiles(cpg *CPG, dbt *DBT, fpt *FPT) *ReadDBFOptions {
+	if cpg == nil && dbt == nil && fpt == nil {
+		return o
+	}
+	options := &ReadDBFOptions{}
+	if o != nil {
+		*options = *o
+	}
+	if cpg != nil {
+		options.CPG = cpg
+	}
+	if dbt != nil {
+		options.DBT = dbt
+	}
+	if fpt != nil {
+		options.FPT = fpt
+	}
+	return options
+}
+
+// withSkippedSHPRecords returns a shallow copy of o that does not parse the
+// values of the records whose SHP records in shp are skipped. It returns o if
+// shp is nil or has no skipped records. o may be nil and is not modified.
+func (o *ReadDBFOptions) withSkippedSHPRecords(shp *SHP) *ReadDBFOptions {
+	if shp == nil {
+		return o
+	}
+	var skipped map[int]struct{}
+	for i, record := range shp.Records {
+		if record.Skipped {
+			if skipped == nil {
+				skipped = make(map[int]struct{})
+			}
+			skipped[i] = struct{}{}
+		}
+	}
+	if skipped == nil {
+		return o
+	}
+	options := &ReadDBFOptions{}
+	if o != nil {
+		*options = *o
+	}
+	options.skipped = skipped
+	return options
+}
+
+// A DBFCharsetSource is a source of the charset of a DBF.
+type DBFCharsetSource int
+
+// DBF charset sources.
+const (
+	DBFCharsetSourceCPG     DBFCharsetSource = iota + 1 // The .cpg file.
+	DBFCharsetSourceCharset                             // ReadDBFOptions.Charset.
+	DBFCharsetSourceLDID                                // The language driver ID in the DBF header.
+	DBFCharsetSourceDetect                              // Detected from the character fields of the first records.
+)
+
+// DefaultDBFCharsetPriority is the default order in which the sources of a
+// DBF's charset are consulted. The first source that specifies a charset is
+// used. If no source specifies a charset then ISO-8859-1 is used. Charset
+// detection is opt-in: add DBFCharsetSourceDetect to
+// ReadDBFOptions.CharsetPriority to enable it.
+var DefaultDBFCharsetPriority = []DBFCharsetSource{
+	DBFCharsetSourceCPG,
+	DBFCharsetSourceCharset,
+	DBFCharsetSourceLDID,
+}
+
+// WriteDBFOptions are options to WriteDBF and NewDBFWriter.
+type WriteDBFOptions struct {
+	Charset          string
+	LanguageDriverID byte // Used as the charset if Charset is empty.
+	LastUpdate       time.Time
+}
+
+// A dbfRecordLayout is the layout of the fields to be read from each record
+// of a DBF.
+type dbfRecordLayout struct {
+	fields          []*dbfFieldLayout
+	nullFlagsOffset int
+	nullFlagsLength int
+	fpt             bool // Whether memos are in a .fpt file rather than a .dbt file.
+}
+
+// A dbfFieldLayout is the layout of a field in each record of a DBF.
+type dbfFieldLayout struct {
+	fieldDescriptor *DBFFieldDescriptor
+	offset          int
+	nullBit         int // -1 if the field is not nullable.
+	varLengthBit    int // -1 if the field is not variable length.
+}
+
+// A DBFMemo is a reference to a memo in a .dbt or .fpt file. It is returned
+// for memo fields when there is no memo file.
 type DBFMemo string
 
+// A DBFCurrency is a currency value in units of one ten-thousandth.
+type DBFCurrency int64
+
+// A DBFWriter writes records to a DBF. The number of records in the header is
+// written when the DBFWriter is closed.
+type DBFWriter struct {
+	w                io.WriteSeeker
+	fieldDescriptors []*DBFFieldDescriptor
+	encoder          *encoding.Encoder
+	languageDriverID byte
+	lastUpdate       time.Time
+	records          int
+	data             []byte
+}
+
 // ReadDBF reads a DBF from an io.Reader.
 func ReadDBF(r io.Reader, _ int64, options *ReadDBFOptions) (*DBF, error) {
-	headerData := make([]byte, dbfHeaderLength)
-	if err := readFull(r, headerData); err != nil {
+	header, err := readDBFHeader(r, options)
+	if err != nil {
 		return nil, err
 	}
-	header, err := ParseDBFHeader(headerData, options)
+	fieldDescriptors, err := readDBFFieldDescriptors(r, header)
 	if err != nil {
 		return nil, err
 	}
-	if header.Version != 3 {
-		return nil, fmt.Errorf("%d: unsupported version", header.Version)
-	}
-
-	var fieldDescriptors []*DBFFieldDescriptor
-	for i := 0; ; i++ {
-		fieldDescriptorData := make([]byte, dbfFieldDescriptorSize)
-		if err := readFull(r, fieldDescriptorData[:1]); err != nil {
-			return nil, err
-		}
-		if fieldDescriptorData[0] == '\x0d' {
-			break
-		}
-		if err := readFull(r, fieldDescriptorData[1:]); err != nil {
-			return nil, err
-		}
-
-		name := string(TrimTrailingZeros(fieldDescriptorData[:11]))
-		fieldType := fieldDescriptorData[11]
-		if _, ok := knownFieldTypes[fieldType]; !ok {
-			return nil, fmt.Errorf("field %d: %d: invalid field type", i, fieldType)
-		}
-		length := int(fieldDescriptorData[16])
-		workAreaID := fieldDescriptorData[20]
-		setFields := fieldDescriptorData[23]
-
-		fieldDescriptor := &DBFFieldDescriptor{
-			Name:       name,
-			Type:       fieldType,
-			Length:     length,
-			WorkAreaID: workAreaID,
-			SetFields:  setFields,
-		}
-		fieldDescriptors = append(fieldDescriptors, fieldDescriptor)
-	}
 
-	totalLength := 0
-	for _, fieldDescriptor := range fieldDescriptors {
-		totalLength += fieldDescriptor.Length
-	}
-	if totalLength+1 != header.RecordSize {
-		return nil, errors.New("invalid total length of fields")
+	layout, err := newDBFRecordLayout(header, fieldDescriptors, options)
+	if err != nil {
+		return nil, err
 	}
 
-	var decoder *encoding.Decoder
-	if options != nil && options.Charset != "" {
-		enc, _ := charset.Lookup(options.Charset)
-		if enc == nil {
-			return nil, fmt.Errorf("unknown charset '%s'", options.Charset)
-		}
-		decoder = enc.NewDecoder()
-	} else {
-		decoder = charmap.ISO8859_1.NewDecoder()
+	enc, charsetName, err := dbfCharsetEncoding(header, fieldDescriptors, options, func(n int) ([]byte, error) {
+		bufioReader := bufio.NewReaderSize(r, n)
+		r = bufioReader
+		return peekFull(bufioReader, n)
+	})
+	if err != nil {
+		return nil, err
 	}
+	decoder := enc.NewDecoder()
 	records := make([][]any, 0, header.Records)
-	for range header.Records {
+	deleted := make([]bool, 0, header.Records)
+	for i := range header.Records {
 		recordData := make([]byte, header.RecordSize)
 		if err := readFull(r, recordData); err != nil {
 			return nil, err
 		}
-		switch recordData[0] {
-		case ' ':
-			record := make([]any, 0, len(fieldDescriptors))
-			offset := 1
-			for _, fieldDescriptor := range fieldDescriptors {
-				fieldData := recordData[offset : offset+fieldDescriptor.Length]
-				offset += fieldDescriptor.Length
-				field, err := fieldDescriptor.ParseRecord(fieldData, decoder)
-				if err != nil && !options.SkipBrokenFields {
-					return nil, fmt.Errorf("field %s: %w", fieldDescriptor.Name, err)
-				}
-				record = append(record, field)
+		deleted = append(deleted, recordData[0] == '*')
+		if options != nil && options.skipped != nil {
+			if _, ok := options.skipped[i]; ok {
+				records = append(records, nil)
+				continue
 			}
-			records = append(records, record)
-		case '*':
-			records = append(records, nil)
-		default:
-			return nil, fmt.Errorf("%d: invalid record flag", recordData[0])
 		}
+		record, err := parseDBFRecord(recordData, layout, decoder, options)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
 	}
 
 	data := make([]byte, 1)
@@ -197,28 +309,71 @@ func ReadDBF(r io.Reader, _ int64, options *ReadDBFOptions) (*DBF, error) {
 
 	return &DBF{
 		DBFHeader:        *header,
-		FieldDescriptors: fieldDescriptors,
+		FieldDescriptors: layout.fieldDescriptors(),
 		Records:          records,
+		Charset:          charsetName,
+		deleted:          deleted,
 	}, nil
 }
 
-// ParseDBFHeader parses a DBFHeader from data.
-func ParseDBFHeader(data []byte, options *ReadDBFOptions) (*DBFHeader, error) {
-	if len(data) != dbfHeaderLength {
-		return nil, errors.New("invalid header length")
+// readDBFHeader reads a DBFHeader from r, including the extended header of
+// dBase level 7 tables.
+func readDBFHeader(r io.Reader, options *ReadDBFOptions) (*DBFHeader, error) {
+	headerData := make([]byte, dbf7HeaderLength)
+	if err := readFull(r, headerData[:dbfHeaderLength]); err != nil {
+		return nil, err
 	}
+	if isDBF7Header(headerData) {
+		if err := readFull(r, headerData[dbfHeaderLength:]); err != nil {
+			return nil, err
+		}
+	} else {
+		headerData = headerData[:dbfHeaderLength]
+	}
+	return ParseDBFHeader(headerData, options)
+}
 
-	version := int(data[0]) & 0x7
-	if version != 3 {
-		return nil, fmt.Errorf("%d: unsupported version", version)
+// isDBF7Header returns if data is the start of a dBase level 7 header.
+func isDBF7Header(data []byte) bool {
+	return data[0] == 0x04 || data[0] == 0x8c
+}
+
+// ParseDBFHeader parses a DBFHeader from data. dBase level 7 headers are 68
+// bytes long, all other headers are 32 bytes long.
+func ParseDBFHeader(data []byte, options *ReadDBFOptions) (*DBFHeader, error) {
+	if len(data) < dbfHeaderLength {
+		return nil, errors.New("invalid header length")
 	}
-	memo := int(data[0])&0x8 == 0x8
-	if memo {
-		return nil, errors.New("memo files not supported")
+	switch level7 := isDBF7Header(data); {
+	case level7 && len(data) != dbf7HeaderLength:
+		return nil, errors.New("invalid header length")
+	case !level7 && len(data) != dbfHeaderLength:
+		return nil, errors.New("invalid header length")
 	}
-	dbt := int(data[0])&0x80 == 0x80
-	if dbt {
-		return nil, errors.New(".DBT files are not supported")
+
+	var version int
+	var memo, dbt, fpt, visualFoxPro bool
+	switch data[0] {
+	case 0x30, 0x31, 0x32: // Visual FoxPro.
+		version = 3
+		memo = data[28]&0x2 == 0x2
+		fpt = memo
+		visualFoxPro = true
+	case 0xf5: // FoxPro 2.x with memo.
+		version = 3
+		memo = true
+		fpt = true
+	case 0x04, 0x8c: // dBase level 7.
+		version = 7
+		memo = data[0] == 0x8c
+		dbt = memo
+	default:
+		version = int(data[0]) & 0x7
+		if version != 3 {
+			return nil, fmt.Errorf("%d: unsupported version", version)
+		}
+		memo = int(data[0])&0x8 == 0x8
+		dbt = int(data[0])&0x80 == 0x80
 	}
 
 	lastUpdateYear := int(data[1]) + 1900
@@ -241,17 +396,268 @@ func ParseDBFHeader(data []byte, options *ReadDBFOptions) (*DBFHeader, error) {
 		return nil, errors.New("records too large")
 	}
 
+	var languageDriverName string
+	if version == 7 {
+		languageDriverName = string(TrimTrailingZeros(data[32:64]))
+	}
+
 	return &DBFHeader{
-		Version:    version,
-		Memo:       memo,
-		DBT:        dbt,
-		LastUpdate: lastUpdate,
-		Records:    records,
-		HeaderSize: headerSize,
-		RecordSize: recordSize,
+		Version:      version,
+		Memo:         memo,
+		DBT:          dbt,
+		FPT:          fpt,
+		VisualFoxPro: visualFoxPro,
+		LastUpdate:   lastUpdate,
+		Records:      records,
+		HeaderSize:   headerSize,
+		RecordSize:   recordSize,
+
+		LanguageDriverID:   data[29],
+		LanguageDriverName: languageDriverName,
 	}, nil
 }
 
+// readDBFFieldDescriptors reads the field descriptors of a DBF with header
+// from r, and skips any remaining header data, for example the Visual FoxPro
+// backlink or the dBase level 7 field properties, so that r is positioned at
+// the first record.
+func readDBFFieldDescriptors(r io.Reader, header *DBFHeader) ([]*DBFFieldDescriptor, error) {
+	headerLength, fieldDescriptorSize := dbfHeaderLength, dbfFieldDescriptorSize
+	if header.Version == 7 {
+		headerLength, fieldDescriptorSize = dbf7HeaderLength, dbf7FieldDescriptorSize
+	}
+
+	var fieldDescriptors []*DBFFieldDescriptor
+	for i := 0; ; i++ {
+		fieldDescriptorData := make([]byte, fieldDescriptorSize)
+		if err := readFull(r, fieldDescriptorData[:1]); err != nil {
+			return nil, err
+		}
+		if fieldDescriptorData[0] == '\x0d' {
+			break
+		}
+		if err := readFull(r, fieldDescriptorData[1:]); err != nil {
+			return nil, err
+		}
+
+		var fieldDescriptor *DBFFieldDescriptor
+		if header.Version == 7 {
+			fieldDescriptor = &DBFFieldDescriptor{
+				Name:         string(TrimTrailingZeros(fieldDescriptorData[:32])),
+				Type:         fieldDescriptorData[32],
+				Length:       int(fieldDescriptorData[33]),
+				DecimalCount: int(fieldDescriptorData[34]),
+				Level7:       true,
+			}
+		} else {
+			fieldDescriptor = &DBFFieldDescriptor{
+				Name:         string(TrimTrailingZeros(fieldDescriptorData[:11])),
+				Type:         fieldDescriptorData[11],
+				Length:       int(fieldDescriptorData[16]),
+				DecimalCount: int(fieldDescriptorData[17]),
+				Flags:        fieldDescriptorData[18],
+				WorkAreaID:   fieldDescriptorData[20],
+				SetFields:    fieldDescriptorData[23],
+				VisualFoxPro: header.VisualFoxPro,
+			}
+		}
+		if _, ok := knownFieldTypes[fieldDescriptor.Type]; !ok {
+			return nil, fmt.Errorf("field %d: %d: invalid field type", i, fieldDescriptor.Type)
+		}
+		fieldDescriptors = append(fieldDescriptors, fieldDescriptor)
+	}
+
+	totalLength := 0
+	for _, fieldDescriptor := range fieldDescriptors {
+		totalLength += fieldDescriptor.Length
+	}
+	if totalLength+1 != header.RecordSize {
+		return nil, errors.New("invalid total length of fields")
+	}
+
+	switch n := header.HeaderSize - headerLength - fieldDescriptorSize*len(fieldDescriptors) - 1; {
+	case n < 0:
+		return nil, errors.New("invalid header size")
+	case n > 0:
+		if _, err := io.CopyN(io.Discard, r, int64(n)); err != nil {
+			return nil, err
+		}
+	}
+
+	return fieldDescriptors, nil
+}
+
+// dbfCharsetEncoding returns the encoding and name of the charset of the DBF
+// with header and fieldDescriptors, taken from the first charset source in
+// options' charset priority that specifies a charset. sample is called to
+// read the first n bytes of records if the charset needs to be detected.
+func dbfCharsetEncoding(header *DBFHeader, fieldDescriptors []*DBFFieldDescriptor, options *ReadDBFOptions, sample func(n int) ([]byte, error)) (encoding.Encoding, string, error) {
+	charsetPriority := DefaultDBFCharsetPriority
+	if options != nil && options.CharsetPriority != nil {
+		charsetPriority = options.CharsetPriority
+	}
+	for _, charsetSource := range charsetPriority {
+		var name string
+		switch charsetSource {
+		case DBFCharsetSourceCPG:
+			if options != nil && options.CPG != nil {
+				name = options.CPG.Charset
+			}
+		case DBFCharsetSourceCharset:
+			if options != nil {
+				name = options.Charset
+			}
+		case DBFCharsetSourceLDID:
+			name = header.LanguageDriverCharset()
+		case DBFCharsetSourceDetect:
+			n := dbfCharsetSampleLength(header)
+			if n == 0 {
+				continue
+			}
+			data, err := sample(n)
+			if err != nil {
+				return nil, "", err
+			}
+			name = detectDBFCharset(data, header.RecordSize, fieldDescriptors)
+		default:
+			return nil, "", fmt.Errorf("%d: invalid charset source", charsetSource)
+		}
+		if name == "" {
+			continue
+		}
+		enc, canonicalName := lookupCharset(name)
+		if enc == nil {
+			return nil, "", fmt.Errorf("unknown charset '%s'", name)
+		}
+		return enc, canonicalName, nil
+	}
+	return charmap.ISO8859_1, defaultCharset, nil
+}
+
+// newDBFRecordLayout returns the layout of the fields of fieldDescriptors that
+// are selected by options.Fields, in a table with header.
+func newDBFRecordLayout(header *DBFHeader, fieldDescriptors []*DBFFieldDescriptor, options *ReadDBFOptions) (*dbfRecordLayout, error) {
+	layout := &dbfRecordLayout{
+		nullFlagsOffset: -1,
+		fpt:             header.FPT,
+	}
+	fields := make([]*dbfFieldLayout, 0, len(fieldDescriptors))
+	offset, bit := 1, 0
+	for _, fieldDescriptor := range fieldDescriptors {
+		field := &dbfFieldLayout{
+			fieldDescriptor: fieldDescriptor,
+			offset:          offset,
+			nullBit:         -1,
+			varLengthBit:    -1,
+		}
+		if fieldDescriptor.Flags&DBFFieldFlagNullable != 0 {
+			field.nullBit = bit
+			bit++
+		}
+		if fieldDescriptor.Type == 'Q' || fieldDescriptor.Type == 'V' {
+			field.varLengthBit = bit
+			bit++
+		}
+		if fieldDescriptor.Type == '0' && layout.nullFlagsOffset == -1 {
+			layout.nullFlagsOffset = offset
+			layout.nullFlagsLength = fieldDescriptor.Length
+		}
+		fields = append(fields, field)
+		offset += fieldDescriptor.Length
+	}
+
+	if options == nil || options.Fields == nil {
+		layout.fields = fields
+		return layout, nil
+	}
+
+	fieldsByName := make(map[string]*dbfFieldLayout, len(fields))
+	for _, field := range fields {
+		fieldsByName[field.fieldDescriptor.Name] = field
+	}
+	layout.fields = make([]*dbfFieldLayout, 0, len(options.Fields))
+	for _, name := range options.Fields {
+		field, ok := fieldsByName[name]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("field %s: unknown field", name)
+		case field == nil:
+			return nil, fmt.Errorf("field %s: duplicate field", name)
+		}
+		layout.fields = append(layout.fields, field)
+		fieldsByName[name] = nil
+	}
+	return layout, nil
+}
+
+// fieldDescriptors returns the field descriptors of the fields in l.
+func (l *dbfRecordLayout) fieldDescriptors() []*DBFFieldDescriptor {
+	fieldDescriptors := make([]*DBFFieldDescriptor, 0, len(l.fields))
+	for _, field := range l.fields {
+		fieldDescriptors = append(fieldDescriptors, field.fieldDescriptor)
+	}
+	return fieldDescriptors
+}
+
+// parseDBFRecord parses the fields in layout from the record in recordData.
+// It returns nil if the record is deleted, unless options.ReadDeleted is set.
+func parseDBFRecord(recordData []byte, layout *dbfRecordLayout, decoder *encoding.Decoder, options *ReadDBFOptions) ([]any, error) {
+	switch recordData[0] {
+	case ' ':
+	case '*':
+		if options == nil || !options.ReadDeleted {
+			return nil, nil
+		}
+	default:
+		return nil, fmt.Errorf("%d: invalid record flag", recordData[0])
+	}
+
+	var nullFlags []byte
+	if layout.nullFlagsOffset != -1 {
+		nullFlags = recordData[layout.nullFlagsOffset : layout.nullFlagsOffset+layout.nullFlagsLength]
+	}
+	record := make([]any, 0, len(layout.fields))
+	for _, field := range layout.fields {
+		fieldDescriptor := field.fieldDescriptor
+		fieldData := recordData[field.offset : field.offset+fieldDescriptor.Length]
+		var isNull, isVarLength bool
+		if nullFlags != nil {
+			isNull = field.nullBit != -1 && isBitSet(nullFlags, field.nullBit)
+			isVarLength = field.varLengthBit != -1 && isBitSet(nullFlags, field.varLengthBit)
+		}
+		if isNull {
+			record = append(record, nil)
+			continue
+		}
+		// Variable length fields that are not full store their length in
+		// their last byte.
+		if isVarLength && len(fieldData) > 0 {
+			if length := int(fieldData[len(fieldData)-1]); length < len(fieldData) {
+				fieldData = fieldData[:length]
+			}
+		}
+		field, err := fieldDescriptor.ParseRecord(fieldData, decoder)
+		if memo, ok := field.(DBFMemo); ok && err == nil && options != nil {
+			switch {
+			case layout.fpt && options.FPT != nil:
+				field, err = options.FPT.parseMemo(memo, fieldDescriptor.Type, decoder, options.MaxMemoSize)
+			case !layout.fpt && options.DBT != nil:
+				field, err = options.DBT.parseMemo(memo, fieldDescriptor.Type, decoder, options.MaxMemoSize)
+			}
+		}
+		if err != nil && (options == nil || !options.SkipBrokenFields) {
+			return nil, fmt.Errorf("field %s: %w", fieldDescriptor.Name, err)
+		}
+		record = append(record, field)
+	}
+	return record, nil
+}
+
+// isBitSet returns if bit i of data is set.
+func isBitSet(data []byte, i int) bool {
+	return i/8 < len(data) && data[i/8]&(1<<(i%8)) != 0
+}
+
 // ReadDBFZipFile reads a DBF from a *zip.File.
 func ReadDBFZipFile(zipFile *zip.File, options *ReadDBFOptions) (*DBF, error) {
 	readCloser, err := zipFile.Open()
@@ -266,6 +672,120 @@ func ReadDBFZipFile(zipFile *zip.File, options *ReadDBFOptions) (*DBF, error) {
 	return dbf, nil
 }
 
+// NewDBFWriter returns a new DBFWriter that writes records with
+// fieldDescriptors to w.
+func NewDBFWriter(w io.WriteSeeker, fieldDescriptors []*DBFFieldDescriptor, options *WriteDBFOptions) (*DBFWriter, error) {
+	if err := checkDBFFieldDescriptors(fieldDescriptors); err != nil {
+		return nil, err
+	}
+	encoder, err := newDBFEncoder(options)
+	if err != nil {
+		return nil, err
+	}
+	var languageDriverID byte
+	lastUpdate := time.Now()
+	if options != nil {
+		languageDriverID = options.LanguageDriverID
+		if !options.LastUpdate.IsZero() {
+			lastUpdate = options.LastUpdate
+		}
+	}
+	dbfWriter := &DBFWriter{
+		w:                w,
+		fieldDescriptors: fieldDescriptors,
+		encoder:          encoder,
+		languageDriverID: languageDriverID,
+		lastUpdate:       lastUpdate,
+	}
+	if _, err := w.Write(appendDBFHeader(nil, fieldDescriptors, 0, languageDriverID, lastUpdate)); err != nil {
+		return nil, err
+	}
+	return dbfWriter, nil
+}
+
+// Write writes record. A nil record is written as a deleted record.
+func (w *DBFWriter) Write(record []any) error {
+	data, err := w.encode(record)
+	if err != nil {
+		return err
+	}
+	return w.writeEncoded(data)
+}
+
+// Close writes the end of file marker and the number of records. It does not
+// close the underlying writer.
+func (w *DBFWriter) Close() error {
+	if _, err := w.w.Write([]byte{'\x1a'}); err != nil {
+		return err
+	}
+	if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(appendDBFHeader(nil, w.fieldDescriptors, w.records, w.languageDriverID, w.lastUpdate)[:dbfHeaderLength]); err != nil {
+		return err
+	}
+	if _, err := w.w.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	return nil
+}
+
+// encode returns record encoded as w's next record.
+func (w *DBFWriter) encode(record []any) ([]byte, error) {
+	data, err := appendDBFRecord(w.data[:0], w.fieldDescriptors, record, w.encoder)
+	if err != nil {
+		return nil, fmt.Errorf("record %d: %w", w.records+1, err)
+	}
+	w.data = data
+	return data, nil
+}
+
+// writeEncoded writes data, returned by encode.
+func (w *DBFWriter) writeEncoded(data []byte) error {
+	if _, err := w.w.Write(data); err != nil {
+		return err
+	}
+	w.records++
+	return nil
+}
+
+// WriteDBF writes a DBF with fieldDescriptors and records to w. nil records
+// are written as deleted records.
+func WriteDBF(w io.Writer, fieldDescriptors []*DBFFieldDescriptor, records [][]any, options *WriteDBFOptions) error {
+	if err := checkDBFFieldDescriptors(fieldDescriptors); err != nil {
+		return err
+	}
+	encoder, err := newDBFEncoder(options)
+	if err != nil {
+		return err
+	}
+	var languageDriverID byte
+	lastUpdate := time.Now()
+	if options != nil {
+		languageDriverID = options.LanguageDriverID
+		if !options.LastUpdate.IsZero() {
+			lastUpdate = options.LastUpdate
+		}
+	}
+
+	data := appendDBFHeader(nil, fieldDescriptors, len(records), languageDriverID, lastUpdate)
+	for i, record := range records {
+		data, err = appendDBFRecord(data, fieldDescriptors, record, encoder)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i+1, err)
+		}
+	}
+	data = append(data, '\x1a')
+
+	_, err = w.Write(data)
+	return err
+}
+
+// Deleted returns if the ith record is deleted.
+func (d *DBF) Deleted(i int) bool {
+	return d.Records[i] == nil || i < len(d.deleted) && d.deleted[i]
+}
+
 // Record returns the ith record.
 func (d *DBF) Record(i int) map[string]any {
 	if d.Records[i] == nil {
@@ -279,26 +799,77 @@ func (d *DBF) Record(i int) map[string]any {
 	return fields
 }
 
-// ParseRecord parses a record from data.
+// ParseRecord parses a record from data. Blank dates, which DBFWriter writes
+// for nil values, and dates filled with NUL bytes are parsed as nil.
 func (d *DBFFieldDescriptor) ParseRecord(data []byte, decoder *encoding.Decoder) (any, error) {
 	switch d.Type {
+	case '+':
+		return parseLong(data)
+	case '0', 'Q':
+		return bytes.Clone(data), nil
+	case '@':
+		return parseTimestamp(data)
+	case 'B':
+		// dBase stores binary memos in B fields, Visual FoxPro stores doubles.
+		if d.VisualFoxPro {
+			return parseDouble(data)
+		}
+		return parseMemo(data, false)
 	case 'C':
 		return parseCharacter(data, decoder)
 	case 'D':
 		return parseDate(data)
 	case 'F':
 		return parseFloat(data)
+	case 'I':
+		// dBase level 7 longs are stored differently to Visual FoxPro integers.
+		if d.Level7 {
+			return parseLong(data)
+		}
+		return parseInteger(data)
 	case 'L':
 		return parseLogical(data)
-	case 'M':
-		return parseMemo(data), nil
+	case 'G', 'M', 'P':
+		return parseMemo(data, d.VisualFoxPro)
 	case 'N':
 		return parseNumber(data)
+	case 'O':
+		return parseSortableDouble(data)
+	case 'T':
+		return parseDateTime(data)
+	case 'V':
+		return parseVarchar(data, decoder)
+	case 'Y':
+		return parseCurrency(data)
+	default:
+		return nil, fmt.Errorf("%d: unsupported field type", d.Type)
+	}
+}
+
+// FormatRecord formats value as a field described by d.
+func (d *DBFFieldDescriptor) FormatRecord(value any, encoder *encoding.Encoder) ([]byte, error) {
+	switch d.Type {
+	case 'C':
+		return formatCharacter(value, d.Length, encoder)
+	case 'D':
+		return formatDate(value)
+	case 'F', 'N':
+		return formatNumber(value, d.Type, d.Length, d.DecimalCount)
+	case 'L':
+		return formatLogical(value)
 	default:
 		return nil, fmt.Errorf("%d: unsupported field type", d.Type)
 	}
 }
 
+// String returns c as a decimal with four decimal places.
+func (c DBFCurrency) String() string {
+	if c < 0 {
+		return fmt.Sprintf("-%d.%04d", -(c / 10000), -(c % 10000))
+	}
+	return fmt.Sprintf("%d.%04d", c/10000, c%10000)
+}
+
 // TrimTrailingZeros trims any trailing zero bytes from data.
 func TrimTrailingZeros(data []byte) []byte {
 	for i := len(data) - 1; i >= 0; i-- {
@@ -316,25 +887,58 @@ func parseCharacter(data []byte, decoder *encoding.Decoder) (string, error) {
 	return decoder.String(string(bytes.TrimSpace(TrimTrailingZeros(data))))
 }
 
-func parseDate(data []byte) (time.Time, error) {
+func parseCurrency(data []byte) (any, error) {
 	if len(data) != 8 {
-		return time.Time{}, errors.New("invalid date field length")
+		return nil, errors.New("invalid currency field length")
+	}
+	return DBFCurrency(int64(binary.LittleEndian.Uint64(data))), nil
+}
+
+// parseDate parses a date in YYYYMMDD format. Blank dates and dates filled
+// with NUL bytes are null and are returned as nil.
+func parseDate(data []byte) (any, error) {
+	if len(data) != 8 {
+		return nil, errors.New("invalid date field length")
+	}
+	if len(bytes.TrimSpace(TrimTrailingZeros(data))) == 0 {
+		return nil, nil
 	}
 	year, err := strconv.ParseInt(string(data[:4]), 10, 64)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("%s: invalid year: %w", string(data[:4]), err)
+		return nil, fmt.Errorf("%s: invalid year: %w", string(data[:4]), err)
 	}
 	month, err := strconv.ParseInt(string(data[4:6]), 10, 64)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("%s: invalid month: %w", string(data[4:6]), err)
+		return nil, fmt.Errorf("%s: invalid month: %w", string(data[4:6]), err)
 	}
 	day, err := strconv.ParseInt(string(data[6:8]), 10, 64)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("%s: invalid day: %w", string(data[6:8]), err)
+		return nil, fmt.Errorf("%s: invalid day: %w", string(data[6:8]), err)
 	}
 	return time.Date(int(year), time.Month(month), int(day), 0, 0, 0, 0, time.UTC), nil
 }
 
+// parseDateTime parses a Visual FoxPro datetime, which is stored as a Julian
+// day and the number of milliseconds since midnight.
+func parseDateTime(data []byte) (any, error) {
+	if len(data) != 8 {
+		return nil, errors.New("invalid datetime field length")
+	}
+	julianDay := int(binary.LittleEndian.Uint32(data[:4]))
+	milliseconds := int(binary.LittleEndian.Uint32(data[4:]))
+	if julianDay == 0 && milliseconds == 0 {
+		return nil, nil
+	}
+	return julianDayTime(julianDay, milliseconds), nil
+}
+
+func parseDouble(data []byte) (any, error) {
+	if len(data) != 8 {
+		return nil, errors.New("invalid double field length")
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+}
+
 func parseFloat(data []byte) (any, error) {
 	fieldStr := string(bytes.TrimSpace(TrimTrailingZeros(data)))
 	if fieldStr == "" {
@@ -347,6 +951,13 @@ func parseFloat(data []byte) (any, error) {
 	return field, nil
 }
 
+func parseInteger(data []byte) (any, error) {
+	if len(data) != 4 {
+		return nil, errors.New("invalid integer field length")
+	}
+	return int32(binary.LittleEndian.Uint32(data)), nil
+}
+
 func parseLogical(data []byte) (any, error) {
 	if len(data) != 1 {
 		return nil, fmt.Errorf("%q: invalid logical", string(data))
@@ -358,8 +969,28 @@ func parseLogical(data []byte) (any, error) {
 	return field, nil
 }
 
-func parseMemo(data []byte) DBFMemo {
-	return DBFMemo(bytes.TrimSpace(TrimTrailingZeros(data)))
+// parseLong parses a dBase level 7 long, which is stored big endian with its
+// sign bit inverted.
+func parseLong(data []byte) (any, error) {
+	if len(data) != 4 {
+		return nil, errors.New("invalid long field length")
+	}
+	return int32(binary.BigEndian.Uint32(data) ^ 0x80000000), nil
+}
+
+// parseMemo parses a memo block number. Visual FoxPro stores memo block numbers
+// as four byte integers, other versions store them as text.
+func parseMemo(data []byte, visualFoxPro bool) (any, error) {
+	if !visualFoxPro {
+		return DBFMemo(bytes.TrimSpace(TrimTrailingZeros(data))), nil
+	}
+	if len(data) != 4 {
+		return nil, errors.New("invalid memo field length")
+	}
+	if block := binary.LittleEndian.Uint32(data); block != 0 {
+		return DBFMemo(strconv.FormatUint(uint64(block), 10)), nil
+	}
+	return DBFMemo(""), nil
 }
 
 func parseNumber(data []byte) (any, error) {
@@ -380,3 +1011,289 @@ func parseNumber(data []byte) (any, error) {
 	}
 	return int(field), nil
 }
+
+// parseSortableDouble parses a dBase level 7 double, which is stored big
+// endian with its sign bit inverted if positive, or all bits inverted if
+// negative, so that encoded values sort in the same order as their values.
+func parseSortableDouble(data []byte) (any, error) {
+	if len(data) != 8 {
+		return nil, errors.New("invalid double field length")
+	}
+	return decodeSortableDouble(data), nil
+}
+
+// parseTimestamp parses a dBase level 7 timestamp, which is stored as a
+// sortable double number of milliseconds since Julian day zero.
+func parseTimestamp(data []byte) (any, error) {
+	if len(data) != 8 {
+		return nil, errors.New("invalid timestamp field length")
+	}
+	if len(TrimTrailingZeros(data)) == 0 {
+		return nil, nil
+	}
+	milliseconds := int64(decodeSortableDouble(data))
+	return julianDayTime(int(milliseconds/millisecondsPerDay), int(milliseconds%millisecondsPerDay)), nil
+}
+
+func parseVarchar(data []byte, decoder *encoding.Decoder) (string, error) {
+	if decoder == nil {
+		return "", errors.New("decoder is nil")
+	}
+	return decoder.String(string(data))
+}
+
+// decodeSortableDouble decodes a big endian double with its sign bit inverted
+// if positive, or all bits inverted if negative.
+func decodeSortableDouble(data []byte) float64 {
+	bits := binary.BigEndian.Uint64(data)
+	if bits&(1<<63) != 0 {
+		bits ^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	return math.Float64frombits(bits)
+}
+
+// julianDayTime returns the time of milliseconds after the start of
+// julianDay.
+func julianDayTime(julianDay, milliseconds int) time.Time {
+	return time.Date(1970, time.January, 1+julianDay-julianDayUnixEpoch, 0, 0, 0, 0, time.UTC).Add(time.Duration(milliseconds) * time.Millisecond)
+}
+
+// checkDBFFieldDescriptors returns an error if fieldDescriptors cannot be
+// written.
+func checkDBFFieldDescriptors(fieldDescriptors []*DBFFieldDescriptor) error {
+	recordSize := 1
+	for i, fieldDescriptor := range fieldDescriptors {
+		if fieldDescriptor.Name == "" || len(fieldDescriptor.Name) > 10 {
+			return fmt.Errorf("field %d: %s: invalid field name", i, fieldDescriptor.Name)
+		}
+		switch fieldDescriptor.Type {
+		case 'C':
+			if fieldDescriptor.Length < 1 || fieldDescriptor.Length > 255 {
+				return fmt.Errorf("field %s: %d: invalid length", fieldDescriptor.Name, fieldDescriptor.Length)
+			}
+		case 'D':
+			if fieldDescriptor.Length != 8 {
+				return fmt.Errorf("field %s: %d: invalid length", fieldDescriptor.Name, fieldDescriptor.Length)
+			}
+		case 'F', 'N':
+			if fieldDescriptor.Length < 1 || fieldDescriptor.Length > 255 {
+				return fmt.Errorf("field %s: %d: invalid length", fieldDescriptor.Name, fieldDescriptor.Length)
+			}
+			if fieldDescriptor.DecimalCount < 0 || fieldDescriptor.DecimalCount >= fieldDescriptor.Length {
+				return fmt.Errorf("field %s: %d: invalid decimal count", fieldDescriptor.Name, fieldDescriptor.DecimalCount)
+			}
+		case 'L':
+			if fieldDescriptor.Length != 1 {
+				return fmt.Errorf("field %s: %d: invalid length", fieldDescriptor.Name, fieldDescriptor.Length)
+			}
+		default:
+			return fmt.Errorf("field %s: %d: unsupported field type", fieldDescriptor.Name, fieldDescriptor.Type)
+		}
+		recordSize += fieldDescriptor.Length
+	}
+	if recordSize > math.MaxUint16 {
+		return errors.New("records too large")
+	}
+	return nil
+}
+
+// newDBFEncoder returns a new encoder for the charset in options, or, if there
+// is no charset in options, for the charset of the language driver ID in
+// options.
+func newDBFEncoder(options *WriteDBFOptions) (*encoding.Encoder, error) {
+	if options != nil {
+		name := options.Charset
+		if name == "" {
+			name = dbfLanguageDriverCharsets[options.LanguageDriverID]
+		}
+		if name != "" {
+			enc, _ := lookupCharset(name)
+			if enc == nil {
+				return nil, fmt.Errorf("unknown charset '%s'", name)
+			}
+			return enc.NewEncoder(), nil
+		}
+	}
+	return charmap.ISO8859_1.NewEncoder(), nil
+}
+
+// appendDBFHeader appends a DBF header and fieldDescriptors to data.
+func appendDBFHeader(data []byte, fieldDescriptors []*DBFFieldDescriptor, records int, languageDriverID byte, lastUpdate time.Time) []byte {
+	headerSize := dbfHeaderLength + dbfFieldDescriptorSize*len(fieldDescriptors) + 1
+	recordSize := 1
+	for _, fieldDescriptor := range fieldDescriptors {
+		recordSize += fieldDescriptor.Length
+	}
+
+	data = append(data, 3, byte(lastUpdate.Year()-1900), byte(lastUpdate.Month()), byte(lastUpdate.Day()))
+	data = binary.LittleEndian.AppendUint32(data, uint32(records))
+	data = binary.LittleEndian.AppendUint16(data, uint16(headerSize))
+	data = binary.LittleEndian.AppendUint16(data, uint16(recordSize))
+	data = append(data, make([]byte, 17)...)
+	data = append(data, languageDriverID, 0, 0)
+
+	for _, fieldDescriptor := range fieldDescriptors {
+		fieldDescriptorData := make([]byte, dbfFieldDescriptorSize)
+		copy(fieldDescriptorData[:11], fieldDescriptor.Name)
+		fieldDescriptorData[11] = fieldDescriptor.Type
+		fieldDescriptorData[16] = byte(fieldDescriptor.Length)
+		fieldDescriptorData[17] = byte(fieldDescriptor.DecimalCount)
+		fieldDescriptorData[20] = fieldDescriptor.WorkAreaID
+		fieldDescriptorData[23] = fieldDescriptor.SetFields
+		data = append(data, fieldDescriptorData...)
+	}
+	data = append(data, '\x0d')
+
+	return data
+}
+
+// appendDBFRecord appends record with fieldDescriptors to data. A nil record
+// is appended as a deleted record.
+func appendDBFRecord(data []byte, fieldDescriptors []*DBFFieldDescriptor, record []any, encoder *encoding.Encoder) ([]byte, error) {
+	if record == nil {
+		data = append(data, '*')
+		for _, fieldDescriptor := range fieldDescriptors {
+			data = append(data, bytes.Repeat([]byte{' '}, fieldDescriptor.Length)...)
+		}
+		return data, nil
+	}
+	if len(record) != len(fieldDescriptors) {
+		return nil, errors.New("invalid number of fields")
+	}
+	data = append(data, ' ')
+	for i, fieldDescriptor := range fieldDescriptors {
+		fieldData, err := fieldDescriptor.FormatRecord(record[i], encoder)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", fieldDescriptor.Name, err)
+		}
+		data = append(data, fieldData...)
+	}
+	return data, nil
+}
+
+func formatCharacter(value any, length int, encoder *encoding.Encoder) ([]byte, error) {
+	var data []byte
+	switch value := value.(type) {
+	case nil:
+	case string:
+		if encoder == nil {
+			return nil, errors.New("encoder is nil")
+		}
+		var err error
+		data, err = encoder.Bytes([]byte(value))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", value, err)
+		}
+		if len(data) > length {
+			return nil, fmt.Errorf("%q: too long", value)
+		}
+	default:
+		return nil, fmt.Errorf("%T: unsupported character type", value)
+	}
+	return append(data, bytes.Repeat([]byte{' '}, length-len(data))...), nil
+}
+
+func formatDate(value any) ([]byte, error) {
+	switch value := value.(type) {
+	case nil:
+		return []byte("        "), nil
+	case time.Time:
+		if value.Year() < 0 || value.Year() > 9999 {
+			return nil, fmt.Errorf("%d: invalid year", value.Year())
+		}
+		return []byte(value.Format("20060102")), nil
+	default:
+		return nil, fmt.Errorf("%T: unsupported date type", value)
+	}
+}
+
+func formatLogical(value any) ([]byte, error) {
+	switch value {
+	case nil:
+		return []byte{'?'}, nil
+	case false:
+		return []byte{'F'}, nil
+	case true:
+		return []byte{'T'}, nil
+	default:
+		return nil, fmt.Errorf("%T: unsupported logical type", value)
+	}
+}
+
+func formatNumber(value any, fieldType byte, length, decimalCount int) ([]byte, error) {
+	var fieldStr string
+	switch value := value.(type) {
+	case nil:
+		return bytes.Repeat([]byte{' '}, length), nil
+	case int:
+		fieldStr = formatInt(int64(value), decimalCount)
+	case int32:
+		fieldStr = formatInt(int64(value), decimalCount)
+	case int64:
+		fieldStr = formatInt(value, decimalCount)
+	case DBFCurrency:
+		fieldStr = formatCurrency(value, decimalCount)
+	case float32:
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			return nil, fmt.Errorf("%f: invalid numeric", value)
+		}
+		fieldStr = formatFloat(float64(value), 32, fieldType, length, decimalCount)
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("%f: invalid numeric", value)
+		}
+		fieldStr = formatFloat(value, 64, fieldType, length, decimalCount)
+	default:
+		return nil, fmt.Errorf("%T: unsupported numeric type", value)
+	}
+	if len(fieldStr) > length {
+		return nil, fmt.Errorf("%s: too long", fieldStr)
+	}
+	return []byte(strings.Repeat(" ", length-len(fieldStr)) + fieldStr), nil
+}
+
+// formatFloat formats value with decimalCount decimal places. If the result is
+// longer than length and fieldType is 'F' then value is formatted in
+// exponential notation instead.
+func formatFloat(value float64, bitSize int, fieldType byte, length, decimalCount int) string {
+	fieldStr := strconv.FormatFloat(value, 'f', decimalCount, bitSize)
+	if len(fieldStr) > length && fieldType == 'F' {
+		fieldStr = strconv.FormatFloat(value, 'e', -1, bitSize)
+	}
+	return fieldStr
+}
+
+// formatCurrency formats value with decimalCount decimal places, rounding half
+// away from zero. value is formatted from its scaled integer value so no
+// precision is lost.
+func formatCurrency(value DBFCurrency, decimalCount int) string {
+	magnitude := uint64(value)
+	if value < 0 {
+		magnitude = -magnitude
+	}
+	places := min(max(decimalCount, 0), 4)
+	divisor := uint64(1)
+	for range 4 - places {
+		divisor *= 10
+	}
+	magnitude = (magnitude + divisor/2) / divisor
+	unit := uint64(10000) / divisor
+	fieldStr := strconv.FormatUint(magnitude/unit, 10)
+	if decimalCount > 0 {
+		fieldStr += fmt.Sprintf(".%0*d", places, magnitude%unit) + strings.Repeat("0", decimalCount-places)
+	}
+	if value < 0 && magnitude != 0 {
+		fieldStr = "-" + fieldStr
+	}
+	return fieldStr
+}
+
+func formatInt(value int64, decimalCount int) string {
+	fieldStr := strconv.FormatInt(value, 10)
+	if decimalCount > 0 {
+		fieldStr += "." + strings.Repeat("0", decimalCount)
+	}
+	return fieldStr
+}
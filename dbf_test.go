@@ -2,10 +2,15 @@ package shapefile
 
 import (
 	"bytes"
+	"math"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-geom"
+	"golang.org/x/text/encoding/charmap"
 )
 
 func FuzzReadDBF(f *testing.F) {
@@ -20,3 +25,632 @@ func FuzzReadDBF(f *testing.F) {
 		})
 	})
 }
+
+func TestWriteDBF(t *testing.T) {
+	lastUpdate := time.Date(2024, time.February, 3, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name             string
+		fieldDescriptors []*DBFFieldDescriptor
+		records          [][]any
+		options          *WriteDBFOptions
+		expectedRecords  [][]any
+		expectedErr      string
+	}{
+		{
+			name: "empty",
+			fieldDescriptors: []*DBFFieldDescriptor{
+				{Name: "NAME", Type: 'C', Length: 8},
+			},
+			expectedRecords: [][]any{},
+		},
+		{
+			name: "all_types",
+			fieldDescriptors: []*DBFFieldDescriptor{
+				{Name: "NAME", Type: 'C', Length: 8},
+				{Name: "COUNT", Type: 'N', Length: 5},
+				{Name: "AREA", Type: 'N', Length: 10, DecimalCount: 3},
+				{Name: "RATIO", Type: 'F', Length: 8, DecimalCount: 2},
+				{Name: "VALID", Type: 'L', Length: 1},
+				{Name: "DATE", Type: 'D', Length: 8},
+			},
+			records: [][]any{
+				{"Zürich", 168, 215229.266, 0.5, true, time.Date(2021, time.February, 22, 0, 0, 0, 0, time.UTC)},
+				{"", -1, 1, 2.125, false, nil},
+				{nil, nil, nil, nil, nil, nil},
+			},
+			expectedRecords: [][]any{
+				{"Zürich", 168, 215229.266, 0.5, true, time.Date(2021, time.February, 22, 0, 0, 0, 0, time.UTC)},
+				{"", -1, 1., 2.12, false, nil},
+				{"", nil, nil, nil, nil, nil},
+			},
+		},
+		{
+			name: "charset",
+			fieldDescriptors: []*DBFFieldDescriptor{
+				{Name: "NAME", Type: 'C', Length: 16},
+			},
+			records: [][]any{
+				{"Москва"},
+			},
+			options: &WriteDBFOptions{
+				Charset: "windows-1251",
+			},
+		},
+		{
+			name: "too_long",
+			fieldDescriptors: []*DBFFieldDescriptor{
+				{Name: "NAME", Type: 'C', Length: 2},
+			},
+			records: [][]any{
+				{"abc"},
+			},
+			expectedErr: `record 1: field NAME: "abc": too long`,
+		},
+		{
+			name: "invalid_field_name",
+			fieldDescriptors: []*DBFFieldDescriptor{
+				{Name: "VERY_LONG_NAME", Type: 'C', Length: 2},
+			},
+			expectedErr: "field 0: VERY_LONG_NAME: invalid field name",
+		},
+		{
+			name: "unsupported_type",
+			fieldDescriptors: []*DBFFieldDescriptor{
+				{Name: "COUNT", Type: 'N', Length: 4},
+			},
+			records: [][]any{
+				{"1"},
+			},
+			expectedErr: "record 1: field COUNT: string: unsupported numeric type",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			options := &WriteDBFOptions{
+				LastUpdate: lastUpdate,
+			}
+			if tc.options != nil {
+				options.Charset = tc.options.Charset
+			}
+			expectedRecords := tc.expectedRecords
+			if expectedRecords == nil {
+				expectedRecords = tc.records
+			}
+
+			testDBF := func(t *testing.T, data []byte) {
+				t.Helper()
+				dbf, err := ReadDBF(bytes.NewReader(data), int64(len(data)), &ReadDBFOptions{
+					Charset: options.Charset,
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, lastUpdate, dbf.LastUpdate)
+				assert.Equal(t, len(tc.records), dbf.DBFHeader.Records)
+				assert.Equal(t, tc.fieldDescriptors, dbf.FieldDescriptors)
+				assert.Equal(t, expectedRecords, dbf.Records)
+			}
+
+			t.Run("WriteDBF", func(t *testing.T) {
+				buffer := &bytes.Buffer{}
+				err := WriteDBF(buffer, tc.fieldDescriptors, tc.records, options)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+					return
+				}
+				assert.NoError(t, err)
+				testDBF(t, buffer.Bytes())
+			})
+
+			t.Run("DBFWriter", func(t *testing.T) {
+				file, err := os.Create(filepath.Join(t.TempDir(), "test.dbf"))
+				assert.NoError(t, err)
+				defer file.Close()
+
+				dbfWriter, err := NewDBFWriter(file, tc.fieldDescriptors, options)
+				if tc.expectedErr != "" && err != nil {
+					assert.EqualError(t, err, tc.expectedErr)
+					return
+				}
+				assert.NoError(t, err)
+				for _, record := range tc.records {
+					if err := dbfWriter.Write(record); err != nil {
+						assert.EqualError(t, err, tc.expectedErr)
+						return
+					}
+				}
+				assert.Zero(t, tc.expectedErr)
+				assert.NoError(t, dbfWriter.Close())
+
+				data, err := os.ReadFile(file.Name())
+				assert.NoError(t, err)
+				testDBF(t, data)
+			})
+		})
+	}
+}
+
+func TestDBFFieldDescriptorParseRecord(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		fieldType     byte
+		level7        bool
+		visualFoxPro  bool
+		data          []byte
+		expectedValue any
+		expectedErr   string
+	}{
+		{
+			name:          "autoincrement",
+			fieldType:     '+',
+			data:          []byte{0x80, 0x00, 0x00, 0x01},
+			expectedValue: int32(1),
+		},
+		{
+			name:          "autoincrement_negative",
+			fieldType:     '+',
+			data:          []byte{0x7f, 0xff, 0xff, 0xff},
+			expectedValue: int32(-1),
+		},
+		{
+			name:          "binary_memo",
+			fieldType:     'B',
+			data:          []byte("         5"),
+			expectedValue: DBFMemo("5"),
+		},
+		{
+			name:          "binary_memo_eight_bytes",
+			fieldType:     'B',
+			data:          []byte("     123"),
+			expectedValue: DBFMemo("123"),
+		},
+		{
+			name:          "currency",
+			fieldType:     'Y',
+			data:          []byte{0x4e, 0x61, 0xbc, 0x00, 0x00, 0x00, 0x00, 0x00},
+			expectedValue: DBFCurrency(12345678),
+		},
+		{
+			name:          "date",
+			fieldType:     'D',
+			data:          []byte("20230224"),
+			expectedValue: time.Date(2023, time.February, 24, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "date_blank",
+			fieldType: 'D',
+			data:      []byte("        "),
+		},
+		{
+			name:        "date_invalid",
+			fieldType:   'D',
+			data:        []byte("2023XX24"),
+			expectedErr: `XX: invalid month: strconv.ParseInt: parsing "XX": invalid syntax`,
+		},
+		{
+			name:      "date_nul",
+			fieldType: 'D',
+			data:      make([]byte, 8),
+		},
+		{
+			name:          "datetime",
+			fieldType:     'T',
+			data:          []byte{0x60, 0x89, 0x25, 0x00, 0xfc, 0xce, 0x38, 0x00},
+			expectedValue: time.Date(2023, time.February, 24, 1, 2, 3, 4000000, time.UTC),
+		},
+		{
+			name:      "datetime_empty",
+			fieldType: 'T',
+			data:      make([]byte, 8),
+		},
+		{
+			name:          "double",
+			fieldType:     'B',
+			visualFoxPro:  true,
+			data:          []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf8, 0x3f},
+			expectedValue: 1.5,
+		},
+		{
+			name:          "integer",
+			fieldType:     'I',
+			data:          []byte{0xff, 0xff, 0xff, 0xff},
+			expectedValue: int32(-1),
+		},
+		{
+			name:          "integer_level7",
+			fieldType:     'I',
+			level7:        true,
+			data:          []byte{0x7f, 0xff, 0xff, 0xff},
+			expectedValue: int32(-1),
+		},
+		{
+			name:        "integer_invalid_length",
+			fieldType:   'I',
+			data:        []byte{0x00},
+			expectedErr: "invalid integer field length",
+		},
+		{
+			name:          "memo",
+			fieldType:     'M',
+			data:          []byte("        12"),
+			expectedValue: DBFMemo("12"),
+		},
+		{
+			name:          "memo_short",
+			fieldType:     'M',
+			data:          []byte("  12"),
+			expectedValue: DBFMemo("12"),
+		},
+		{
+			name:          "memo_visual_foxpro",
+			fieldType:     'M',
+			visualFoxPro:  true,
+			data:          []byte{0x0c, 0x00, 0x00, 0x00},
+			expectedValue: DBFMemo("12"),
+		},
+		{
+			name:          "memo_visual_foxpro_empty",
+			fieldType:     'M',
+			visualFoxPro:  true,
+			data:          []byte{0x00, 0x00, 0x00, 0x00},
+			expectedValue: DBFMemo(""),
+		},
+		{
+			name:         "memo_visual_foxpro_invalid_length",
+			fieldType:    'M',
+			visualFoxPro: true,
+			data:         []byte("        12"),
+			expectedErr:  "invalid memo field length",
+		},
+		{
+			name:          "sortable_double",
+			fieldType:     'O',
+			data:          []byte{0xbf, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			expectedValue: 1.5,
+		},
+		{
+			name:          "sortable_double_negative",
+			fieldType:     'O',
+			data:          []byte{0x40, 0x07, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			expectedValue: -1.5,
+		},
+		{
+			name:          "timestamp",
+			fieldType:     '@',
+			data:          []byte{0xc2, 0xe8, 0x29, 0xd8, 0x3a, 0xe9, 0xdf, 0x80},
+			expectedValue: time.Date(2023, time.February, 24, 1, 2, 3, 4000000, time.UTC),
+		},
+		{
+			name:      "timestamp_empty",
+			fieldType: '@',
+			data:      make([]byte, 8),
+		},
+		{
+			name:          "varbinary",
+			fieldType:     'Q',
+			data:          []byte{0x01, 0x02},
+			expectedValue: []byte{0x01, 0x02},
+		},
+		{
+			name:          "varchar",
+			fieldType:     'V',
+			data:          []byte("abc "),
+			expectedValue: "abc ",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldDescriptor := &DBFFieldDescriptor{
+				Name:         "FIELD",
+				Type:         tc.fieldType,
+				Length:       len(tc.data),
+				Level7:       tc.level7,
+				VisualFoxPro: tc.visualFoxPro,
+			}
+			value, err := fieldDescriptor.ParseRecord(tc.data, charmap.ISO8859_1.NewDecoder())
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedValue, value)
+		})
+	}
+}
+
+func TestDBFCurrencyString(t *testing.T) {
+	assert.Equal(t, "1234.5678", DBFCurrency(12345678).String())
+	assert.Equal(t, "-0.0001", DBFCurrency(-1).String())
+	assert.Equal(t, "0.0000", DBFCurrency(0).String())
+}
+
+func TestDBFFieldDescriptorFormatRecordCurrency(t *testing.T) {
+	for _, tc := range []struct {
+		value        DBFCurrency
+		decimalCount int
+		expected     string
+	}{
+		{value: 12345678, decimalCount: 4, expected: "   1234.5678"},
+		{value: 12345678, decimalCount: 6, expected: " 1234.567800"},
+		{value: 12345678, decimalCount: 2, expected: "     1234.57"},
+		{value: 12345000, decimalCount: 0, expected: "        1235"},
+		{value: -12345, decimalCount: 3, expected: "      -1.235"},
+		{value: -1, decimalCount: 2, expected: "        0.00"},
+		{value: 9007199254740993, decimalCount: 4, expected: "900719925474.0993"},
+		{value: math.MinInt64, decimalCount: 4, expected: "-922337203685477.5808"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			fieldDescriptor := &DBFFieldDescriptor{
+				Name:         "PRICE",
+				Type:         'N',
+				Length:       max(12, len(tc.expected)),
+				DecimalCount: tc.decimalCount,
+			}
+			actual, err := fieldDescriptor.FormatRecord(tc.value, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+}
+
+func TestParseDBFRecordVarLength(t *testing.T) {
+	fieldDescriptors := []*DBFFieldDescriptor{
+		{Name: "NAME", Type: 'V', Length: 5, Flags: DBFFieldFlagNullable},
+		{Name: "DATA", Type: 'Q', Length: 4},
+		{Name: "_NullFlags", Type: '0', Length: 1, Flags: DBFFieldFlagSystem | DBFFieldFlagBinary},
+	}
+	for _, tc := range []struct {
+		name           string
+		recordData     []byte
+		expectedRecord []any
+	}{
+		{
+			name:           "var_length_and_full",
+			recordData:     []byte(" ab\x00\x00\x02wxyz\x02"),
+			expectedRecord: []any{"ab", []byte("wxyz"), []byte{0x02}},
+		},
+		{
+			name:           "null_and_var_length",
+			recordData:     []byte("      q\x00\x00\x01\x05"),
+			expectedRecord: []any{nil, []byte("q"), []byte{0x05}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			layout, err := newDBFRecordLayout(&DBFHeader{}, fieldDescriptors, nil)
+			assert.NoError(t, err)
+			record, err := parseDBFRecord(tc.recordData, layout, charmap.ISO8859_1.NewDecoder(), nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRecord, record)
+		})
+	}
+}
+
+func TestReadDBFLevel7(t *testing.T) {
+	expectedRecords := [][]any{
+		{"hello", int32(1), int32(1), 1.5, time.Date(2023, time.February, 24, 1, 2, 3, 4000000, time.UTC)},
+		{"world", int32(-1), int32(2), -1.5, nil},
+	}
+
+	shapefile, err := Read(filepath.Join("testdata", "dbase7"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, shapefile.DBF.Version)
+	assert.Equal(t, "DBWINUS0", shapefile.DBF.LanguageDriverName)
+	assert.Equal(t, []string{"A_VERY_LONG_FIELD_NAME", "LONG", "AUTO", "DOUBLE", "TIMESTAMP"}, fieldNames(shapefile.DBF.FieldDescriptors))
+	assert.Equal(t, expectedRecords, shapefile.DBF.Records)
+
+	reader, err := Open(filepath.Join("testdata", "dbase7"), nil)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, reader.Close())
+	}()
+	assert.Equal(t, &shapefile.DBF.DBFHeader, reader.DBFHeader())
+	for i, expectedRecord := range expectedRecords {
+		record, err := reader.DBFRecord(i)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedRecord, record)
+	}
+
+	scanner, err := NewScannerFromBasename(filepath.Join("testdata", "dbase7"), nil)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, scanner.Close())
+	}()
+	scannedShapefile, err := ReadScanner(scanner)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRecords, scannedShapefile.DBF.Records)
+}
+
+func fieldNames(fieldDescriptors []*DBFFieldDescriptor) []string {
+	names := make([]string, 0, len(fieldDescriptors))
+	for _, fieldDescriptor := range fieldDescriptors {
+		names = append(names, fieldDescriptor.Name)
+	}
+	return names
+}
+
+func TestDeletedRecords(t *testing.T) {
+	basename := filepath.Join(t.TempDir(), "deleted")
+	geoms := []geom.T{
+		geom.NewPointFlat(geom.XY, []float64{1, 2}),
+		geom.NewPointFlat(geom.XY, []float64{3, 4}),
+		geom.NewPointFlat(geom.XY, []float64{5, 6}),
+	}
+	fieldDescriptors := []*DBFFieldDescriptor{
+		{Name: "NAME", Type: 'C', Length: 8},
+	}
+
+	shpFile, err := os.Create(basename + ".shp")
+	assert.NoError(t, err)
+	shxFile, err := os.Create(basename + ".shx")
+	assert.NoError(t, err)
+	assert.NoError(t, WriteSHP(shpFile, shxFile, ShapeTypePoint, geoms))
+	assert.NoError(t, shpFile.Close())
+	assert.NoError(t, shxFile.Close())
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, WriteDBF(buffer, fieldDescriptors, [][]any{{"one"}, {"two"}, {"three"}}, nil))
+	data := buffer.Bytes()
+	data[dbfHeaderLength+dbfFieldDescriptorSize+1+(1+8)] = '*'
+	assert.NoError(t, os.WriteFile(basename+".dbf", data, 0o666))
+
+	for _, tc := range []struct {
+		name            string
+		options         *ReadDBFOptions
+		expectedRecords [][]any
+	}{
+		{
+			name:            "default",
+			expectedRecords: [][]any{{"one"}, nil, {"three"}},
+		},
+		{
+			name:            "read_deleted",
+			options:         &ReadDBFOptions{ReadDeleted: true},
+			expectedRecords: [][]any{{"one"}, {"two"}, {"three"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			expectedDeleted := []bool{false, true, false}
+
+			shapefile, err := Read(basename, &ReadShapefileOptions{DBF: tc.options})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRecords, shapefile.DBF.Records)
+			for i, expected := range expectedDeleted {
+				assert.Equal(t, expected, shapefile.DBF.Deleted(i))
+			}
+
+			scanner, err := NewScannerFromBasename(basename, &ReadShapefileOptions{DBF: tc.options})
+			assert.NoError(t, err)
+			defer func() {
+				assert.NoError(t, scanner.Close())
+			}()
+			for i := range geoms {
+				recordSHP, _, recordDBF := scanner.Scan()
+				assert.NoError(t, scanner.Error())
+				assert.Equal(t, geoms[i], recordSHP.Geom)
+				assert.Equal(t, tc.expectedRecords[i], recordDBF)
+				assert.Equal(t, expectedDeleted[i], scanner.Deleted())
+			}
+			assert.Equal(t, int64(len(geoms)), scanner.ScannedRecords())
+
+			reader, err := Open(basename, &ReadShapefileOptions{DBF: tc.options})
+			assert.NoError(t, err)
+			defer func() {
+				assert.NoError(t, reader.Close())
+			}()
+			for i, expected := range expectedDeleted {
+				deleted, err := reader.Deleted(i)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, deleted)
+				record, err := reader.DBFRecord(i)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedRecords[i], record)
+			}
+		})
+	}
+
+	scanner, err := NewScannerFromBasename(basename, nil)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, scanner.Close())
+	}()
+	scannedShapefile, err := ReadScanner(scanner)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(scannedShapefile.DBF.Records))
+	assert.Equal(t, len(scannedShapefile.SHP.Records), len(scannedShapefile.DBF.Records))
+	assert.True(t, scannedShapefile.DBF.Deleted(1))
+}
+
+func TestReadDBFFields(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		basename    string
+		fields      []string
+		expectedErr string
+	}{
+		{
+			name:     "reorder",
+			basename: "poly",
+			fields:   []string{"PRFEDEA", "AREA"},
+		},
+		{
+			name:     "none",
+			basename: "poly",
+			fields:   []string{},
+		},
+		{
+			name:     "null_flags",
+			basename: "vfp",
+			fields:   []string{"COUNT", "NOTES"},
+		},
+		{
+			name:        "unknown_field",
+			basename:    "poly",
+			fields:      []string{"AREA", "UNKNOWN"},
+			expectedErr: "field UNKNOWN: unknown field",
+		},
+		{
+			name:        "duplicate_field",
+			basename:    "poly",
+			fields:      []string{"AREA", "AREA"},
+			expectedErr: "field AREA: duplicate field",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			basename := filepath.Join("testdata", tc.basename)
+			shapefile, err := Read(basename, nil)
+			assert.NoError(t, err)
+			expectedFieldDescriptors := make([]*DBFFieldDescriptor, 0, len(tc.fields))
+			fieldIndexes := make([]int, 0, len(tc.fields))
+			for _, field := range tc.fields {
+				for i, fieldDescriptor := range shapefile.DBF.FieldDescriptors {
+					if fieldDescriptor.Name == field {
+						expectedFieldDescriptors = append(expectedFieldDescriptors, fieldDescriptor)
+						fieldIndexes = append(fieldIndexes, i)
+						break
+					}
+				}
+			}
+			expectedRecords := make([][]any, 0, len(shapefile.DBF.Records))
+			for _, record := range shapefile.DBF.Records {
+				expectedRecord := make([]any, 0, len(fieldIndexes))
+				for _, i := range fieldIndexes {
+					expectedRecord = append(expectedRecord, record[i])
+				}
+				expectedRecords = append(expectedRecords, expectedRecord)
+			}
+
+			options := &ReadShapefileOptions{
+				DBF: &ReadDBFOptions{
+					Fields: tc.fields,
+				},
+			}
+
+			projectedShapefile, err := Read(basename, options)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, expectedFieldDescriptors, projectedShapefile.DBF.FieldDescriptors)
+			assert.Equal(t, expectedRecords, projectedShapefile.DBF.Records)
+
+			reader, err := Open(basename, options)
+			assert.NoError(t, err)
+			defer func() {
+				assert.NoError(t, reader.Close())
+			}()
+			assert.Equal(t, expectedFieldDescriptors, reader.DBFFieldDescriptors())
+			for i, expectedRecord := range expectedRecords {
+				record, err := reader.DBFRecord(i)
+				assert.NoError(t, err)
+				assert.Equal(t, expectedRecord, record)
+			}
+
+			scanner, err := NewScannerFromBasename(basename, options)
+			assert.NoError(t, err)
+			defer func() {
+				assert.NoError(t, scanner.Close())
+			}()
+			scannedShapefile, err := ReadScanner(scanner)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedFieldDescriptors, scannedShapefile.DBF.FieldDescriptors)
+			assert.Equal(t, expectedRecords, scannedShapefile.DBF.Records)
+		})
+	}
+}
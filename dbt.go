@@ -0,0 +1,165 @@
+package shapefile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"golang.org/x/text/encoding"
+)
+
+const (
+	dbtHeaderSize       = 512
+	dbtDefaultBlockSize = 512
+)
+
+// dbtMemoSignature is the signature at the start of each dBase IV memo.
+var dbtMemoSignature = []byte{0xff, 0xff, 0x08, 0x00}
+
+// A DBT is a dBase III or dBase IV .dbt memo file.
+//
+// See https://www.clicketyclick.dk/databases/xbase/format/dbt.html.
+type DBT struct {
+	NextBlock int
+	BlockSize int
+
+	readerAt io.ReaderAt
+	size     int64
+}
+
+// ReadDBT reads a DBT from an io.Reader. If r implements io.ReaderAt then memos
+// are read lazily from r, and so r must remain open while memos are read.
+// Otherwise, r is read into memory.
+func ReadDBT(r io.Reader, size int64) (*DBT, error) {
+	readerAt, ok := r.(io.ReaderAt)
+	if !ok {
+		data, err := readAll(r, size)
+		if err != nil {
+			return nil, err
+		}
+		readerAt = bytes.NewReader(data)
+	}
+
+	if size < dbtHeaderSize {
+		return nil, errors.New("file too short")
+	}
+	headerData := make([]byte, dbtHeaderSize)
+	if _, err := readerAt.ReadAt(headerData, 0); err != nil {
+		return nil, err
+	}
+	nextBlock := int(binary.LittleEndian.Uint32(headerData[:4]))
+	// dBase IV files store the block size in the header. dBase III files store
+	// a version number where dBase IV files have a reserved zero byte, and
+	// always use the default block size.
+	blockSize := int(binary.LittleEndian.Uint16(headerData[20:22]))
+	if blockSize == 0 || headerData[16] != 0 {
+		blockSize = dbtDefaultBlockSize
+	}
+
+	return &DBT{
+		NextBlock: nextBlock,
+		BlockSize: blockSize,
+		readerAt:  readerAt,
+		size:      size,
+	}, nil
+}
+
+// ReadDBTZipFile reads a DBT from a *zip.File. The DBT is read into memory.
+func ReadDBTZipFile(zipFile *zip.File) (*DBT, error) {
+	readCloser, err := zipFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer readCloser.Close()
+	dbt, err := ReadDBT(readCloser, int64(zipFile.UncompressedSize64))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", zipFile.Name, err)
+	}
+	return dbt, nil
+}
+
+// ReadMemo returns the raw data of the memo starting at block. If maxSize is
+// not zero then memos larger than maxSize bytes return an error.
+func (d *DBT) ReadMemo(block, maxSize int) ([]byte, error) {
+	offset := int64(block) * int64(d.BlockSize)
+	if block <= 0 || offset >= d.size {
+		return nil, fmt.Errorf("%d: invalid memo block", block)
+	}
+
+	blockData := make([]byte, min(int64(d.BlockSize), d.size-offset))
+	if _, err := d.readerAt.ReadAt(blockData, offset); err != nil {
+		return nil, err
+	}
+
+	// dBase IV memos start with a signature and their length.
+	if len(blockData) >= 8 && bytes.Equal(blockData[:4], dbtMemoSignature) {
+		length := int64(binary.LittleEndian.Uint32(blockData[4:8])) - 8
+		if length < 0 || offset+8+length > d.size {
+			return nil, fmt.Errorf("%d: invalid memo length", block)
+		}
+		if maxSize != 0 && length > int64(maxSize) {
+			return nil, errors.New("memo too large")
+		}
+		data := make([]byte, length)
+		if _, err := d.readerAt.ReadAt(data, offset+8); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	// dBase III memos are terminated by an end of file marker.
+	var data []byte
+	for {
+		if i := bytes.IndexByte(blockData, '\x1a'); i != -1 {
+			data = append(data, blockData[:i]...)
+			break
+		}
+		data = append(data, blockData...)
+		offset += int64(len(blockData))
+		if offset >= d.size {
+			break
+		}
+		if maxSize != 0 && len(data) > maxSize {
+			return nil, errors.New("memo too large")
+		}
+		blockData = blockData[:min(int64(d.BlockSize), d.size-offset)]
+		if _, err := d.readerAt.ReadAt(blockData, offset); err != nil {
+			return nil, err
+		}
+	}
+	if maxSize != 0 && len(data) > maxSize {
+		return nil, errors.New("memo too large")
+	}
+	return data, nil
+}
+
+// parseMemo returns the value of memo. Memos in memo fields are decoded with
+// decoder and returned as strings, memos in other fields are returned as
+// []bytes. It returns nil if memo does not reference a block.
+func (d *DBT) parseMemo(memo DBFMemo, fieldType byte, decoder *encoding.Decoder, maxSize int) (any, error) {
+	if memo == "" {
+		return nil, nil
+	}
+	block, err := strconv.Atoi(string(memo))
+	if err != nil {
+		return nil, fmt.Errorf("%q: invalid memo block: %w", memo, err)
+	}
+	if block == 0 {
+		return nil, nil
+	}
+	data, err := d.ReadMemo(block, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if fieldType != 'M' {
+		return data, nil
+	}
+	if decoder == nil {
+		return nil, errors.New("decoder is nil")
+	}
+	return decoder.String(string(data))
+}
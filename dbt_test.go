@@ -0,0 +1,137 @@
+package shapefile
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func FuzzReadDBT(f *testing.F) {
+	assert.NoError(f, addFuzzDataFromFS(f, os.DirFS("."), "testdata", ".dbt"))
+
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		dbt, err := ReadDBT(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		_, _ = dbt.ReadMemo(1, 4096)
+	})
+}
+
+func TestReadDBTMemos(t *testing.T) {
+	longMemo := strings.Repeat("Long memo. ", 60)
+	for _, tc := range []struct {
+		basename          string
+		expectedBlockSize int
+	}{
+		{
+			basename:          "memo3",
+			expectedBlockSize: 512,
+		},
+		{
+			basename:          "memo4",
+			expectedBlockSize: 64,
+		},
+	} {
+		t.Run(tc.basename, func(t *testing.T) {
+			expectedRecords := [][]any{
+				{"one", "Short memo"},
+				{"two", longMemo},
+				{"three", nil},
+			}
+
+			shapefile, err := Read(filepath.Join("testdata", tc.basename), nil)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedRecords, shapefile.DBF.Records)
+
+			reader, err := Open(filepath.Join("testdata", tc.basename), nil)
+			assert.NoError(t, err)
+			defer func() {
+				assert.NoError(t, reader.Close())
+			}()
+			for i, expectedRecord := range expectedRecords {
+				record, err := reader.DBFRecord(i)
+				assert.NoError(t, err)
+				assert.Equal(t, expectedRecord, record)
+			}
+
+			scanner, err := NewScannerFromBasename(filepath.Join("testdata", tc.basename), nil)
+			assert.NoError(t, err)
+			defer func() {
+				assert.NoError(t, scanner.Close())
+			}()
+			scannedShapefile, err := ReadScanner(scanner)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedRecords, scannedShapefile.DBF.Records)
+
+			dbtFile, err := os.Open(filepath.Join("testdata", tc.basename+".dbt"))
+			assert.NoError(t, err)
+			defer dbtFile.Close()
+			fileInfo, err := dbtFile.Stat()
+			assert.NoError(t, err)
+			dbt, err := ReadDBT(dbtFile, fileInfo.Size())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedBlockSize, dbt.BlockSize)
+
+			dbfFile, err := os.Open(filepath.Join("testdata", tc.basename+".dbf"))
+			assert.NoError(t, err)
+			defer dbfFile.Close()
+			fileInfo, err = dbfFile.Stat()
+			assert.NoError(t, err)
+			_, err = ReadDBF(dbfFile, fileInfo.Size(), &ReadDBFOptions{
+				DBT:         dbt,
+				MaxMemoSize: 100,
+			})
+			assert.EqualError(t, err, "field NOTES: memo too large")
+		})
+	}
+}
+
+func TestReadDBFMemoWithoutDBT(t *testing.T) {
+	dbfFile, err := os.Open(filepath.Join("testdata", "memo3.dbf"))
+	assert.NoError(t, err)
+	defer dbfFile.Close()
+	fileInfo, err := dbfFile.Stat()
+	assert.NoError(t, err)
+	dbf, err := ReadDBF(dbfFile, fileInfo.Size(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{
+		{"one", DBFMemo("1")},
+		{"two", DBFMemo("2")},
+		{"three", DBFMemo("")},
+	}, dbf.Records)
+}
+
+func TestReadDBTOptionsNotModified(t *testing.T) {
+	options := &ReadShapefileOptions{
+		DBF: &ReadDBFOptions{},
+	}
+	basename := filepath.Join("testdata", "memo3")
+
+	_, err := Read(basename, options)
+	assert.NoError(t, err)
+	_, err = ReadFS(os.DirFS("testdata"), "memo3", options)
+	assert.NoError(t, err)
+	reader, err := Open(basename, options)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	scanner, err := NewScannerFromBasename(basename, options)
+	assert.NoError(t, err)
+	assert.NoError(t, scanner.Close())
+
+	assert.Equal(t, &ReadShapefileOptions{DBF: &ReadDBFOptions{}}, options)
+}
+
+func TestReadDBTInvalidSize(t *testing.T) {
+	data, err := os.ReadFile("testdata/memo3.dbt")
+	assert.NoError(t, err)
+	// Hide the io.ReaderAt implementation so that ReadDBT reads into memory.
+	r := struct{ io.Reader }{bytes.NewReader(data)}
+	_, err = ReadDBT(r, 1<<62)
+	assert.IsError(t, err, io.ErrUnexpectedEOF)
+}
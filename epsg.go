@@ -0,0 +1,718 @@
+package shapefile
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// An EPSGConfidence is the confidence of an EPSG code returned by PRJ.EPSG.
+type EPSGConfidence int
+
+// EPSG confidences, in increasing order of confidence.
+const (
+	EPSGConfidenceNone       EPSGConfidence = iota // No match.
+	EPSGConfidenceParameters                       // The parameters match but the name does not.
+	EPSGConfidenceName                             // The name and parameters match.
+	EPSGConfidenceAuthority                        // The WKT contains an EPSG authority code.
+)
+
+// An epsgGeogCS is a known geographic coordinate reference system. All known
+// geographic coordinate reference systems use the Greenwich prime meridian and
+// degrees.
+type epsgGeogCS struct {
+	code              int
+	names             []string // names[0] is the EPSG name, names[1] is the ESRI name.
+	datumNames        []string // datumNames[0] is the ESRI name.
+	spheroidName      string
+	semiMajorAxis     float64
+	inverseFlattening float64
+	toWGS84           []float64 // TOWGS84 parameters used by Transformer.
+}
+
+// An epsgProjCS is a known projected coordinate reference system. All known
+// projected coordinate reference systems use meters.
+type epsgProjCS struct {
+	code       int
+	names      []string // names[0] is the EPSG name, names[1] is the ESRI name.
+	geogCSCode int
+	projection string // ESRI projection name.
+	parameters []*PRJParameter
+}
+
+// epsgGeogCSs are the known geographic coordinate reference systems.
+var epsgGeogCSs = []*epsgGeogCS{
+	{
+		code:              4326,
+		names:             []string{"WGS 84", "GCS_WGS_1984"},
+		datumNames:        []string{"D_WGS_1984", "World_Geodetic_System_1984"},
+		spheroidName:      "WGS_1984",
+		semiMajorAxis:     6378137,
+		inverseFlattening: 298.257223563,
+		toWGS84:           []float64{0, 0, 0},
+	},
+	{
+		code:              4258,
+		names:             []string{"ETRS89", "GCS_ETRS_1989"},
+		datumNames:        []string{"D_ETRS_1989", "European_Terrestrial_Reference_System_1989"},
+		spheroidName:      "GRS_1980",
+		semiMajorAxis:     6378137,
+		inverseFlattening: 298.257222101,
+		toWGS84:           []float64{0, 0, 0},
+	},
+	{
+		code:              4269,
+		names:             []string{"NAD83", "GCS_North_American_1983"},
+		datumNames:        []string{"D_North_American_1983", "North_American_Datum_1983"},
+		spheroidName:      "GRS_1980",
+		semiMajorAxis:     6378137,
+		inverseFlattening: 298.257222101,
+		toWGS84:           []float64{0, 0, 0},
+	},
+	{
+		code:              4267,
+		names:             []string{"NAD27", "GCS_North_American_1927"},
+		datumNames:        []string{"D_North_American_1927", "North_American_Datum_1927"},
+		spheroidName:      "Clarke_1866",
+		semiMajorAxis:     6378206.4,
+		inverseFlattening: 294.9786982,
+		toWGS84:           []float64{-8, 160, 176},
+	},
+	{
+		code:              4277,
+		names:             []string{"OSGB36", "GCS_OSGB_1936", "OSGB 1936"},
+		datumNames:        []string{"D_OSGB_1936", "Ordnance_Survey_of_Great_Britain_1936"},
+		spheroidName:      "Airy_1830",
+		semiMajorAxis:     6377563.396,
+		inverseFlattening: 299.3249646,
+		toWGS84:           []float64{446.448, -125.157, 542.06, 0.15, 0.247, 0.842, -20.489},
+	},
+	{
+		code:              4230,
+		names:             []string{"ED50", "GCS_European_1950"},
+		datumNames:        []string{"D_European_1950", "European_Datum_1950"},
+		spheroidName:      "International_1924",
+		semiMajorAxis:     6378388,
+		inverseFlattening: 297,
+		toWGS84:           []float64{-87, -98, -121},
+	},
+	{
+		code:              4283,
+		names:             []string{"GDA94", "GCS_GDA_1994"},
+		datumNames:        []string{"D_GDA_1994", "Geocentric_Datum_of_Australia_1994"},
+		spheroidName:      "GRS_1980",
+		semiMajorAxis:     6378137,
+		inverseFlattening: 298.257222101,
+		toWGS84:           []float64{0, 0, 0},
+	},
+	{
+		code:              4171,
+		names:             []string{"RGF93 v1", "GCS_RGF_1993", "RGF93"},
+		datumNames:        []string{"D_RGF_1993", "Reseau_Geodesique_Francais_1993"},
+		spheroidName:      "GRS_1980",
+		semiMajorAxis:     6378137,
+		inverseFlattening: 298.257222101,
+		toWGS84:           []float64{0, 0, 0},
+	},
+	{
+		code:              4150,
+		names:             []string{"CH1903+", "GCS_CH1903+"},
+		datumNames:        []string{"D_CH1903+"},
+		spheroidName:      "Bessel_1841",
+		semiMajorAxis:     6377397.155,
+		inverseFlattening: 299.1528128,
+		toWGS84:           []float64{674.374, 15.056, 405.346},
+	},
+	{
+		code:              4314,
+		names:             []string{"DHDN", "GCS_Deutsches_Hauptdreiecksnetz"},
+		datumNames:        []string{"D_Deutsches_Hauptdreiecksnetz"},
+		spheroidName:      "Bessel_1841",
+		semiMajorAxis:     6377397.155,
+		inverseFlattening: 299.1528128,
+		toWGS84:           []float64{598.1, 73.7, 418.2, 0.202, 0.045, -2.455, 6.7},
+	},
+	{
+		code:              4167,
+		names:             []string{"NZGD2000", "GCS_NZGD_2000"},
+		datumNames:        []string{"D_NZGD_2000", "New_Zealand_Geodetic_Datum_2000"},
+		spheroidName:      "GRS_1980",
+		semiMajorAxis:     6378137,
+		inverseFlattening: 298.257222101,
+		toWGS84:           []float64{0, 0, 0},
+	},
+}
+
+// epsgProjCSs returns the known projected coordinate reference systems.
+var epsgProjCSs = sync.OnceValue(func() []*epsgProjCS {
+	projCSs := []*epsgProjCS{
+		{
+			code:       3857,
+			names:      []string{"WGS 84 / Pseudo-Mercator", "WGS_1984_Web_Mercator_Auxiliary_Sphere"},
+			geogCSCode: 4326,
+			projection: "Mercator_Auxiliary_Sphere",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 0},
+				{Name: "False_Northing", Value: 0},
+				{Name: "Central_Meridian", Value: 0},
+				{Name: "Standard_Parallel_1", Value: 0},
+				{Name: "Auxiliary_Sphere_Type", Value: 0},
+			},
+		},
+		{
+			code:       3395,
+			names:      []string{"WGS 84 / World Mercator", "WGS_1984_World_Mercator"},
+			geogCSCode: 4326,
+			projection: "Mercator",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 0},
+				{Name: "False_Northing", Value: 0},
+				{Name: "Central_Meridian", Value: 0},
+				{Name: "Standard_Parallel_1", Value: 0},
+			},
+		},
+		{
+			code:       3035,
+			names:      []string{"ETRS89-extended / LAEA Europe", "ETRS_1989_LAEA", "ETRS89 / LAEA Europe"},
+			geogCSCode: 4258,
+			projection: "Lambert_Azimuthal_Equal_Area",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 4321000},
+				{Name: "False_Northing", Value: 3210000},
+				{Name: "Central_Meridian", Value: 10},
+				{Name: "Latitude_Of_Origin", Value: 52},
+			},
+		},
+		{
+			code:       3034,
+			names:      []string{"ETRS89-extended / LCC Europe", "ETRS_1989_LCC", "ETRS89 / LCC Europe"},
+			geogCSCode: 4258,
+			projection: "Lambert_Conformal_Conic",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 4000000},
+				{Name: "False_Northing", Value: 2800000},
+				{Name: "Central_Meridian", Value: 10},
+				{Name: "Standard_Parallel_1", Value: 35},
+				{Name: "Standard_Parallel_2", Value: 65},
+				{Name: "Latitude_Of_Origin", Value: 52},
+			},
+		},
+		{
+			code:       27700,
+			names:      []string{"OSGB36 / British National Grid", "British_National_Grid", "OSGB 1936 / British National Grid"},
+			geogCSCode: 4277,
+			projection: "Transverse_Mercator",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 400000},
+				{Name: "False_Northing", Value: -100000},
+				{Name: "Central_Meridian", Value: -2},
+				{Name: "Scale_Factor", Value: 0.9996012717},
+				{Name: "Latitude_Of_Origin", Value: 49},
+			},
+		},
+		{
+			code:       2154,
+			names:      []string{"RGF93 v1 / Lambert-93", "RGF_1993_Lambert_93", "RGF93 / Lambert-93"},
+			geogCSCode: 4171,
+			projection: "Lambert_Conformal_Conic",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 700000},
+				{Name: "False_Northing", Value: 6600000},
+				{Name: "Central_Meridian", Value: 3},
+				{Name: "Standard_Parallel_1", Value: 49},
+				{Name: "Standard_Parallel_2", Value: 44},
+				{Name: "Latitude_Of_Origin", Value: 46.5},
+			},
+		},
+		{
+			code:       2056,
+			names:      []string{"CH1903+ / LV95", "CH1903+_LV95"},
+			geogCSCode: 4150,
+			projection: "Hotine_Oblique_Mercator_Azimuth_Center",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 2600000},
+				{Name: "False_Northing", Value: 1200000},
+				{Name: "Scale_Factor", Value: 1},
+				{Name: "Azimuth", Value: 90},
+				{Name: "Longitude_Of_Center", Value: 7.439583333333333},
+				{Name: "Latitude_Of_Center", Value: 46.95240555555556},
+			},
+		},
+		{
+			code:       31467,
+			names:      []string{"DHDN / 3-degree Gauss-Kruger zone 3", "DHDN_3_Degree_Gauss_Zone_3"},
+			geogCSCode: 4314,
+			projection: "Transverse_Mercator",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 3500000},
+				{Name: "False_Northing", Value: 0},
+				{Name: "Central_Meridian", Value: 9},
+				{Name: "Scale_Factor", Value: 1},
+				{Name: "Latitude_Of_Origin", Value: 0},
+			},
+		},
+		{
+			code:       2193,
+			names:      []string{"NZGD2000 / New Zealand Transverse Mercator 2000", "NZGD_2000_New_Zealand_Transverse_Mercator"},
+			geogCSCode: 4167,
+			projection: "Transverse_Mercator",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 1600000},
+				{Name: "False_Northing", Value: 10000000},
+				{Name: "Central_Meridian", Value: 173},
+				{Name: "Scale_Factor", Value: 0.9996},
+				{Name: "Latitude_Of_Origin", Value: 0},
+			},
+		},
+		{
+			code:       3577,
+			names:      []string{"GDA94 / Australian Albers", "GDA_1994_Australia_Albers"},
+			geogCSCode: 4283,
+			projection: "Albers",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 0},
+				{Name: "False_Northing", Value: 0},
+				{Name: "Central_Meridian", Value: 132},
+				{Name: "Standard_Parallel_1", Value: -18},
+				{Name: "Standard_Parallel_2", Value: -36},
+				{Name: "Latitude_Of_Origin", Value: 0},
+			},
+		},
+		{
+			code:       5070,
+			names:      []string{"NAD83 / Conus Albers", "NAD_1983_Contiguous_USA_Albers"},
+			geogCSCode: 4269,
+			projection: "Albers",
+			parameters: []*PRJParameter{
+				{Name: "False_Easting", Value: 0},
+				{Name: "False_Northing", Value: 0},
+				{Name: "Central_Meridian", Value: -96},
+				{Name: "Standard_Parallel_1", Value: 29.5},
+				{Name: "Standard_Parallel_2", Value: 45.5},
+				{Name: "Latitude_Of_Origin", Value: 23},
+			},
+		},
+	}
+
+	for _, utm := range []struct {
+		baseCode      int
+		geogCSCode    int
+		epsgFormat    string
+		esriFormat    string
+		minZone       int
+		maxZone       int
+		falseNorthing float64
+	}{
+		{32600, 4326, "WGS 84 / UTM zone %dN", "WGS_1984_UTM_Zone_%dN", 1, 60, 0},
+		{32700, 4326, "WGS 84 / UTM zone %dS", "WGS_1984_UTM_Zone_%dS", 1, 60, 10000000},
+		{25800, 4258, "ETRS89 / UTM zone %dN", "ETRS_1989_UTM_Zone_%dN", 28, 38, 0},
+		{26900, 4269, "NAD83 / UTM zone %dN", "NAD_1983_UTM_Zone_%dN", 1, 23, 0},
+		{26700, 4267, "NAD27 / UTM zone %dN", "NAD_1927_UTM_Zone_%dN", 1, 22, 0},
+		{23000, 4230, "ED50 / UTM zone %dN", "ED_1950_UTM_Zone_%dN", 28, 38, 0},
+		{28300, 4283, "GDA94 / MGA zone %d", "GDA_1994_MGA_Zone_%d", 48, 58, 10000000},
+	} {
+		for zone := utm.minZone; zone <= utm.maxZone; zone++ {
+			projCSs = append(projCSs, &epsgProjCS{
+				code:       utm.baseCode + zone,
+				names:      []string{fmt.Sprintf(utm.epsgFormat, zone), fmt.Sprintf(utm.esriFormat, zone)},
+				geogCSCode: utm.geogCSCode,
+				projection: "Transverse_Mercator",
+				parameters: []*PRJParameter{
+					{Name: "False_Easting", Value: 500000},
+					{Name: "False_Northing", Value: utm.falseNorthing},
+					{Name: "Central_Meridian", Value: float64(6*zone - 183)},
+					{Name: "Scale_Factor", Value: 0.9996},
+					{Name: "Latitude_Of_Origin", Value: 0},
+				},
+			})
+		}
+	}
+
+	return projCSs
+})
+
+// esriProjectionNames maps normalized OGC and other projection names to the
+// ESRI projection names used in epsgProjCSs and ESRI WKT. Projections with the
+// same name in OGC and ESRI WKT are not included.
+var esriProjectionNames = map[string]string{
+	"albersconicequalarea":               "Albers",
+	"equirectangular":                    "Equidistant_Cylindrical",
+	"gausskruger":                        "Transverse_Mercator",
+	"hotineobliquemercator":              "Hotine_Oblique_Mercator_Azimuth_Natural_Origin",
+	"lambertconformalconic1sp":           "Lambert_Conformal_Conic",
+	"lambertconformalconic2sp":           "Lambert_Conformal_Conic",
+	"mercator1sp":                        "Mercator",
+	"mercator2sp":                        "Mercator",
+	"obliquemercator":                    "Hotine_Oblique_Mercator_Azimuth_Center",
+	"obliquestereographic":               "Double_Stereographic",
+	"popularvisualisationpseudomercator": "Mercator_Auxiliary_Sphere",
+}
+
+// esriParameterNames maps normalized parameter names to ESRI parameter names.
+var esriParameterNames = map[string]string{
+	"auxiliaryspheretype": "Auxiliary_Sphere_Type",
+	"azimuth":             "Azimuth",
+	"centralmeridian":     "Central_Meridian",
+	"falseeasting":        "False_Easting",
+	"falsenorthing":       "False_Northing",
+	"latitudeofcenter":    "Latitude_Of_Center",
+	"latitudeoforigin":    "Latitude_Of_Origin",
+	"longitudeofcenter":   "Longitude_Of_Center",
+	"scalefactor":         "Scale_Factor",
+	"standardparallel1":   "Standard_Parallel_1",
+	"standardparallel2":   "Standard_Parallel_2",
+}
+
+// esriUnitNames maps normalized unit names to ESRI unit names.
+var esriUnitNames = map[string]string{
+	"degree":            "Degree",
+	"foot":              "Foot",
+	"footus":            "Foot_US",
+	"grad":              "Grad",
+	"internationalfoot": "Foot",
+	"kilometer":         "Kilometer",
+	"kilometre":         "Kilometer",
+	"meter":             "Meter",
+	"metre":             "Meter",
+	"radian":            "Radian",
+	"ussurveyfoot":      "Foot_US",
+	"usurveyfoot":       "Foot_US",
+}
+
+// epsgParameterAliases maps normalized parameter names to canonical
+// normalized parameter names.
+var epsgParameterAliases = map[string]string{
+	"latitudeofcenter":  "latitudeoforigin",
+	"longitudeofcenter": "centralmeridian",
+	"longitudeoforigin": "centralmeridian",
+}
+
+// epsgParameterDefaults are the values of parameters that are not present.
+var epsgParameterDefaults = map[string]float64{
+	"scalefactor": 1,
+}
+
+// PRJFromEPSG returns a new PRJ for the coordinate reference system with the
+// EPSG code, from the same table of common coordinate reference systems used
+// by PRJ.EPSG. The PRJ's Projection is ESRI WKT.
+func PRJFromEPSG(code int) (*PRJ, error) {
+	prj := &PRJ{}
+	if index := slices.IndexFunc(epsgGeogCSs, func(geogCS *epsgGeogCS) bool {
+		return geogCS.code == code
+	}); index != -1 {
+		prj.GeogCS = epsgGeogCSs[index].prjGeogCS()
+	} else if index := slices.IndexFunc(epsgProjCSs(), func(projCS *epsgProjCS) bool {
+		return projCS.code == code
+	}); index != -1 {
+		prj.ProjCS = epsgProjCSs()[index].prjProjCS()
+	} else {
+		return nil, fmt.Errorf("%d: unknown EPSG code", code)
+	}
+	projection, err := prj.ESRIWKT()
+	if err != nil {
+		return nil, err
+	}
+	// Parse the WKT so that values are exactly as if the PRJ were read.
+	return ParsePRJ(projection)
+}
+
+// EPSG returns the EPSG code of p and the confidence of the match. If p's
+// WKT contains an EPSG authority code then it is returned. Otherwise, p is
+// compared with a table of common coordinate reference systems, matching
+// first by parameters (datum, spheroid, projection, projection parameters,
+// and units) and then by name. If no coordinate reference system in the
+// table has the same parameters, or p could not be parsed, then EPSG returns
+// zero and EPSGConfidenceNone.
+func (p *PRJ) EPSG() (int, EPSGConfidence) {
+	switch {
+	case p.ProjCS != nil:
+		if code, ok := p.ProjCS.Authority.epsgCode(); ok {
+			return code, EPSGConfidenceAuthority
+		}
+		return matchEPSG(epsgProjCSs(), p.ProjCS.Name, func(projCS *epsgProjCS) (int, []string, bool) {
+			return projCS.code, projCS.names, projCS.matches(p.ProjCS)
+		})
+	case p.GeogCS != nil:
+		if code, ok := p.GeogCS.Authority.epsgCode(); ok {
+			return code, EPSGConfidenceAuthority
+		}
+		return matchEPSG(epsgGeogCSs, p.GeogCS.Name, func(geogCS *epsgGeogCS) (int, []string, bool) {
+			return geogCS.code, geogCS.names, geogCS.matches(p.GeogCS)
+		})
+	default:
+		return 0, EPSGConfidenceNone
+	}
+}
+
+// matchEPSG returns the code of the first of crss whose parameters and name
+// match, or otherwise the code of the first of crss whose parameters match.
+// match returns a coordinate reference system's code, its names, and if its
+// parameters match.
+func matchEPSG[T any](crss []T, name string, match func(T) (int, []string, bool)) (int, EPSGConfidence) {
+	normalizedName := normalizeEPSGName(name)
+	code, confidence := 0, EPSGConfidenceNone
+	for _, crs := range crss {
+		crsCode, crsNames, ok := match(crs)
+		if !ok {
+			continue
+		}
+		for _, crsName := range crsNames {
+			if normalizeEPSGName(crsName) == normalizedName {
+				return crsCode, EPSGConfidenceName
+			}
+		}
+		if confidence == EPSGConfidenceNone {
+			code, confidence = crsCode, EPSGConfidenceParameters
+		}
+	}
+	return code, confidence
+}
+
+// epsgCode returns a's code if a is an EPSG authority.
+func (a *PRJAuthority) epsgCode() (int, bool) {
+	if a == nil || !strings.EqualFold(a.Name, "EPSG") {
+		return 0, false
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(a.Code))
+	if err != nil {
+		return 0, false
+	}
+	return code, true
+}
+
+// prjGeogCS returns a new PRJGeogCS for g.
+func (g *epsgGeogCS) prjGeogCS() *PRJGeogCS {
+	return &PRJGeogCS{
+		Name: g.names[1],
+		Datum: &PRJDatum{
+			Name: g.datumNames[0],
+			Spheroid: &PRJSpheroid{
+				Name:              g.spheroidName,
+				SemiMajorAxis:     g.semiMajorAxis,
+				InverseFlattening: g.inverseFlattening,
+			},
+		},
+		PrimeMeridian: &PRJPrimeMeridian{
+			Name: "Greenwich",
+		},
+		AngularUnit: &PRJUnit{
+			Name:             "Degree",
+			ConversionFactor: math.Pi / 180,
+		},
+	}
+}
+
+// matches returns if g has the same parameters as geogCS.
+func (g *epsgGeogCS) matches(geogCS *PRJGeogCS) bool {
+	if geogCS.Datum == nil || geogCS.Datum.Spheroid == nil || geogCS.PrimeMeridian == nil || geogCS.AngularUnit == nil {
+		return false
+	}
+	datumName := normalizeEPSGDatumName(geogCS.Datum.Name)
+	return slices.ContainsFunc(g.datumNames, func(name string) bool {
+		return normalizeEPSGDatumName(name) == datumName
+	}) &&
+		epsgValuesEqual(geogCS.Datum.Spheroid.SemiMajorAxis, g.semiMajorAxis) &&
+		epsgValuesEqual(geogCS.Datum.Spheroid.InverseFlattening, g.inverseFlattening) &&
+		epsgValuesEqual(geogCS.PrimeMeridian.Longitude, 0) &&
+		epsgValuesEqual(geogCS.AngularUnit.ConversionFactor, math.Pi/180)
+}
+
+// prjProjCS returns a new PRJProjCS for p.
+func (p *epsgProjCS) prjProjCS() *PRJProjCS {
+	parameters := make([]*PRJParameter, 0, len(p.parameters))
+	for _, parameter := range p.parameters {
+		parameters = append(parameters, &PRJParameter{
+			Name:  parameter.Name,
+			Value: parameter.Value,
+		})
+	}
+	return &PRJProjCS{
+		Name:       p.names[1],
+		GeogCS:     p.geogCS().prjGeogCS(),
+		Projection: p.projection,
+		Parameters: parameters,
+		LinearUnit: &PRJUnit{
+			Name:             "Meter",
+			ConversionFactor: 1,
+		},
+	}
+}
+
+// geogCS returns p's geographic coordinate reference system.
+func (p *epsgProjCS) geogCS() *epsgGeogCS {
+	for _, geogCS := range epsgGeogCSs {
+		if geogCS.code == p.geogCSCode {
+			return geogCS
+		}
+	}
+	return nil
+}
+
+// matches returns if p has the same parameters as projCS.
+func (p *epsgProjCS) matches(projCS *PRJProjCS) bool {
+	if projCS.GeogCS == nil || projCS.LinearUnit == nil ||
+		!p.geogCS().matches(projCS.GeogCS) ||
+		normalizeEPSGProjection(projCS.Projection) != normalizeEPSGProjection(p.projection) ||
+		!epsgValuesEqual(projCS.LinearUnit.ConversionFactor, 1) {
+		return false
+	}
+	return epsgParametersEqual(p.parameters, projCS.Parameters)
+}
+
+// epsgParametersEqual returns if parameters1 and parameters2 have the same
+// values, after normalizing names and applying defaults.
+func epsgParametersEqual(parameters1, parameters2 []*PRJParameter) bool {
+	values1 := epsgParameterValues(parameters1)
+	values2 := epsgParameterValues(parameters2)
+	for name, value1 := range values1 {
+		value2, ok := values2[name]
+		if !ok {
+			value2 = epsgParameterDefaults[name]
+		}
+		if !epsgValuesEqual(value1, value2) {
+			return false
+		}
+	}
+	for name, value2 := range values2 {
+		if _, ok := values1[name]; !ok && !epsgValuesEqual(epsgParameterDefaults[name], value2) {
+			return false
+		}
+	}
+	return true
+}
+
+// epsgParameterValues returns a map of normalized parameter names to values.
+func epsgParameterValues(parameters []*PRJParameter) map[string]float64 {
+	values := make(map[string]float64, len(parameters))
+	for _, parameter := range parameters {
+		name := normalizeEPSGName(parameter.Name)
+		if alias, ok := epsgParameterAliases[name]; ok {
+			name = alias
+		}
+		values[name] = parameter.Value
+	}
+	return values
+}
+
+// epsgValuesEqual returns if value1 and value2 are equal, allowing for
+// rounding errors.
+func epsgValuesEqual(value1, value2 float64) bool {
+	return math.Abs(value1-value2) <= 1e-9*max(1, math.Abs(value1), math.Abs(value2))
+}
+
+// normalizeEPSGName returns name in lowercase with all characters except
+// letters and digits removed.
+func normalizeEPSGName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z' || '0' <= r && r <= '9':
+			return r
+		case 'A' <= r && r <= 'Z':
+			return r - 'A' + 'a'
+		case r == '+':
+			return r
+		default:
+			return -1
+		}
+	}, name)
+}
+
+// normalizeEPSGDatumName returns the normalized datum name, without any ESRI
+// D_ prefix.
+func normalizeEPSGDatumName(name string) string {
+	if len(name) >= 2 && strings.EqualFold(name[:2], "D_") {
+		name = name[2:]
+	}
+	return normalizeEPSGName(name)
+}
+
+// normalizeEPSGProjection returns the normalized ESRI projection name.
+func normalizeEPSGProjection(projection string) string {
+	return normalizeEPSGName(esriProjectionName(projection))
+}
+
+// esriGeogCSNames returns the ESRI names of g, its datum, and its spheroid. The
+// names of known geographic coordinate reference systems are taken from
+// epsgGeogCSs. Otherwise, spaces are replaced with underscores and the datum
+// name is given a D_ prefix.
+func esriGeogCSNames(g *PRJGeogCS) (name, datumName, spheroidName string) {
+	name = g.Name
+	datumName = strings.ReplaceAll(g.Datum.Name, " ", "_")
+	if len(datumName) < 2 || !strings.EqualFold(datumName[:2], "D_") {
+		datumName = "D_" + datumName
+	}
+	spheroidName = strings.ReplaceAll(g.Datum.Spheroid.Name, " ", "_")
+	normalizedName := normalizeEPSGName(g.Name)
+	normalizedDatumName := normalizeEPSGDatumName(g.Datum.Name)
+	for _, geogCS := range epsgGeogCSs {
+		if !slices.ContainsFunc(geogCS.datumNames, func(name string) bool {
+			return normalizeEPSGDatumName(name) == normalizedDatumName
+		}) {
+			continue
+		}
+		datumName = geogCS.datumNames[0]
+		if epsgValuesEqual(g.Datum.Spheroid.SemiMajorAxis, geogCS.semiMajorAxis) &&
+			epsgValuesEqual(g.Datum.Spheroid.InverseFlattening, geogCS.inverseFlattening) {
+			spheroidName = geogCS.spheroidName
+		}
+		if slices.ContainsFunc(geogCS.names, func(name string) bool {
+			return normalizeEPSGName(name) == normalizedName
+		}) {
+			name = geogCS.names[1]
+		}
+		break
+	}
+	return name, datumName, spheroidName
+}
+
+// esriProjCSName returns the ESRI name of p. The names of known projected
+// coordinate reference systems are taken from epsgProjCSs, other names are
+// unchanged.
+func esriProjCSName(p *PRJProjCS) string {
+	if code, confidence := matchEPSG(epsgProjCSs(), p.Name, func(projCS *epsgProjCS) (int, []string, bool) {
+		return projCS.code, projCS.names, projCS.matches(p)
+	}); confidence == EPSGConfidenceName {
+		for _, projCS := range epsgProjCSs() {
+			if projCS.code == code {
+				return projCS.names[1]
+			}
+		}
+	}
+	return p.Name
+}
+
+// esriProjectionName returns the ESRI name of projection.
+func esriProjectionName(projection string) string {
+	if esriName, ok := esriProjectionNames[normalizeEPSGName(projection)]; ok {
+		return esriName
+	}
+	return projection
+}
+
+// esriParameterName returns the ESRI name of the parameter name of
+// projection. ESRI only uses Longitude_Of_Center and Latitude_Of_Center for
+// Hotine oblique Mercator projections, other projections use
+// Central_Meridian and Latitude_Of_Origin.
+func esriParameterName(projection, name string) string {
+	normalizedName := normalizeEPSGName(name)
+	if !strings.HasPrefix(normalizeEPSGProjection(projection), "hotineobliquemercator") {
+		if alias, ok := epsgParameterAliases[normalizedName]; ok {
+			normalizedName = alias
+		}
+	}
+	if esriName, ok := esriParameterNames[normalizedName]; ok {
+		return esriName
+	}
+	return name
+}
+
+// esriUnitName returns the ESRI name of the unit name.
+func esriUnitName(name string) string {
+	if esriName, ok := esriUnitNames[normalizeEPSGName(name)]; ok {
+		return esriName
+	}
+	return name
+}
@@ -0,0 +1,167 @@
+package shapefile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+const testUTM32NESRIWKT = `PROJCS["WGS_1984_UTM_Zone_32N",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",500000.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",9.0],PARAMETER["Scale_Factor",0.9996],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`
+
+func TestPRJEPSG(t *testing.T) {
+	polyPRJ, err := os.ReadFile(filepath.Join("testdata", "poly.prj"))
+	assert.NoError(t, err)
+	utm32N := testUTM32NESRIWKT
+
+	for _, tc := range []struct {
+		name               string
+		projection         string
+		expectedCode       int
+		expectedConfidence EPSGConfidence
+	}{
+		{
+			name:               "poly",
+			projection:         string(polyPRJ),
+			expectedCode:       27700,
+			expectedConfidence: EPSGConfidenceName,
+		},
+		{
+			name:               "wgs84_esri",
+			projection:         `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`,
+			expectedCode:       4326,
+			expectedConfidence: EPSGConfidenceName,
+		},
+		{
+			name:               "wgs84_ogc",
+			projection:         `GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]]`,
+			expectedCode:       4326,
+			expectedConfidence: EPSGConfidenceAuthority,
+		},
+		{
+			name:               "etrs89",
+			projection:         `GEOGCS["GCS_ETRS_1989",DATUM["D_ETRS_1989",SPHEROID["GRS_1980",6378137.0,298.257222101]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`,
+			expectedCode:       4258,
+			expectedConfidence: EPSGConfidenceName,
+		},
+		{
+			name:               "nad83_renamed",
+			projection:         `GEOGCS["My NAD83",DATUM["North_American_Datum_1983",SPHEROID["GRS 1980",6378137,298.257222101]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]]`,
+			expectedCode:       4269,
+			expectedConfidence: EPSGConfidenceParameters,
+		},
+		{
+			name:               "utm_32n",
+			projection:         utm32N,
+			expectedCode:       32632,
+			expectedConfidence: EPSGConfidenceName,
+		},
+		{
+			name:               "utm_32n_renamed",
+			projection:         strings.Replace(utm32N, "WGS_1984_UTM_Zone_32N", "Custom", 1),
+			expectedCode:       32632,
+			expectedConfidence: EPSGConfidenceParameters,
+		},
+		{
+			name:               "utm_33s_ogc",
+			projection:         `PROJCS["WGS 84 / UTM zone 33S",GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",15],PARAMETER["scale_factor",0.9996],PARAMETER["false_easting",500000],PARAMETER["false_northing",10000000],UNIT["metre",1],AXIS["Easting",EAST],AXIS["Northing",NORTH]]`,
+			expectedCode:       32733,
+			expectedConfidence: EPSGConfidenceName,
+		},
+		{
+			name:               "laea_europe_ogc",
+			projection:         `PROJCS["ETRS89 / LAEA Europe",GEOGCS["ETRS89",DATUM["European_Terrestrial_Reference_System_1989",SPHEROID["GRS 1980",6378137,298.257222101]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Lambert_Azimuthal_Equal_Area"],PARAMETER["latitude_of_center",52],PARAMETER["longitude_of_center",10],PARAMETER["false_easting",4321000],PARAMETER["false_northing",3210000],UNIT["metre",1]]`,
+			expectedCode:       3035,
+			expectedConfidence: EPSGConfidenceName,
+		},
+		{
+			name:               "web_mercator",
+			projection:         `PROJCS["WGS_1984_Web_Mercator_Auxiliary_Sphere",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Mercator_Auxiliary_Sphere"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",0.0],PARAMETER["Standard_Parallel_1",0.0],PARAMETER["Auxiliary_Sphere_Type",0.0],UNIT["Meter",1.0]]`,
+			expectedCode:       3857,
+			expectedConfidence: EPSGConfidenceName,
+		},
+		{
+			name:       "different_parameter",
+			projection: strings.Replace(utm32N, `"Central_Meridian",9.0`, `"Central_Meridian",10.0`, 1),
+		},
+		{
+			name:       "different_unit",
+			projection: strings.Replace(utm32N, `UNIT["Meter",1.0]`, `UNIT["Foot_US",0.3048006096012192]`, 1),
+		},
+		{
+			name:       "unknown_datum",
+			projection: strings.ReplaceAll(utm32N, "D_WGS_1984", "D_Unknown"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prj, err := ParsePRJ(tc.projection)
+			assert.NoError(t, err)
+			code, confidence := prj.EPSG()
+			assert.Equal(t, tc.expectedCode, code)
+			assert.Equal(t, tc.expectedConfidence, confidence)
+		})
+	}
+}
+
+func TestPRJEPSGUnparsed(t *testing.T) {
+	code, confidence := (&PRJ{Projection: "invalid"}).EPSG()
+	assert.Equal(t, 0, code)
+	assert.Equal(t, EPSGConfidenceNone, confidence)
+}
+
+func TestEPSGTable(t *testing.T) {
+	codes := make(map[int]bool)
+	for _, geogCS := range epsgGeogCSs {
+		assert.False(t, codes[geogCS.code])
+		codes[geogCS.code] = true
+	}
+	for _, projCS := range epsgProjCSs() {
+		assert.False(t, codes[projCS.code])
+		codes[projCS.code] = true
+		assert.NotZero(t, projCS.geogCS())
+	}
+}
+
+func TestPRJFromEPSG(t *testing.T) {
+	prj, err := PRJFromEPSG(4326)
+	assert.NoError(t, err)
+	assert.Equal(t, `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`, prj.Projection)
+	assert.True(t, prj.IsGeographic())
+
+	prj, err = PRJFromEPSG(32632)
+	assert.NoError(t, err)
+	assert.Equal(t, testUTM32NESRIWKT, prj.Projection)
+	assert.Equal(t, "WGS_1984_UTM_Zone_32N", prj.ProjCS.Name)
+
+	_, err = PRJFromEPSG(1)
+	assert.EqualError(t, err, "1: unknown EPSG code")
+}
+
+func TestPRJFromEPSGRoundTrip(t *testing.T) {
+	codes := make([]int, 0, len(epsgGeogCSs)+len(epsgProjCSs()))
+	for _, geogCS := range epsgGeogCSs {
+		codes = append(codes, geogCS.code)
+	}
+	for _, projCS := range epsgProjCSs() {
+		codes = append(codes, projCS.code)
+	}
+	for _, code := range codes {
+		prj, err := PRJFromEPSG(code)
+		assert.NoError(t, err)
+
+		actualCode, confidence := prj.EPSG()
+		assert.Equal(t, code, actualCode)
+		assert.Equal(t, EPSGConfidenceName, confidence)
+
+		buffer := &bytes.Buffer{}
+		assert.NoError(t, WritePRJ(buffer, &PRJ{ProjCS: prj.ProjCS, GeogCS: prj.GeogCS}))
+		assert.Equal(t, prj.Projection, buffer.String())
+
+		readPRJ, err := ReadPRJ(buffer, int64(buffer.Len()))
+		assert.NoError(t, err)
+		assert.Equal(t, prj, readPRJ)
+	}
+}
@@ -0,0 +1,136 @@
+package shapefile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"golang.org/x/text/encoding"
+)
+
+const fptHeaderSize = 512
+
+// An FPTMemoType is the type of a memo in an FPT.
+type FPTMemoType int
+
+// FPT memo types.
+const (
+	FPTMemoTypePicture FPTMemoType = 0
+	FPTMemoTypeText    FPTMemoType = 1
+	FPTMemoTypeObject  FPTMemoType = 2
+)
+
+// An FPT is a FoxPro .fpt memo file.
+//
+// See https://www.clicketyclick.dk/databases/xbase/format/fpt.html.
+type FPT struct {
+	NextBlock int
+	BlockSize int
+
+	readerAt io.ReaderAt
+	size     int64
+}
+
+// ReadFPT reads an FPT from an io.Reader. If r implements io.ReaderAt then
+// memos are read lazily from r, and so r must remain open while memos are
+// read. Otherwise, r is read into memory.
+func ReadFPT(r io.Reader, size int64) (*FPT, error) {
+	readerAt, ok := r.(io.ReaderAt)
+	if !ok {
+		data, err := readAll(r, size)
+		if err != nil {
+			return nil, err
+		}
+		readerAt = bytes.NewReader(data)
+	}
+
+	if size < fptHeaderSize {
+		return nil, errors.New("file too short")
+	}
+	headerData := make([]byte, fptHeaderSize)
+	if _, err := readerAt.ReadAt(headerData, 0); err != nil {
+		return nil, err
+	}
+	nextBlock := int(binary.BigEndian.Uint32(headerData[:4]))
+	// A block size of zero means that each block is a single byte.
+	blockSize := max(int(binary.BigEndian.Uint16(headerData[6:8])), 1)
+
+	return &FPT{
+		NextBlock: nextBlock,
+		BlockSize: blockSize,
+		readerAt:  readerAt,
+		size:      size,
+	}, nil
+}
+
+// ReadFPTZipFile reads an FPT from a *zip.File. The FPT is read into memory.
+func ReadFPTZipFile(zipFile *zip.File) (*FPT, error) {
+	readCloser, err := zipFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer readCloser.Close()
+	fpt, err := ReadFPT(readCloser, int64(zipFile.UncompressedSize64))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", zipFile.Name, err)
+	}
+	return fpt, nil
+}
+
+// ReadMemo returns the type and raw data of the memo starting at block. If
+// maxSize is not zero then memos larger than maxSize bytes return an error.
+func (f *FPT) ReadMemo(block, maxSize int) (FPTMemoType, []byte, error) {
+	offset := int64(block) * int64(f.BlockSize)
+	if block <= 0 || offset < fptHeaderSize || offset+8 > f.size {
+		return 0, nil, fmt.Errorf("%d: invalid memo block", block)
+	}
+
+	memoHeaderData := make([]byte, 8)
+	if _, err := f.readerAt.ReadAt(memoHeaderData, offset); err != nil {
+		return 0, nil, err
+	}
+	memoType := FPTMemoType(binary.BigEndian.Uint32(memoHeaderData[:4]))
+	length := int64(binary.BigEndian.Uint32(memoHeaderData[4:8]))
+	if offset+8+length > f.size {
+		return 0, nil, fmt.Errorf("%d: invalid memo length", block)
+	}
+	if maxSize != 0 && length > int64(maxSize) {
+		return 0, nil, errors.New("memo too large")
+	}
+	data := make([]byte, length)
+	if _, err := f.readerAt.ReadAt(data, offset+8); err != nil {
+		return 0, nil, err
+	}
+	return memoType, data, nil
+}
+
+// parseMemo returns the value of memo. Text memos in memo fields are decoded
+// with decoder and returned as strings, all other memos are returned as
+// []bytes. It returns nil if memo does not reference a block.
+func (f *FPT) parseMemo(memo DBFMemo, fieldType byte, decoder *encoding.Decoder, maxSize int) (any, error) {
+	if memo == "" {
+		return nil, nil
+	}
+	block, err := strconv.Atoi(string(memo))
+	if err != nil {
+		return nil, fmt.Errorf("%q: invalid memo block: %w", memo, err)
+	}
+	if block == 0 {
+		return nil, nil
+	}
+	memoType, data, err := f.ReadMemo(block, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if memoType != FPTMemoTypeText || fieldType != 'M' {
+		return data, nil
+	}
+	if decoder == nil {
+		return nil, errors.New("decoder is nil")
+	}
+	return decoder.String(string(data))
+}
@@ -0,0 +1,196 @@
+package shapefile
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func FuzzReadFPT(f *testing.F) {
+	assert.NoError(f, addFuzzDataFromFS(f, os.DirFS("."), "testdata", ".fpt"))
+
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		fpt, err := ReadFPT(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		_, _, _ = fpt.ReadMemo(8, 4096)
+	})
+}
+
+func TestReadVisualFoxPro(t *testing.T) {
+	basename := filepath.Join("testdata", "vfp")
+	expectedFieldDescriptors := []*DBFFieldDescriptor{
+		{Name: "NAME", Type: 'C', Length: 10, VisualFoxPro: true},
+		{Name: "NOTES", Type: 'M', Length: 4, VisualFoxPro: true},
+		{Name: "COUNT", Type: 'N', Length: 5, Flags: DBFFieldFlagNullable, VisualFoxPro: true},
+		{Name: "_NullFlags", Type: '0', Length: 1, Flags: DBFFieldFlagSystem | DBFFieldFlagBinary, VisualFoxPro: true},
+	}
+	expectedRecords := [][]any{
+		{"one", "Short memo", 12, []byte{0}},
+		{"two", nil, nil, []byte{1}},
+	}
+
+	shapefile, err := Read(basename, nil)
+	assert.NoError(t, err)
+	assert.True(t, shapefile.DBF.VisualFoxPro)
+	assert.True(t, shapefile.DBF.Memo)
+	assert.True(t, shapefile.DBF.FPT)
+	assert.False(t, shapefile.DBF.DBT)
+	assert.Equal(t, 424, shapefile.DBF.HeaderSize)
+	assert.Equal(t, expectedFieldDescriptors, shapefile.DBF.FieldDescriptors)
+	assert.Equal(t, expectedRecords, shapefile.DBF.Records)
+
+	reader, err := Open(basename, nil)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, reader.Close())
+	}()
+	for i, expectedRecord := range expectedRecords {
+		record, err := reader.DBFRecord(i)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedRecord, record)
+	}
+
+	scanner, err := NewScannerFromBasename(basename, nil)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, scanner.Close())
+	}()
+	scannedShapefile, err := ReadScanner(scanner)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRecords, scannedShapefile.DBF.Records)
+}
+
+func TestReadDBFMemoSource(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		files           map[string]string
+		basename        string
+		expectedRecords [][]any
+	}{
+		{
+			name: "dbase_with_stray_fpt",
+			files: map[string]string{
+				"memo3.dbf": "memo3.dbf",
+				"memo3.dbt": "memo3.dbt",
+				"vfp.fpt":   "memo3.fpt",
+			},
+			basename: "memo3",
+			expectedRecords: [][]any{
+				{"one", "Short memo"},
+				{"two", strings.Repeat("Long memo. ", 60)},
+				{"three", nil},
+			},
+		},
+		{
+			name: "dbase_with_only_fpt",
+			files: map[string]string{
+				"memo3.dbf": "memo3.dbf",
+				"vfp.fpt":   "memo3.fpt",
+			},
+			basename: "memo3",
+			expectedRecords: [][]any{
+				{"one", DBFMemo("1")},
+				{"two", DBFMemo("2")},
+				{"three", DBFMemo("")},
+			},
+		},
+		{
+			name: "visual_foxpro_with_stray_dbt",
+			files: map[string]string{
+				"vfp.dbf":   "vfp.dbf",
+				"vfp.fpt":   "vfp.fpt",
+				"memo3.dbt": "vfp.dbt",
+			},
+			basename: "vfp",
+			expectedRecords: [][]any{
+				{"one", "Short memo", 12, []byte{0}},
+				{"two", nil, nil, []byte{1}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			for src, dst := range tc.files {
+				data, err := os.ReadFile(filepath.Join("testdata", src))
+				assert.NoError(t, err)
+				assert.NoError(t, os.WriteFile(filepath.Join(tempDir, dst), data, 0o666))
+			}
+			shapefile, err := Read(filepath.Join(tempDir, tc.basename), nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRecords, shapefile.DBF.Records)
+		})
+	}
+}
+
+func TestParseDBFHeaderFoxPro(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		version              byte
+		tableFlags           byte
+		expectedMemo         bool
+		expectedFPT          bool
+		expectedVisualFoxPro bool
+		expectedErr          string
+	}{
+		{
+			name:    "dbase_iii",
+			version: 0x03,
+		},
+		{
+			name:         "foxpro_memo",
+			version:      0xf5,
+			expectedMemo: true,
+			expectedFPT:  true,
+		},
+		{
+			name:                 "visual_foxpro",
+			version:              0x30,
+			expectedVisualFoxPro: true,
+		},
+		{
+			name:                 "visual_foxpro_memo",
+			version:              0x31,
+			tableFlags:           0x02,
+			expectedMemo:         true,
+			expectedFPT:          true,
+			expectedVisualFoxPro: true,
+		},
+		{
+			name:        "unsupported",
+			version:     0x02,
+			expectedErr: "2: unsupported version",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data := make([]byte, dbfHeaderLength)
+			data[0] = tc.version
+			data[28] = tc.tableFlags
+			header, err := ParseDBFHeader(data, nil)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 3, header.Version)
+			assert.Equal(t, tc.expectedMemo, header.Memo)
+			assert.Equal(t, tc.expectedFPT, header.FPT)
+			assert.Equal(t, tc.expectedVisualFoxPro, header.VisualFoxPro)
+		})
+	}
+}
+
+func TestReadFPTInvalidSize(t *testing.T) {
+	data, err := os.ReadFile("testdata/vfp.fpt")
+	assert.NoError(t, err)
+	// Hide the io.ReaderAt implementation so that ReadFPT reads into memory.
+	r := struct{ io.Reader }{bytes.NewReader(data)}
+	_, err = ReadFPT(r, 1<<62)
+	assert.IsError(t, err, io.ErrUnexpectedEOF)
+}
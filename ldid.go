@@ -0,0 +1,73 @@
+package shapefile
+
+// dbfLanguageDriverCharsets maps DBF language driver IDs to charsets. Only
+// language drivers whose code pages are supported are included. The ESRI
+// language driver ID 0x57 means "current ANSI code page", which is
+// indistinguishable from no language driver ID.
+//
+// See https://github.com/OSGeo/gdal/blob/master/ogr/ogrsf_frmts/shape/ogrshapelayer.cpp.
+var dbfLanguageDriverCharsets = map[byte]string{
+	0x01: "IBM437",         // US MS-DOS.
+	0x02: "IBM850",         // International MS-DOS.
+	0x03: "windows-1252",   // Windows ANSI.
+	0x04: "macintosh",      // Standard Macintosh.
+	0x08: "IBM865",         // Danish OEM.
+	0x09: "IBM437",         // Dutch OEM.
+	0x0a: "IBM850",         // Dutch OEM secondary.
+	0x0b: "IBM437",         // Finnish OEM.
+	0x0d: "IBM437",         // French OEM.
+	0x0e: "IBM850",         // French OEM secondary.
+	0x0f: "IBM437",         // German OEM.
+	0x10: "IBM850",         // German OEM secondary.
+	0x11: "IBM437",         // Italian OEM.
+	0x12: "IBM850",         // Italian OEM secondary.
+	0x13: "shift_jis",      // Japanese Shift-JIS.
+	0x14: "IBM850",         // Spanish OEM secondary.
+	0x15: "IBM437",         // Swedish OEM.
+	0x16: "IBM850",         // Swedish OEM secondary.
+	0x17: "IBM865",         // Norwegian OEM.
+	0x18: "IBM437",         // Spanish OEM.
+	0x19: "IBM437",         // English OEM (Great Britain).
+	0x1a: "IBM850",         // English OEM (Great Britain) secondary.
+	0x1b: "IBM437",         // English OEM (US).
+	0x1c: "IBM863",         // French OEM (Canada).
+	0x1d: "IBM850",         // French OEM secondary.
+	0x1f: "IBM852",         // Czech OEM.
+	0x22: "IBM852",         // Hungarian OEM.
+	0x23: "IBM852",         // Polish OEM.
+	0x24: "IBM860",         // Portuguese OEM.
+	0x25: "IBM850",         // Portuguese OEM secondary.
+	0x26: "IBM866",         // Russian OEM.
+	0x37: "IBM850",         // English OEM (US) secondary.
+	0x40: "IBM852",         // Romanian OEM.
+	0x4d: "gbk",            // Chinese GBK (PRC).
+	0x4e: "euc-kr",         // Korean (ANSI/OEM).
+	0x4f: "big5",           // Chinese Big5 (Taiwan).
+	0x50: "windows-874",    // Thai (ANSI/OEM).
+	0x58: "windows-1252",   // Western European ANSI.
+	0x59: "windows-1252",   // Spanish ANSI.
+	0x64: "IBM852",         // Eastern European MS-DOS.
+	0x65: "IBM866",         // Russian MS-DOS.
+	0x66: "IBM865",         // Nordic MS-DOS.
+	0x6c: "IBM863",         // French-Canadian MS-DOS.
+	0x78: "big5",           // Taiwan Big5.
+	0x79: "euc-kr",         // Hangul (Wansung).
+	0x7a: "gbk",            // PRC GBK.
+	0x7b: "shift_jis",      // Japanese Shift-JIS.
+	0x7c: "windows-874",    // Thai Windows/MS-DOS.
+	0x7d: "windows-1255",   // Hebrew Windows.
+	0x7e: "windows-1256",   // Arabic Windows.
+	0x87: "IBM852",         // Slovenian OEM.
+	0x96: "x-mac-cyrillic", // Russian Macintosh.
+	0xc8: "windows-1250",   // Eastern European Windows.
+	0xc9: "windows-1251",   // Russian Windows.
+	0xca: "windows-1254",   // Turkish Windows.
+	0xcb: "windows-1253",   // Greek Windows.
+	0xcc: "windows-1257",   // Baltic Windows.
+}
+
+// LanguageDriverCharset returns the charset of h's language driver ID, or the
+// empty string if the language driver ID is unknown or not set.
+func (h *DBFHeader) LanguageDriverCharset() string {
+	return dbfLanguageDriverCharsets[h.LanguageDriverID]
+}
@@ -0,0 +1,108 @@
+package shapefile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestDBFLanguageDriverCharsets(t *testing.T) {
+	for languageDriverID, charset := range dbfLanguageDriverCharsets {
+		enc, name := lookupCharset(charset)
+		assert.NotZero(t, enc, "language driver ID %#02x", languageDriverID)
+		assert.Equal(t, strings.ToLower(charset), strings.ToLower(name))
+	}
+}
+
+func TestDBFCharsetPriority(t *testing.T) {
+	fieldDescriptors := []*DBFFieldDescriptor{
+		{Name: "NAME", Type: 'C', Length: 16},
+	}
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, WriteDBF(buffer, fieldDescriptors, [][]any{{"Москва"}}, &WriteDBFOptions{
+		LanguageDriverID: 0xc9,
+	}))
+	data := buffer.Bytes()
+	assert.Equal(t, byte(0xc9), data[29])
+
+	for _, tc := range []struct {
+		name           string
+		options        *ReadDBFOptions
+		expectedRecord []any
+		expectedErr    string
+	}{
+		{
+			name:           "ldid",
+			expectedRecord: []any{"Москва"},
+		},
+		{
+			name: "cpg_before_ldid",
+			options: &ReadDBFOptions{
+				CPG: &CPG{Charset: "windows-1252"},
+			},
+			expectedRecord: []any{"Ìîñêâà"},
+		},
+		{
+			name: "charset_before_ldid",
+			options: &ReadDBFOptions{
+				Charset: "windows-1252",
+			},
+			expectedRecord: []any{"Ìîñêâà"},
+		},
+		{
+			name: "ldid_before_charset",
+			options: &ReadDBFOptions{
+				Charset:         "windows-1252",
+				CharsetPriority: []DBFCharsetSource{DBFCharsetSourceLDID, DBFCharsetSourceCharset},
+			},
+			expectedRecord: []any{"Москва"},
+		},
+		{
+			name: "charset_before_cpg",
+			options: &ReadDBFOptions{
+				Charset:         "windows-1251",
+				CPG:             &CPG{Charset: "windows-1252"},
+				CharsetPriority: []DBFCharsetSource{DBFCharsetSourceCharset, DBFCharsetSourceCPG},
+			},
+			expectedRecord: []any{"Москва"},
+		},
+		{
+			name: "no_sources",
+			options: &ReadDBFOptions{
+				CharsetPriority: []DBFCharsetSource{},
+			},
+			expectedRecord: []any{"Ìîñêâà"},
+		},
+		{
+			name: "invalid_source",
+			options: &ReadDBFOptions{
+				CharsetPriority: []DBFCharsetSource{0},
+			},
+			expectedErr: "0: invalid charset source",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dbf, err := ReadDBF(bytes.NewReader(data), int64(len(data)), tc.options)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, byte(0xc9), dbf.LanguageDriverID)
+			assert.Equal(t, "windows-1251", dbf.LanguageDriverCharset())
+			assert.Equal(t, [][]any{tc.expectedRecord}, dbf.Records)
+		})
+	}
+}
+
+func TestReadCPGIBMCodePage(t *testing.T) {
+	cpg, err := ReadCPG(strings.NewReader("IBM866"), 6)
+	assert.NoError(t, err)
+	assert.Equal(t, "IBM866", strings.ToUpper(cpg.Charset))
+
+	cpg, err = ReadCPG(strings.NewReader("IBM437"), 6)
+	assert.NoError(t, err)
+	assert.Equal(t, "IBM437", cpg.Charset)
+}
@@ -0,0 +1,270 @@
+package shapefile
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A dbfStructField is a struct field that is marshalled to or unmarshalled
+// from a DBF field.
+type dbfStructField struct {
+	index   int
+	name    string
+	tagged  bool
+	options []string
+}
+
+var (
+	dbfCurrencyType   = reflect.TypeFor[DBFCurrency]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	timeType          = reflect.TypeFor[time.Time]()
+)
+
+// DBFFieldDescriptorsOf returns the DBF field descriptors of the struct type
+// T.
+//
+// Each exported struct field is a DBF field, unless it has the tag
+// `dbf:"-"`. The tag `dbf:"NAME,TYPE,LENGTH,DECIMALCOUNT"` sets the DBF field's
+// name, type, length, and decimal count. All parts of the tag are optional.
+// The default name is the struct field's name, and the default type, length,
+// and decimal count depend on the struct field's type. Strings and types that
+// implement encoding.TextMarshaler are character fields, integers are numeric
+// fields, floating point numbers are floating point fields, bools are logical
+// fields, and time.Times are date fields. Pointer struct fields may be nil, in which case the DBF
+// field is null.
+func DBFFieldDescriptorsOf[T any]() ([]*DBFFieldDescriptor, error) {
+	structType := reflect.TypeFor[T]()
+	structFields, err := dbfStructFields(structType)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldDescriptors := make([]*DBFFieldDescriptor, 0, len(structFields))
+	names := make(map[string]struct{}, len(structFields))
+	for _, structField := range structFields {
+		if _, ok := names[structField.name]; ok {
+			return nil, fmt.Errorf("field %s: duplicate field name", structField.name)
+		}
+		names[structField.name] = struct{}{}
+		fieldDescriptor, err := newDBFFieldDescriptor(structField.name, structType.Field(structField.index).Type, structField.options)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", structField.name, err)
+		}
+		fieldDescriptors = append(fieldDescriptors, fieldDescriptor)
+	}
+
+	if err := checkDBFFieldDescriptors(fieldDescriptors); err != nil {
+		return nil, err
+	}
+	return fieldDescriptors, nil
+}
+
+// MarshalDBFRecord returns the DBF record of src, which must be a struct or a
+// pointer to a struct. The fields of the record are in the same order as the
+// field descriptors returned by DBFFieldDescriptorsOf and can be written with
+// a DBFWriter. See DBFFieldDescriptorsOf.
+func MarshalDBFRecord(src any) ([]any, error) {
+	value := reflect.ValueOf(src)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil, fmt.Errorf("%T: nil pointer", src)
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil, errors.New("nil value")
+	}
+	// Make value addressable so that methods with pointer receivers can be
+	// called on its fields.
+	if !value.CanAddr() {
+		addressableValue := reflect.New(value.Type()).Elem()
+		addressableValue.Set(value)
+		value = addressableValue
+	}
+
+	structFields, err := dbfStructFields(value.Type())
+	if err != nil {
+		return nil, err
+	}
+	record := make([]any, 0, len(structFields))
+	for _, structField := range structFields {
+		field, err := marshalValue(value.Field(structField.index))
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", structField.name, err)
+		}
+		record = append(record, field)
+	}
+	return record, nil
+}
+
+// dbfStructFields returns the struct fields of structType that are
+// marshalled to or unmarshalled from DBF fields.
+func dbfStructFields(structType reflect.Type) ([]*dbfStructField, error) {
+	if structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s: not a struct", structType)
+	}
+	var structFields []*dbfStructField
+	for i := range structType.NumField() {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, ok := field.Tag.Lookup("dbf")
+		if tag == "-" {
+			continue
+		}
+		structField := &dbfStructField{
+			index: i,
+			name:  field.Name,
+		}
+		if ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				structField.name = parts[0]
+				structField.tagged = true
+			}
+			structField.options = parts[1:]
+		}
+		structFields = append(structFields, structField)
+	}
+	return structFields, nil
+}
+
+// newDBFFieldDescriptor returns a new DBFFieldDescriptor for a struct field
+// with name, type, and tag options.
+func newDBFFieldDescriptor(name string, fieldType reflect.Type, options []string) (*DBFFieldDescriptor, error) {
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+
+	var compatibleTypes string
+	fieldDescriptor := &DBFFieldDescriptor{
+		Name: name,
+	}
+	switch {
+	case fieldType == timeType:
+		compatibleTypes = "D"
+		fieldDescriptor.Type = 'D'
+		fieldDescriptor.Length = 8
+	case fieldType == dbfCurrencyType:
+		compatibleTypes = "FN"
+		fieldDescriptor.Type = 'N'
+		fieldDescriptor.Length = 20
+		fieldDescriptor.DecimalCount = 4
+	case fieldType.Kind() == reflect.String || reflect.PointerTo(fieldType).Implements(textMarshalerType):
+		compatibleTypes = "C"
+		fieldDescriptor.Type = 'C'
+		fieldDescriptor.Length = 254
+	case fieldType.Kind() == reflect.Bool:
+		compatibleTypes = "L"
+		fieldDescriptor.Type = 'L'
+		fieldDescriptor.Length = 1
+	case fieldType.Kind() == reflect.Float32 || fieldType.Kind() == reflect.Float64:
+		// Values that do not fit with 15 decimal places are written in
+		// exponential notation, which fits any float64 in 24 characters.
+		compatibleTypes = "FN"
+		fieldDescriptor.Type = 'F'
+		fieldDescriptor.Length = 24
+		fieldDescriptor.DecimalCount = 15
+	case fieldType.Kind() >= reflect.Int && fieldType.Kind() <= reflect.Uint64:
+		compatibleTypes = "FN"
+		fieldDescriptor.Type = 'N'
+		// The length is the number of characters in the largest value.
+		switch fieldType.Kind() {
+		case reflect.Int8:
+			fieldDescriptor.Length = len(strconv.Itoa(math.MinInt8))
+		case reflect.Int16:
+			fieldDescriptor.Length = len(strconv.Itoa(math.MinInt16))
+		case reflect.Int32:
+			fieldDescriptor.Length = len(strconv.Itoa(math.MinInt32))
+		case reflect.Uint8:
+			fieldDescriptor.Length = len(strconv.Itoa(math.MaxUint8))
+		case reflect.Uint16:
+			fieldDescriptor.Length = len(strconv.Itoa(math.MaxUint16))
+		case reflect.Uint32:
+			fieldDescriptor.Length = len(strconv.Itoa(math.MaxUint32))
+		default:
+			fieldDescriptor.Length = len(strconv.Itoa(math.MinInt64))
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported type", fieldType)
+	}
+
+	if len(options) > 3 {
+		return nil, fmt.Errorf("%q: invalid tag", strings.Join(options, ","))
+	}
+	if len(options) > 0 && options[0] != "" {
+		if len(options[0]) != 1 || !strings.Contains(compatibleTypes, options[0]) {
+			return nil, fmt.Errorf("%s: invalid field type for %s", options[0], fieldType)
+		}
+		fieldDescriptor.Type = options[0][0]
+	}
+	if len(options) > 1 && options[1] != "" {
+		length, err := strconv.Atoi(options[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid length: %w", options[1], err)
+		}
+		fieldDescriptor.Length = length
+	}
+	if len(options) > 2 && options[2] != "" {
+		decimalCount, err := strconv.Atoi(options[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid decimal count: %w", options[2], err)
+		}
+		fieldDescriptor.DecimalCount = decimalCount
+	}
+	return fieldDescriptor, nil
+}
+
+// marshalValue returns the DBF value of value.
+func marshalValue(value reflect.Value) (any, error) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+
+	switch {
+	case value.Type() == timeType:
+		return value.Interface(), nil
+	case value.Type() == dbfCurrencyType:
+		return DBFCurrency(value.Int()), nil
+	}
+
+	textMarshalerValue := value
+	if value.CanAddr() {
+		textMarshalerValue = value.Addr()
+	}
+	if textMarshaler, ok := textMarshalerValue.Interface().(encoding.TextMarshaler); ok {
+		text, err := textMarshaler.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := value.Uint(); u <= math.MaxInt64 {
+			return int64(u), nil
+		}
+		return nil, fmt.Errorf("%d: overflows int64", value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported type", value.Type())
+	}
+}
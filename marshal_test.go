@@ -0,0 +1,203 @@
+package shapefile
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+type testColor int
+
+func (c testColor) MarshalText() ([]byte, error) {
+	return []byte([]string{"red", "green", "blue"}[c]), nil
+}
+
+func (c *testColor) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "red":
+		*c = 0
+	case "green":
+		*c = 1
+	default:
+		*c = 2
+	}
+	return nil
+}
+
+type testFeature struct {
+	Name     string    `dbf:"NAME,C,20"`
+	Count    int       `dbf:"COUNT,N,10"`
+	Height   *float64  `dbf:"HEIGHT,N,10,2"`
+	Color    testColor `dbf:"COLOR,C,8"`
+	Visible  bool      `dbf:"VISIBLE"`
+	Updated  time.Time `dbf:"UPDATED"`
+	Price    DBFCurrency
+	Level    int8
+	Internal string `dbf:"-"`
+}
+
+func TestDBFFieldDescriptorsOf(t *testing.T) {
+	fieldDescriptors, err := DBFFieldDescriptorsOf[testFeature]()
+	assert.NoError(t, err)
+	assert.Equal(t, []*DBFFieldDescriptor{
+		{Name: "NAME", Type: 'C', Length: 20},
+		{Name: "COUNT", Type: 'N', Length: 10},
+		{Name: "HEIGHT", Type: 'N', Length: 10, DecimalCount: 2},
+		{Name: "COLOR", Type: 'C', Length: 8},
+		{Name: "VISIBLE", Type: 'L', Length: 1},
+		{Name: "UPDATED", Type: 'D', Length: 8},
+		{Name: "Price", Type: 'N', Length: 20, DecimalCount: 4},
+		{Name: "Level", Type: 'N', Length: 4},
+	}, fieldDescriptors)
+}
+
+func TestDBFFieldDescriptorsOfErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		f           func() ([]*DBFFieldDescriptor, error)
+		expectedErr string
+	}{
+		{
+			name:        "not_a_struct",
+			f:           DBFFieldDescriptorsOf[int],
+			expectedErr: "int: not a struct",
+		},
+		{
+			name: "name_too_long",
+			f: DBFFieldDescriptorsOf[struct {
+				LongFieldName string
+			}],
+			expectedErr: "field 0: LongFieldName: invalid field name",
+		},
+		{
+			name: "duplicate_name",
+			f: DBFFieldDescriptorsOf[struct {
+				A string `dbf:"NAME"`
+				B string `dbf:"NAME"`
+			}],
+			expectedErr: "field NAME: duplicate field name",
+		},
+		{
+			name: "incompatible_type",
+			f: DBFFieldDescriptorsOf[struct {
+				A string `dbf:"A,N"`
+			}],
+			expectedErr: "field A: N: invalid field type for string",
+		},
+		{
+			name: "invalid_length",
+			f: DBFFieldDescriptorsOf[struct {
+				A string `dbf:"A,C,x"`
+			}],
+			expectedErr: `field A: x: invalid length: strconv.Atoi: parsing "x": invalid syntax`,
+		},
+		{
+			name: "invalid_decimal_count",
+			f: DBFFieldDescriptorsOf[struct {
+				A float64 `dbf:"A,N,10,10"`
+			}],
+			expectedErr: "field A: 10: invalid decimal count",
+		},
+		{
+			name: "unsupported_type",
+			f: DBFFieldDescriptorsOf[struct {
+				A []int
+			}],
+			expectedErr: "field A: []int: unsupported type",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.f()
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestMarshalDBFRecordFloat(t *testing.T) {
+	type feature struct {
+		Value float64
+	}
+	fieldDescriptors, err := DBFFieldDescriptorsOf[feature]()
+	assert.NoError(t, err)
+	assert.Equal(t, []*DBFFieldDescriptor{
+		{Name: "Value", Type: 'F', Length: 24, DecimalCount: 15},
+	}, fieldDescriptors)
+
+	features := []feature{
+		{Value: 0},
+		{Value: 1.5},
+		{Value: -123456789.125},
+		{Value: 6.02214076e23},
+		{Value: -math.MaxFloat64},
+		{Value: math.SmallestNonzeroFloat64},
+	}
+	records := make([][]any, 0, len(features))
+	for _, feature := range features {
+		record, err := MarshalDBFRecord(feature)
+		assert.NoError(t, err)
+		records = append(records, record)
+	}
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, WriteDBF(buffer, fieldDescriptors, records, nil))
+
+	dbf, err := ReadDBF(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), nil)
+	assert.NoError(t, err)
+	for i, expected := range features[:5] {
+		var actual feature
+		assert.NoError(t, dbf.UnmarshalRecord(i, &actual))
+		assert.Equal(t, expected, actual)
+	}
+	// Values smaller than the decimal places are rounded.
+	var actual feature
+	assert.NoError(t, dbf.UnmarshalRecord(5, &actual))
+	assert.Equal(t, feature{}, actual)
+}
+
+func TestMarshalDBFRecord(t *testing.T) {
+	height := 12.5
+	features := []testFeature{
+		{
+			Name:    "one",
+			Count:   1,
+			Height:  &height,
+			Color:   1,
+			Visible: true,
+			Updated: time.Date(2024, time.February, 3, 0, 0, 0, 0, time.UTC),
+			Price:   12345,
+			Level:   -3,
+		},
+		{
+			Name:  "two",
+			Color: 2,
+		},
+	}
+
+	record, err := MarshalDBFRecord(&features[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"one", int64(1), 12.5, "green", true, time.Date(2024, time.February, 3, 0, 0, 0, 0, time.UTC), DBFCurrency(12345), int64(-3)}, record)
+
+	fieldDescriptors, err := DBFFieldDescriptorsOf[testFeature]()
+	assert.NoError(t, err)
+	records := make([][]any, 0, len(features))
+	for _, feature := range features {
+		record, err := MarshalDBFRecord(feature)
+		assert.NoError(t, err)
+		records = append(records, record)
+	}
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, WriteDBF(buffer, fieldDescriptors, records, nil))
+
+	dbf, err := ReadDBF(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), nil)
+	assert.NoError(t, err)
+	for i, expected := range features {
+		var actual testFeature
+		assert.NoError(t, dbf.UnmarshalRecord(i, &actual))
+		assert.Equal(t, expected, actual)
+	}
+
+	_, err = MarshalDBFRecord((*testFeature)(nil))
+	assert.EqualError(t, err, "*shapefile.testFeature: nil pointer")
+}
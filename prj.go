@@ -2,24 +2,131 @@ package shapefile
 
 import (
 	"archive/zip"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"strconv"
+	"strings"
 )
 
-// A PRJ is a .prj file.
+// prjMaxDepth is the maximum nesting depth of WKT in a .prj file.
+const prjMaxDepth = 16
+
+// A PRJ is a .prj file. Projection is the raw well-known text (WKT). If the
+// WKT can be parsed then exactly one of ProjCS and GeogCS is set. When
+// writing, if Projection is empty then ESRI WKT is generated from ProjCS or
+// GeogCS.
 type PRJ struct {
 	Projection string
+	ProjCS     *PRJProjCS
+	GeogCS     *PRJGeogCS
+}
+
+// A PRJProjCS is a projected coordinate reference system.
+type PRJProjCS struct {
+	Name       string
+	GeogCS     *PRJGeogCS
+	Projection string
+	Parameters []*PRJParameter
+	LinearUnit *PRJUnit
+	Authority  *PRJAuthority
+}
+
+// A PRJGeogCS is a geographic coordinate reference system.
+type PRJGeogCS struct {
+	Name          string
+	Datum         *PRJDatum
+	PrimeMeridian *PRJPrimeMeridian
+	AngularUnit   *PRJUnit
+	Authority     *PRJAuthority
+}
+
+// A PRJDatum is a geodetic datum.
+type PRJDatum struct {
+	Name      string
+	Spheroid  *PRJSpheroid
+	ToWGS84   []float64
+	Authority *PRJAuthority
+}
+
+// A PRJSpheroid is a reference ellipsoid.
+type PRJSpheroid struct {
+	Name              string
+	SemiMajorAxis     float64
+	InverseFlattening float64
+	Authority         *PRJAuthority
+}
+
+// A PRJPrimeMeridian is a prime meridian. Longitude is in the angular unit of
+// the enclosing geographic coordinate reference system.
+type PRJPrimeMeridian struct {
+	Name      string
+	Longitude float64
+	Authority *PRJAuthority
+}
+
+// A PRJParameter is a projection parameter.
+type PRJParameter struct {
+	Name  string
+	Value float64
+}
+
+// A PRJUnit is a unit of measure. ConversionFactor converts values in the unit
+// to meters for linear units, or to radians for angular units.
+type PRJUnit struct {
+	Name             string
+	ConversionFactor float64
+	Authority        *PRJAuthority
 }
 
-// ReadPRJ reads a PRJ from an io.Reader.
+// A PRJAuthority is an authority code, for example EPSG 4326.
+type PRJAuthority struct {
+	Name string
+	Code string
+}
+
+// A PRJParseError is an error parsing the WKT in a .prj file.
+type PRJParseError struct {
+	Offset  int // Byte offset of the error in the WKT.
+	Message string
+}
+
+// A prjNode is a node in a generic WKT syntax tree.
+type prjNode struct {
+	keyword string
+	offset  int
+	values  []*prjValue
+}
+
+// A prjValue is a value in a WKT node. Exactly one of text and node is set.
+type prjValue struct {
+	offset int
+	text   string
+	quoted bool
+	node   *prjNode
+}
+
+// A prjParser parses WKT.
+type prjParser struct {
+	wkt    string
+	offset int
+}
+
+// ReadPRJ reads a PRJ from an io.Reader. If the WKT cannot be parsed then only
+// the returned PRJ's Projection is set; use ParsePRJ to get the error.
 func ReadPRJ(r io.Reader, _ int64) (*PRJ, error) {
 	data, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
 
+	projection := string(data)
+	if prj, err := ParsePRJ(projection); err == nil {
+		return prj, nil
+	}
 	return &PRJ{
-		Projection: string(data),
+		Projection: projection,
 	}, nil
 }
 
@@ -36,3 +143,686 @@ func ReadPRJZipFile(zipFile *zip.File) (*PRJ, error) {
 	}
 	return prj, nil
 }
+
+// ParsePRJ parses ESRI or OGC WKT1 for a projected or geographic coordinate
+// reference system. Unknown nodes, like AXIS, are ignored. Errors are
+// returned as *PRJParseErrors.
+func ParsePRJ(projection string) (*PRJ, error) {
+	parser := &prjParser{
+		wkt: projection,
+	}
+	parser.offset = len(projection) - len(strings.TrimPrefix(projection, "\ufeff"))
+	parser.skipSpace()
+	root, err := parser.parseNode(0)
+	if err != nil {
+		return nil, err
+	}
+	parser.skipSpace()
+	if parser.offset != len(projection) {
+		return nil, parser.errorf("trailing data")
+	}
+
+	prj := &PRJ{
+		Projection: projection,
+	}
+	switch root.keyword {
+	case "PROJCS":
+		prj.ProjCS, err = newPRJProjCS(root)
+	case "GEOGCS":
+		prj.GeogCS, err = newPRJGeogCS(root)
+	default:
+		err = root.errorf("unsupported coordinate reference system")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return prj, nil
+}
+
+// WritePRJ writes prj to w. If prj's Projection is empty then prj's ESRI WKT
+// is written.
+func WritePRJ(w io.Writer, prj *PRJ) error {
+	projection := prj.Projection
+	if projection == "" {
+		var err error
+		projection, err = prj.ESRIWKT()
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, projection)
+	return err
+}
+
+// ESRIWKT returns p's ProjCS or GeogCS formatted as ESRI WKT1, as expected by
+// ArcGIS. OGC names of datums, projections, parameters, and units, and the
+// names of known coordinate reference systems, are replaced with their ESRI
+// names. Authority codes, TOWGS84 parameters, and axes are not included.
+func (p *PRJ) ESRIWKT() (string, error) {
+	var sb strings.Builder
+	switch {
+	case p.ProjCS != nil:
+		if err := p.ProjCS.writeESRIWKT(&sb); err != nil {
+			return "", err
+		}
+	case p.GeogCS != nil:
+		if err := p.GeogCS.writeESRIWKT(&sb); err != nil {
+			return "", err
+		}
+	default:
+		return "", errors.New("no coordinate reference system")
+	}
+	return sb.String(), nil
+}
+
+// IsGeographic returns if p is a geographic coordinate reference system.
+func (p *PRJ) IsGeographic() bool {
+	return p.GeogCS != nil
+}
+
+// IsProjected returns if p is a projected coordinate reference system.
+func (p *PRJ) IsProjected() bool {
+	return p.ProjCS != nil
+}
+
+// Datum returns p's datum, or nil if p could not be parsed.
+func (p *PRJ) Datum() *PRJDatum {
+	if geogCS := p.geogCS(); geogCS != nil {
+		return geogCS.Datum
+	}
+	return nil
+}
+
+// AngularUnit returns the angular unit of p's geographic coordinate reference
+// system, or nil if p could not be parsed.
+func (p *PRJ) AngularUnit() *PRJUnit {
+	if geogCS := p.geogCS(); geogCS != nil {
+		return geogCS.AngularUnit
+	}
+	return nil
+}
+
+// LinearUnit returns p's linear unit, or nil if p is not a projected
+// coordinate reference system.
+func (p *PRJ) LinearUnit() *PRJUnit {
+	if p.ProjCS != nil {
+		return p.ProjCS.LinearUnit
+	}
+	return nil
+}
+
+// geogCS returns p's geographic coordinate reference system, or the
+// geographic coordinate reference system that p's projected coordinate
+// reference system is based on.
+func (p *PRJ) geogCS() *PRJGeogCS {
+	if p.ProjCS != nil {
+		return p.ProjCS.GeogCS
+	}
+	return p.GeogCS
+}
+
+// Parameter returns the value of the parameter with name, ignoring case, and
+// if it exists.
+func (p *PRJProjCS) Parameter(name string) (float64, bool) {
+	for _, parameter := range p.Parameters {
+		if strings.EqualFold(parameter.Name, name) {
+			return parameter.Value, true
+		}
+	}
+	return 0, false
+}
+
+// writeESRIWKT writes p as ESRI WKT to sb.
+func (p *PRJProjCS) writeESRIWKT(sb *strings.Builder) error {
+	switch {
+	case p.GeogCS == nil:
+		return errors.New("PROJCS: missing GEOGCS")
+	case p.LinearUnit == nil:
+		return errors.New("PROJCS: missing UNIT")
+	}
+	sb.WriteString("PROJCS[")
+	writePRJString(sb, esriProjCSName(p))
+	sb.WriteByte(',')
+	if err := p.GeogCS.writeESRIWKT(sb); err != nil {
+		return err
+	}
+	sb.WriteString(",PROJECTION[")
+	writePRJString(sb, esriProjectionName(p.Projection))
+	sb.WriteByte(']')
+	for _, parameter := range p.Parameters {
+		sb.WriteString(",PARAMETER[")
+		writePRJString(sb, esriParameterName(p.Projection, parameter.Name))
+		sb.WriteByte(',')
+		if err := writePRJNumber(sb, parameter.Value); err != nil {
+			return fmt.Errorf("PARAMETER: %w", err)
+		}
+		sb.WriteByte(']')
+	}
+	sb.WriteByte(',')
+	if err := p.LinearUnit.writeESRIWKT(sb); err != nil {
+		return err
+	}
+	sb.WriteByte(']')
+	return nil
+}
+
+// writeESRIWKT writes g as ESRI WKT to sb.
+func (g *PRJGeogCS) writeESRIWKT(sb *strings.Builder) error {
+	switch {
+	case g.Datum == nil:
+		return errors.New("GEOGCS: missing DATUM")
+	case g.Datum.Spheroid == nil:
+		return errors.New("DATUM: missing SPHEROID")
+	case g.PrimeMeridian == nil:
+		return errors.New("GEOGCS: missing PRIMEM")
+	case g.AngularUnit == nil:
+		return errors.New("GEOGCS: missing UNIT")
+	}
+	name, datumName, spheroidName := esriGeogCSNames(g)
+	sb.WriteString("GEOGCS[")
+	writePRJString(sb, name)
+	sb.WriteString(",DATUM[")
+	writePRJString(sb, datumName)
+	sb.WriteString(",SPHEROID[")
+	writePRJString(sb, spheroidName)
+	sb.WriteByte(',')
+	if err := writePRJNumber(sb, g.Datum.Spheroid.SemiMajorAxis); err != nil {
+		return fmt.Errorf("SPHEROID: %w", err)
+	}
+	sb.WriteByte(',')
+	if err := writePRJNumber(sb, g.Datum.Spheroid.InverseFlattening); err != nil {
+		return fmt.Errorf("SPHEROID: %w", err)
+	}
+	sb.WriteString("]],PRIMEM[")
+	writePRJString(sb, g.PrimeMeridian.Name)
+	sb.WriteByte(',')
+	if err := writePRJNumber(sb, g.PrimeMeridian.Longitude); err != nil {
+		return fmt.Errorf("PRIMEM: %w", err)
+	}
+	sb.WriteString("],")
+	if err := g.AngularUnit.writeESRIWKT(sb); err != nil {
+		return err
+	}
+	sb.WriteByte(']')
+	return nil
+}
+
+// writeESRIWKT writes u as ESRI WKT to sb.
+func (u *PRJUnit) writeESRIWKT(sb *strings.Builder) error {
+	sb.WriteString("UNIT[")
+	writePRJString(sb, esriUnitName(u.Name))
+	sb.WriteByte(',')
+	if err := writePRJNumber(sb, u.ConversionFactor); err != nil {
+		return fmt.Errorf("UNIT: %w", err)
+	}
+	sb.WriteByte(']')
+	return nil
+}
+
+func (e *PRJParseError) Error() string {
+	return fmt.Sprintf("offset %d: %s", e.Offset, e.Message)
+}
+
+// newPRJProjCS returns a new PRJProjCS from a PROJCS node.
+func newPRJProjCS(node *prjNode) (*PRJProjCS, error) {
+	name, err := node.name()
+	if err != nil {
+		return nil, err
+	}
+	geogCSNode, err := node.requiredChild("GEOGCS")
+	if err != nil {
+		return nil, err
+	}
+	geogCS, err := newPRJGeogCS(geogCSNode)
+	if err != nil {
+		return nil, err
+	}
+	projectionNode, err := node.requiredChild("PROJECTION")
+	if err != nil {
+		return nil, err
+	}
+	projection, err := projectionNode.name()
+	if err != nil {
+		return nil, err
+	}
+	var parameters []*PRJParameter
+	for _, value := range node.values[1:] {
+		if value.node == nil || value.node.keyword != "PARAMETER" {
+			continue
+		}
+		name, err := value.node.name()
+		if err != nil {
+			return nil, err
+		}
+		parameterValue, err := value.node.number(1)
+		if err != nil {
+			return nil, err
+		}
+		parameters = append(parameters, &PRJParameter{
+			Name:  name,
+			Value: parameterValue,
+		})
+	}
+	unitNode, err := node.requiredChild("UNIT")
+	if err != nil {
+		return nil, err
+	}
+	linearUnit, err := newPRJUnit(unitNode)
+	if err != nil {
+		return nil, err
+	}
+	authority, err := node.authority()
+	if err != nil {
+		return nil, err
+	}
+	return &PRJProjCS{
+		Name:       name,
+		GeogCS:     geogCS,
+		Projection: projection,
+		Parameters: parameters,
+		LinearUnit: linearUnit,
+		Authority:  authority,
+	}, nil
+}
+
+// newPRJGeogCS returns a new PRJGeogCS from a GEOGCS node.
+func newPRJGeogCS(node *prjNode) (*PRJGeogCS, error) {
+	name, err := node.name()
+	if err != nil {
+		return nil, err
+	}
+	datumNode, err := node.requiredChild("DATUM")
+	if err != nil {
+		return nil, err
+	}
+	datum, err := newPRJDatum(datumNode)
+	if err != nil {
+		return nil, err
+	}
+	primeMeridianNode, err := node.requiredChild("PRIMEM")
+	if err != nil {
+		return nil, err
+	}
+	primeMeridian, err := newPRJPrimeMeridian(primeMeridianNode)
+	if err != nil {
+		return nil, err
+	}
+	unitNode, err := node.requiredChild("UNIT")
+	if err != nil {
+		return nil, err
+	}
+	angularUnit, err := newPRJUnit(unitNode)
+	if err != nil {
+		return nil, err
+	}
+	authority, err := node.authority()
+	if err != nil {
+		return nil, err
+	}
+	return &PRJGeogCS{
+		Name:          name,
+		Datum:         datum,
+		PrimeMeridian: primeMeridian,
+		AngularUnit:   angularUnit,
+		Authority:     authority,
+	}, nil
+}
+
+// newPRJDatum returns a new PRJDatum from a DATUM node.
+func newPRJDatum(node *prjNode) (*PRJDatum, error) {
+	name, err := node.name()
+	if err != nil {
+		return nil, err
+	}
+	spheroidNode, err := node.requiredChild("SPHEROID")
+	if err != nil {
+		return nil, err
+	}
+	spheroid, err := newPRJSpheroid(spheroidNode)
+	if err != nil {
+		return nil, err
+	}
+	var toWGS84 []float64
+	if toWGS84Node := node.child("TOWGS84"); toWGS84Node != nil {
+		toWGS84 = make([]float64, 0, len(toWGS84Node.values))
+		for i := range toWGS84Node.values {
+			value, err := toWGS84Node.number(i)
+			if err != nil {
+				return nil, err
+			}
+			toWGS84 = append(toWGS84, value)
+		}
+	}
+	authority, err := node.authority()
+	if err != nil {
+		return nil, err
+	}
+	return &PRJDatum{
+		Name:      name,
+		Spheroid:  spheroid,
+		ToWGS84:   toWGS84,
+		Authority: authority,
+	}, nil
+}
+
+// newPRJSpheroid returns a new PRJSpheroid from a SPHEROID node.
+func newPRJSpheroid(node *prjNode) (*PRJSpheroid, error) {
+	name, err := node.name()
+	if err != nil {
+		return nil, err
+	}
+	semiMajorAxis, err := node.number(1)
+	if err != nil {
+		return nil, err
+	}
+	inverseFlattening, err := node.number(2)
+	if err != nil {
+		return nil, err
+	}
+	authority, err := node.authority()
+	if err != nil {
+		return nil, err
+	}
+	return &PRJSpheroid{
+		Name:              name,
+		SemiMajorAxis:     semiMajorAxis,
+		InverseFlattening: inverseFlattening,
+		Authority:         authority,
+	}, nil
+}
+
+// newPRJPrimeMeridian returns a new PRJPrimeMeridian from a PRIMEM node.
+func newPRJPrimeMeridian(node *prjNode) (*PRJPrimeMeridian, error) {
+	name, err := node.name()
+	if err != nil {
+		return nil, err
+	}
+	longitude, err := node.number(1)
+	if err != nil {
+		return nil, err
+	}
+	authority, err := node.authority()
+	if err != nil {
+		return nil, err
+	}
+	return &PRJPrimeMeridian{
+		Name:      name,
+		Longitude: longitude,
+		Authority: authority,
+	}, nil
+}
+
+// newPRJUnit returns a new PRJUnit from a UNIT node.
+func newPRJUnit(node *prjNode) (*PRJUnit, error) {
+	name, err := node.name()
+	if err != nil {
+		return nil, err
+	}
+	conversionFactor, err := node.number(1)
+	if err != nil {
+		return nil, err
+	}
+	authority, err := node.authority()
+	if err != nil {
+		return nil, err
+	}
+	return &PRJUnit{
+		Name:             name,
+		ConversionFactor: conversionFactor,
+		Authority:        authority,
+	}, nil
+}
+
+// authority returns n's authority, or nil if n has no AUTHORITY child.
+func (n *prjNode) authority() (*PRJAuthority, error) {
+	authorityNode := n.child("AUTHORITY")
+	if authorityNode == nil {
+		return nil, nil
+	}
+	name, err := authorityNode.name()
+	if err != nil {
+		return nil, err
+	}
+	if len(authorityNode.values) < 2 || authorityNode.values[1].node != nil {
+		return nil, authorityNode.errorf("missing code")
+	}
+	return &PRJAuthority{
+		Name: name,
+		Code: authorityNode.values[1].text,
+	}, nil
+}
+
+// child returns n's first child node with keyword, or nil if there is no such
+// child.
+func (n *prjNode) child(keyword string) *prjNode {
+	for _, value := range n.values {
+		if value.node != nil && value.node.keyword == keyword {
+			return value.node
+		}
+	}
+	return nil
+}
+
+// errorf returns a *PRJParseError at n's offset.
+func (n *prjNode) errorf(format string, args ...any) *PRJParseError {
+	return &PRJParseError{
+		Offset:  n.offset,
+		Message: n.keyword + ": " + fmt.Sprintf(format, args...),
+	}
+}
+
+// name returns n's name, which is its first value and must be a quoted
+// string.
+func (n *prjNode) name() (string, error) {
+	if len(n.values) == 0 || !n.values[0].quoted {
+		return "", n.errorf("missing name")
+	}
+	return n.values[0].text, nil
+}
+
+// number returns n's ith value, which must be a number.
+func (n *prjNode) number(i int) (float64, error) {
+	if i >= len(n.values) {
+		return 0, n.errorf("missing value")
+	}
+	value := n.values[i]
+	if value.node != nil || value.quoted {
+		return 0, &PRJParseError{
+			Offset:  value.offset,
+			Message: n.keyword + ": expected number",
+		}
+	}
+	number, err := strconv.ParseFloat(value.text, 64)
+	if err != nil {
+		return 0, &PRJParseError{
+			Offset:  value.offset,
+			Message: n.keyword + ": " + value.text + ": invalid number",
+		}
+	}
+	return number, nil
+}
+
+// requiredChild returns n's first child node with keyword, or an error if
+// there is no such child.
+func (n *prjNode) requiredChild(keyword string) (*prjNode, error) {
+	if child := n.child(keyword); child != nil {
+		return child, nil
+	}
+	return nil, n.errorf("missing %s", keyword)
+}
+
+// parseNode parses a node, which is a keyword followed by a bracketed list of
+// values, at depth.
+func (p *prjParser) parseNode(depth int) (*prjNode, error) {
+	if depth > prjMaxDepth {
+		return nil, p.errorf("too deeply nested")
+	}
+	offset := p.offset
+	keyword := p.parseKeyword()
+	if keyword == "" {
+		return nil, p.errorf("expected keyword")
+	}
+	p.skipSpace()
+	var closing byte
+	switch p.peek() {
+	case '[':
+		closing = ']'
+	case '(':
+		closing = ')'
+	default:
+		return nil, p.errorf("expected [ or (")
+	}
+	p.offset++
+
+	node := &prjNode{
+		keyword: keyword,
+		offset:  offset,
+	}
+	for {
+		p.skipSpace()
+		value, err := p.parseValue(depth)
+		if err != nil {
+			return nil, err
+		}
+		node.values = append(node.values, value)
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.offset++
+		case closing:
+			p.offset++
+			return node, nil
+		default:
+			return nil, p.errorf("expected , or %c", closing)
+		}
+	}
+}
+
+// parseValue parses a quoted string, a number, an unquoted keyword, or a node
+// at depth.
+func (p *prjParser) parseValue(depth int) (*prjValue, error) {
+	offset := p.offset
+	switch c := p.peek(); {
+	case c == '"':
+		var sb strings.Builder
+		p.offset++
+		for {
+			end := strings.IndexByte(p.wkt[p.offset:], '"')
+			if end == -1 {
+				return nil, &PRJParseError{
+					Offset:  offset,
+					Message: "unterminated string",
+				}
+			}
+			sb.WriteString(p.wkt[p.offset : p.offset+end])
+			p.offset += end + 1
+			if p.peek() != '"' {
+				break
+			}
+			sb.WriteByte('"')
+			p.offset++
+		}
+		return &prjValue{
+			offset: offset,
+			text:   sb.String(),
+			quoted: true,
+		}, nil
+	case c == '+' || c == '-' || c == '.' || '0' <= c && c <= '9':
+		end := p.offset + 1
+		for end < len(p.wkt) && strings.IndexByte("+-.0123456789Ee", p.wkt[end]) != -1 {
+			end++
+		}
+		p.offset = end
+		return &prjValue{
+			offset: offset,
+			text:   p.wkt[offset:end],
+		}, nil
+	case isPRJKeywordStart(c):
+		keyword := p.parseKeyword()
+		p.skipSpace()
+		if c := p.peek(); c != '[' && c != '(' {
+			return &prjValue{
+				offset: offset,
+				text:   keyword,
+			}, nil
+		}
+		p.offset = offset
+		node, err := p.parseNode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &prjValue{
+			offset: offset,
+			node:   node,
+		}, nil
+	default:
+		return nil, p.errorf("expected value")
+	}
+}
+
+// parseKeyword parses a keyword, returning the empty string if there is no
+// keyword.
+func (p *prjParser) parseKeyword() string {
+	start := p.offset
+	if !isPRJKeywordStart(p.peek()) {
+		return ""
+	}
+	for p.offset < len(p.wkt) && (isPRJKeywordStart(p.wkt[p.offset]) || '0' <= p.wkt[p.offset] && p.wkt[p.offset] <= '9') {
+		p.offset++
+	}
+	return strings.ToUpper(p.wkt[start:p.offset])
+}
+
+// peek returns the next byte, or zero if there are no more bytes.
+func (p *prjParser) peek() byte {
+	if p.offset >= len(p.wkt) {
+		return 0
+	}
+	return p.wkt[p.offset]
+}
+
+// skipSpace skips whitespace.
+func (p *prjParser) skipSpace() {
+	for p.offset < len(p.wkt) && strings.IndexByte(" \t\n\r\x00", p.wkt[p.offset]) != -1 {
+		p.offset++
+	}
+}
+
+// errorf returns a *PRJParseError at p's current offset.
+func (p *prjParser) errorf(format string, args ...any) *PRJParseError {
+	message := fmt.Sprintf(format, args...)
+	if p.offset >= len(p.wkt) {
+		message = "unexpected end of WKT: " + message
+	}
+	return &PRJParseError{
+		Offset:  p.offset,
+		Message: message,
+	}
+}
+
+// isPRJKeywordStart returns if c can start a WKT keyword.
+func isPRJKeywordStart(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || c == '_'
+}
+
+// writePRJString writes s as a quoted WKT string to sb.
+func writePRJString(sb *strings.Builder, s string) {
+	sb.WriteByte('"')
+	sb.WriteString(strings.ReplaceAll(s, `"`, `""`))
+	sb.WriteByte('"')
+}
+
+// writePRJNumber writes value to sb like ESRI software does, with 15
+// significant digits and at least one digit after the decimal point. WKT
+// cannot represent NaNs or infinities.
+func writePRJNumber(sb *strings.Builder, value float64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%f: invalid number", value)
+	}
+	text := strconv.FormatFloat(value, 'g', 15, 64)
+	if !strings.ContainsAny(text, ".e") {
+		text += ".0"
+	}
+	sb.WriteString(text)
+	return nil
+}
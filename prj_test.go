@@ -0,0 +1,264 @@
+package shapefile
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func FuzzParsePRJ(f *testing.F) {
+	data, err := os.ReadFile(filepath.Join("testdata", "poly.prj"))
+	assert.NoError(f, err)
+	f.Add(string(data))
+
+	f.Fuzz(func(_ *testing.T, projection string) {
+		prj, err := ParsePRJ(projection)
+		if err != nil {
+			return
+		}
+		_ = prj.Datum()
+		_ = prj.LinearUnit()
+	})
+}
+
+func TestReadPRJProjCS(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "poly.prj"))
+	assert.NoError(t, err)
+	defer file.Close()
+
+	prj, err := ReadPRJ(file, 0)
+	assert.NoError(t, err)
+	assert.False(t, prj.IsGeographic())
+	assert.True(t, prj.IsProjected())
+	assert.Equal(t, &PRJProjCS{
+		Name: "OSGB 1936 / British National Grid",
+		GeogCS: &PRJGeogCS{
+			Name: "OSGB 1936",
+			Datum: &PRJDatum{
+				Name: "D_OSGB_1936",
+				Spheroid: &PRJSpheroid{
+					Name:              "Airy_1830",
+					SemiMajorAxis:     6377563.396,
+					InverseFlattening: 299.3249646,
+				},
+			},
+			PrimeMeridian: &PRJPrimeMeridian{
+				Name: "Greenwich",
+			},
+			AngularUnit: &PRJUnit{
+				Name:             "Degree",
+				ConversionFactor: 0.017453292519943295,
+			},
+		},
+		Projection: "Transverse_Mercator",
+		Parameters: []*PRJParameter{
+			{Name: "latitude_of_origin", Value: 49},
+			{Name: "central_meridian", Value: -2},
+			{Name: "scale_factor", Value: 0.9996012717},
+			{Name: "false_easting", Value: 400000},
+			{Name: "false_northing", Value: -100000},
+		},
+		LinearUnit: &PRJUnit{
+			Name:             "Meter",
+			ConversionFactor: 1,
+		},
+	}, prj.ProjCS)
+	assert.Equal(t, prj.ProjCS.GeogCS.Datum, prj.Datum())
+	assert.Equal(t, prj.ProjCS.GeogCS.AngularUnit, prj.AngularUnit())
+	assert.Equal(t, prj.ProjCS.LinearUnit, prj.LinearUnit())
+
+	centralMeridian, ok := prj.ProjCS.Parameter("Central_Meridian")
+	assert.True(t, ok)
+	assert.Equal(t, -2.0, centralMeridian)
+	_, ok = prj.ProjCS.Parameter("standard_parallel_1")
+	assert.False(t, ok)
+}
+
+func TestReadPRJGeogCS(t *testing.T) {
+	shapefile, err := ReadZipFile(filepath.Join("testdata", "Luftfahrthindernisse.zip"), nil)
+	assert.NoError(t, err)
+	prj := shapefile.PRJ
+	assert.True(t, prj.IsGeographic())
+	assert.False(t, prj.IsProjected())
+	assert.Equal(t, "GCS_ETRS_1989", prj.GeogCS.Name)
+	assert.Equal(t, "D_ETRS_1989", prj.Datum().Name)
+	assert.Equal(t, 298.257222101, prj.Datum().Spheroid.InverseFlattening)
+	assert.Equal(t, "Degree", prj.AngularUnit().Name)
+	assert.Zero(t, prj.LinearUnit())
+}
+
+func TestParsePRJOGC(t *testing.T) {
+	prj, err := ParsePRJ("\ufeff" + `GEOGCS["WGS 84",
+    DATUM["WGS_1984",
+        SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],
+        TOWGS84[0,0,0,0,0,0,0],
+        AUTHORITY["EPSG","6326"]],
+    PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],
+    UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],
+    AXIS["Latitude",NORTH],
+    AXIS["Longitude",EAST],
+    AUTHORITY["EPSG","4326"]]
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, &PRJAuthority{Name: "EPSG", Code: "4326"}, prj.GeogCS.Authority)
+	assert.Equal(t, &PRJAuthority{Name: "EPSG", Code: "6326"}, prj.Datum().Authority)
+	assert.Equal(t, &PRJAuthority{Name: "EPSG", Code: "7030"}, prj.Datum().Spheroid.Authority)
+	assert.Equal(t, []float64{0, 0, 0, 0, 0, 0, 0}, prj.Datum().ToWGS84)
+}
+
+func TestParsePRJErrors(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "poly.prj"))
+	assert.NoError(t, err)
+	projection := string(data)
+
+	for _, tc := range []struct {
+		name        string
+		projection  string
+		expectedErr string
+	}{
+		{
+			name:        "empty",
+			expectedErr: "offset 0: unexpected end of WKT: expected keyword",
+		},
+		{
+			name:        "unsupported",
+			projection:  `GEOCCS["WGS 84"]`,
+			expectedErr: "offset 0: GEOCCS: unsupported coordinate reference system",
+		},
+		{
+			name:        "missing_bracket",
+			projection:  `GEOGCS "WGS 84"`,
+			expectedErr: "offset 7: expected [ or (",
+		},
+		{
+			name:        "unterminated_string",
+			projection:  `GEOGCS["WGS 84]`,
+			expectedErr: "offset 7: unterminated string",
+		},
+		{
+			name:        "truncated",
+			projection:  projection[:110],
+			expectedErr: "offset 110: unexpected end of WKT: expected , or ]",
+		},
+		{
+			name:        "trailing_data",
+			projection:  projection + "]",
+			expectedErr: "offset 415: trailing data",
+		},
+		{
+			name:        "invalid_number",
+			projection:  strings.Replace(projection, "6377563.396", "6377563.3.96", 1),
+			expectedErr: "offset 103: SPHEROID: 6377563.3.96: invalid number",
+		},
+		{
+			name:        "expected_number",
+			projection:  strings.Replace(projection, "6377563.396", `"a"`, 1),
+			expectedErr: "offset 103: SPHEROID: expected number",
+		},
+		{
+			name:        "missing_datum",
+			projection:  `GEOGCS["WGS 84",PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]]`,
+			expectedErr: "offset 0: GEOGCS: missing DATUM",
+		},
+		{
+			name:        "missing_name",
+			projection:  strings.Replace(projection, `PROJECTION["Transverse_Mercator"]`, `PROJECTION[1]`, 1),
+			expectedErr: "offset 188: PROJECTION: missing name",
+		},
+		{
+			name:        "too_deeply_nested",
+			projection:  strings.Repeat("A[", prjMaxDepth+2),
+			expectedErr: "offset 34: too deeply nested",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParsePRJ(tc.projection)
+			var prjParseError *PRJParseError
+			assert.True(t, errors.As(err, &prjParseError))
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestReadPRJInvalid(t *testing.T) {
+	prj, err := ReadPRJ(strings.NewReader("invalid"), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &PRJ{Projection: "invalid"}, prj)
+	assert.False(t, prj.IsGeographic())
+	assert.False(t, prj.IsProjected())
+	assert.Zero(t, prj.Datum())
+}
+
+func TestPRJESRIWKT(t *testing.T) {
+	shapefile, err := ReadZipFile(filepath.Join("testdata", "110m-admin-0-countries.zip"), nil)
+	assert.NoError(t, err)
+	esriWKT, err := shapefile.PRJ.ESRIWKT()
+	assert.NoError(t, err)
+	assert.Equal(t, shapefile.PRJ.Projection, esriWKT)
+
+	prj, err := ParsePRJ(`GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],TOWGS84[0,0,0,0,0,0,0]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433],AUTHORITY["EPSG","4326"]]`)
+	assert.NoError(t, err)
+	esriWKT, err = prj.ESRIWKT()
+	assert.NoError(t, err)
+	assert.Equal(t, `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`, esriWKT)
+
+	prj.GeogCS.Name = `Quoted "name"`
+	esriWKT, err = prj.ESRIWKT()
+	assert.NoError(t, err)
+	parsedPRJ, err := ParsePRJ(esriWKT)
+	assert.NoError(t, err)
+	assert.Equal(t, `Quoted "name"`, parsedPRJ.GeogCS.Name)
+}
+
+func TestPRJESRIWKTOGCNames(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		wkt      string
+		expected string
+	}{
+		{
+			name:     "utm",
+			wkt:      `PROJCS["WGS 84 / UTM zone 33N",GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]],PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",15],PARAMETER["scale_factor",0.9996],PARAMETER["false_easting",500000],PARAMETER["false_northing",0],UNIT["metre",1,AUTHORITY["EPSG","9001"]],AXIS["Easting",EAST],AXIS["Northing",NORTH],AUTHORITY["EPSG","32633"]]`,
+			expected: `PROJCS["WGS_1984_UTM_Zone_33N",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["Latitude_Of_Origin",0.0],PARAMETER["Central_Meridian",15.0],PARAMETER["Scale_Factor",0.9996],PARAMETER["False_Easting",500000.0],PARAMETER["False_Northing",0.0],UNIT["Meter",1.0]]`,
+		},
+		{
+			name:     "albers",
+			wkt:      `PROJCS["NAD83 / Conus Albers",GEOGCS["NAD83",DATUM["North_American_Datum_1983",SPHEROID["GRS 1980",6378137,298.257222101]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Albers_Conic_Equal_Area"],PARAMETER["latitude_of_center",23],PARAMETER["longitude_of_center",-96],PARAMETER["standard_parallel_1",29.5],PARAMETER["standard_parallel_2",45.5],PARAMETER["false_easting",0],PARAMETER["false_northing",0],UNIT["metre",1]]`,
+			expected: `PROJCS["NAD_1983_Contiguous_USA_Albers",GEOGCS["GCS_North_American_1983",DATUM["D_North_American_1983",SPHEROID["GRS_1980",6378137.0,298.257222101]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Albers"],PARAMETER["Latitude_Of_Origin",23.0],PARAMETER["Central_Meridian",-96.0],PARAMETER["Standard_Parallel_1",29.5],PARAMETER["Standard_Parallel_2",45.5],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],UNIT["Meter",1.0]]`,
+		},
+		{
+			name:     "unknown",
+			wkt:      `PROJCS["Custom",GEOGCS["Custom GCS",DATUM["Custom Datum",SPHEROID["Custom Spheroid",6378000,300]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Oblique_Mercator"],PARAMETER["latitude_of_center",46],PARAMETER["longitude_of_center",7],PARAMETER["azimuth",90],PARAMETER["scale_factor",1],PARAMETER["false_easting",0],PARAMETER["false_northing",0],UNIT["US survey foot",0.304800609601219]]`,
+			expected: `PROJCS["Custom",GEOGCS["Custom GCS",DATUM["D_Custom_Datum",SPHEROID["Custom_Spheroid",6378000.0,300.0]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Hotine_Oblique_Mercator_Azimuth_Center"],PARAMETER["Latitude_Of_Center",46.0],PARAMETER["Longitude_Of_Center",7.0],PARAMETER["Azimuth",90.0],PARAMETER["Scale_Factor",1.0],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],UNIT["Foot_US",0.304800609601219]]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prj, err := ParsePRJ(tc.wkt)
+			assert.NoError(t, err)
+			esriWKT, err := prj.ESRIWKT()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, esriWKT)
+		})
+	}
+}
+
+func TestWritePRJErrors(t *testing.T) {
+	assert.EqualError(t, WritePRJ(io.Discard, &PRJ{}), "no coordinate reference system")
+	assert.EqualError(t, WritePRJ(io.Discard, &PRJ{ProjCS: &PRJProjCS{}}), "PROJCS: missing GEOGCS")
+	assert.EqualError(t, WritePRJ(io.Discard, &PRJ{GeogCS: &PRJGeogCS{}}), "GEOGCS: missing DATUM")
+
+	prj, err := PRJFromEPSG(32633)
+	assert.NoError(t, err)
+	prj.Projection = ""
+	prj.ProjCS.Parameters[0].Value = math.NaN()
+	assert.EqualError(t, WritePRJ(io.Discard, prj), "PARAMETER: NaN: invalid number")
+	prj.ProjCS.Parameters[0].Value = 0
+	prj.ProjCS.GeogCS.Datum.Spheroid.InverseFlattening = math.Inf(1)
+	assert.EqualError(t, WritePRJ(io.Discard, prj), "SPHEROID: +Inf: invalid number")
+}
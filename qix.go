@@ -0,0 +1,438 @@
+package shapefile
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"slices"
+
+	"github.com/twpayne/go-geom"
+)
+
+const (
+	qixHeaderSize      = 16
+	qixNodeSize        = 4 + 8*4 + 4 + 4
+	qixMaxDepth        = 64
+	qixMaxDefaultDepth = 12
+	qixMaxSubNodes     = 4
+	qixLSBByteOrder    = 1
+	qixMSBByteOrder    = 2
+	qixSplitRatio      = 0.55
+)
+
+// A QIX is a MapServer or GDAL .qix quadtree spatial index.
+//
+// See https://github.com/OSGeo/gdal/blob/master/frmts/shapelib/shptree.c.
+type QIX struct {
+	ByteOrder binary.ByteOrder
+	Version   int
+	NumShapes int
+	MaxDepth  int
+	Root      *QIXNode
+}
+
+// A QIXNode is a node in a QIX quadtree. The shapes in a node are contained
+// by the node's bounds but not by the bounds of any of its children.
+type QIXNode struct {
+	Bounds   *geom.Bounds
+	ShapeIDs []int // Zero-based record indexes.
+	Children []*QIXNode
+}
+
+// A qixParser parses QIX nodes.
+type qixParser struct {
+	data      []byte
+	offset    int
+	byteOrder binary.ByteOrder
+	numShapes int
+}
+
+// ReadQIX reads a QIX from an io.Reader.
+func ReadQIX(r io.Reader, size int64) (*QIX, error) {
+	if size < qixHeaderSize {
+		return nil, errors.New("file too short")
+	}
+	data, err := readAll(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return ParseQIX(data)
+}
+
+// ReadQIXZipFile reads a QIX from a *zip.File.
+func ReadQIXZipFile(zipFile *zip.File) (*QIX, error) {
+	readCloser, err := zipFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer readCloser.Close()
+	qix, err := ReadQIX(readCloser, int64(zipFile.UncompressedSize64))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", zipFile.Name, err)
+	}
+	return qix, nil
+}
+
+// ParseQIX parses a QIX from data.
+func ParseQIX(data []byte) (*QIX, error) {
+	if len(data) < qixHeaderSize {
+		return nil, errors.New("file too short")
+	}
+	if string(data[:3]) != "SQT" {
+		return nil, errors.New("invalid signature")
+	}
+	var byteOrder binary.ByteOrder
+	switch data[3] {
+	case qixLSBByteOrder:
+		byteOrder = binary.LittleEndian
+	case qixMSBByteOrder:
+		byteOrder = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%d: invalid byte order", data[3])
+	}
+	numShapes := int(int32(byteOrder.Uint32(data[8:12])))
+	if numShapes < 0 {
+		return nil, fmt.Errorf("%d: invalid number of shapes", numShapes)
+	}
+	maxDepth := int(int32(byteOrder.Uint32(data[12:16])))
+	if maxDepth < 0 || maxDepth > qixMaxDepth {
+		return nil, fmt.Errorf("%d: invalid maximum depth", maxDepth)
+	}
+
+	parser := &qixParser{
+		data:      data,
+		offset:    qixHeaderSize,
+		byteOrder: byteOrder,
+		numShapes: numShapes,
+	}
+	root, err := parser.parseNode(0)
+	if err != nil {
+		return nil, err
+	}
+	if parser.offset != len(data) {
+		return nil, errors.New("trailing data")
+	}
+
+	return &QIX{
+		ByteOrder: byteOrder,
+		Version:   int(data[4]),
+		NumShapes: numShapes,
+		MaxDepth:  maxDepth,
+		Root:      root,
+	}, nil
+}
+
+// Search returns an iterator over the IDs of the shapes in the nodes of q
+// whose bounds intersect bounds in X and Y, in increasing order. These are the
+// candidate records whose geometries might intersect bounds.
+func (q *QIX) Search(bounds *geom.Bounds) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		if q.Root == nil {
+			return
+		}
+		shapeIDs := q.Root.appendShapeIDs(nil, bounds)
+		slices.Sort(shapeIDs)
+		for _, shapeID := range slices.Compact(shapeIDs) {
+			if !yield(shapeID) {
+				return
+			}
+		}
+	}
+}
+
+// appendShapeIDs appends the shape IDs of n and its descendants whose bounds
+// intersect bounds to shapeIDs.
+func (n *QIXNode) appendShapeIDs(shapeIDs []int, bounds *geom.Bounds) []int {
+	if !bounds.Overlaps(geom.XY, n.Bounds) {
+		return shapeIDs
+	}
+	shapeIDs = append(shapeIDs, n.ShapeIDs...)
+	for _, child := range n.Children {
+		shapeIDs = child.appendShapeIDs(shapeIDs, bounds)
+	}
+	return shapeIDs
+}
+
+// parseNode parses the node at p's offset, at depth, and its children.
+func (p *qixParser) parseNode(depth int) (*QIXNode, error) {
+	if depth > qixMaxDepth {
+		return nil, errors.New("too deep")
+	}
+	if len(p.data)-p.offset < qixNodeSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	childrenSize := int(int32(p.readUint32()))
+	minX := p.readFloat64()
+	minY := p.readFloat64()
+	maxX := p.readFloat64()
+	maxY := p.readFloat64()
+	numShapes := int(int32(p.readUint32()))
+	if numShapes < 0 || numShapes > (len(p.data)-p.offset-4)/4 {
+		return nil, fmt.Errorf("%d: invalid number of shapes", numShapes)
+	}
+	shapeIDs := make([]int, 0, numShapes)
+	for range numShapes {
+		shapeID := int(int32(p.readUint32()))
+		if shapeID < 0 || shapeID >= p.numShapes {
+			return nil, fmt.Errorf("%d: invalid shape ID", shapeID)
+		}
+		shapeIDs = append(shapeIDs, shapeID)
+	}
+	numChildren := int(int32(p.readUint32()))
+	if numChildren < 0 || numChildren > qixMaxSubNodes {
+		return nil, fmt.Errorf("%d: invalid number of children", numChildren)
+	}
+
+	childrenOffset := p.offset
+	children := make([]*QIXNode, 0, numChildren)
+	for range numChildren {
+		child, err := p.parseNode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if p.offset-childrenOffset != childrenSize {
+		return nil, fmt.Errorf("%d: invalid offset", childrenSize)
+	}
+
+	return &QIXNode{
+		Bounds:   geom.NewBounds(geom.XY).Set(minX, minY, maxX, maxY),
+		ShapeIDs: shapeIDs,
+		Children: children,
+	}, nil
+}
+
+// readFloat64 reads a float64 from p.
+func (p *qixParser) readFloat64() float64 {
+	value := math.Float64frombits(p.byteOrder.Uint64(p.data[p.offset : p.offset+8]))
+	p.offset += 8
+	return value
+}
+
+// readUint32 reads a uint32 from p.
+func (p *qixParser) readUint32() uint32 {
+	value := p.byteOrder.Uint32(p.data[p.offset : p.offset+4])
+	p.offset += 4
+	return value
+}
+
+// WriteQIXOptions are options to NewQIX and WriteQIX.
+type WriteQIXOptions struct {
+	MaxDepth  int              // Defaults to a depth based on the number of records.
+	ByteOrder binary.ByteOrder // Defaults to binary.LittleEndian.
+}
+
+// NewQIX returns a new QIX for the records in shp, built with the same
+// algorithm as GDAL and MapServer's shptree. Each record is added to the
+// deepest node whose bounds contain the record's bounds, where each node is
+// split into four overlapping quadrants. Null records are not added.
+func NewQIX(shp *SHP, options *WriteQIXOptions) (*QIX, error) {
+	maxDepth := 0
+	byteOrder := binary.ByteOrder(binary.LittleEndian)
+	if options != nil {
+		maxDepth = options.MaxDepth
+		if options.ByteOrder != nil {
+			byteOrder = options.ByteOrder
+		}
+	}
+	switch {
+	case maxDepth < 0 || maxDepth > qixMaxDepth:
+		return nil, fmt.Errorf("%d: invalid maximum depth", maxDepth)
+	case maxDepth == 0:
+		maxDepth = qixDefaultMaxDepth(len(shp.Records))
+	}
+	if byteOrder != binary.LittleEndian && byteOrder != binary.BigEndian {
+		return nil, errors.New("unsupported byte order")
+	}
+
+	rootBounds := shp.Bounds
+	if rootBounds == nil {
+		rootBounds = geom.NewBounds(geom.XY)
+		for _, record := range shp.Records {
+			if bounds := shpRecordBounds(record); bounds != nil {
+				rootBounds.Extend(bounds.Polygon())
+			}
+		}
+	}
+	root := &QIXNode{
+		Bounds: geom.NewBounds(geom.XY).Set(rootBounds.Min(0), rootBounds.Min(1), rootBounds.Max(0), rootBounds.Max(1)),
+	}
+	for i, record := range shp.Records {
+		if bounds := shpRecordBounds(record); bounds != nil {
+			root.addShapeID(i, bounds, maxDepth)
+		}
+	}
+	root.trim()
+
+	return &QIX{
+		ByteOrder: byteOrder,
+		Version:   1,
+		NumShapes: len(shp.Records),
+		MaxDepth:  maxDepth,
+		Root:      root,
+	}, nil
+}
+
+// WriteQIX writes a QIX for the records in shp to w. See NewQIX.
+func WriteQIX(w io.Writer, shp *SHP, options *WriteQIXOptions) error {
+	qix, err := NewQIX(shp, options)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(qix.appendQIX(nil))
+	return err
+}
+
+// qixDefaultMaxDepth returns the default maximum depth of a QIX with
+// numShapes shapes, so that each leaf node contains about eight shapes.
+func qixDefaultMaxDepth(numShapes int) int {
+	maxDepth := 0
+	for maxNodeCount := 1; maxNodeCount*4 < numShapes; maxNodeCount *= 2 {
+		maxDepth++
+	}
+	return min(maxDepth, qixMaxDefaultDepth)
+}
+
+// shpRecordBounds returns the bounds of record, or nil if record is null.
+func shpRecordBounds(record *SHPRecord) *geom.Bounds {
+	switch {
+	case record.Bounds != nil:
+		return record.Bounds
+	case record.Geom != nil:
+		return geom.NewBounds(geom.XY).Extend(record.Geom)
+	default:
+		return nil
+	}
+}
+
+// appendQIX appends q to data.
+func (q *QIX) appendQIX(data []byte) []byte {
+	data = append(data, 'S', 'Q', 'T')
+	if q.ByteOrder == binary.BigEndian {
+		data = append(data, qixMSBByteOrder)
+	} else {
+		data = append(data, qixLSBByteOrder)
+	}
+	data = append(data, byte(q.Version), 0, 0, 0)
+	data = appendUint32(data, q.ByteOrder, uint32(q.NumShapes))
+	data = appendUint32(data, q.ByteOrder, uint32(q.MaxDepth))
+	return q.Root.appendNode(data, q.ByteOrder)
+}
+
+// addShapeID adds the shape with shapeID and bounds to the deepest descendant
+// of n, at most maxDepth levels deep, whose bounds contain bounds.
+func (n *QIXNode) addShapeID(shapeID int, bounds *geom.Bounds, maxDepth int) {
+	if maxDepth > 1 && len(n.Children) > 0 {
+		for _, child := range n.Children {
+			if qixBoundsContain(child.Bounds, bounds) {
+				child.addShapeID(shapeID, bounds, maxDepth-1)
+				return
+			}
+		}
+	} else if maxDepth > 1 {
+		half1, half2 := qixSplitBounds(n.Bounds)
+		quarter1, quarter2 := qixSplitBounds(half1)
+		quarter3, quarter4 := qixSplitBounds(half2)
+		quarters := []*geom.Bounds{quarter1, quarter2, quarter3, quarter4}
+		for _, quarter := range quarters {
+			if qixBoundsContain(quarter, bounds) {
+				n.Children = make([]*QIXNode, 0, len(quarters))
+				for _, quarter := range quarters {
+					n.Children = append(n.Children, &QIXNode{
+						Bounds: quarter,
+					})
+				}
+				n.addShapeID(shapeID, bounds, maxDepth)
+				return
+			}
+		}
+	}
+	n.ShapeIDs = append(n.ShapeIDs, shapeID)
+}
+
+// trim removes n's empty descendants and replaces nodes with no shapes and a
+// single child with that child. It returns if n itself is empty.
+func (n *QIXNode) trim() bool {
+	for i := 0; i < len(n.Children); {
+		if n.Children[i].trim() {
+			n.Children[i] = n.Children[len(n.Children)-1]
+			n.Children = n.Children[:len(n.Children)-1]
+			continue
+		}
+		i++
+	}
+	if len(n.Children) == 1 && len(n.ShapeIDs) == 0 {
+		*n = *n.Children[0]
+	}
+	return len(n.Children) == 0 && len(n.ShapeIDs) == 0
+}
+
+// size returns the size of n when written, excluding its children.
+func (n *QIXNode) size() int {
+	return qixNodeSize + 4*len(n.ShapeIDs)
+}
+
+// childrenSize returns the size of n's descendants when written.
+func (n *QIXNode) childrenSize() int {
+	childrenSize := 0
+	for _, child := range n.Children {
+		childrenSize += child.size() + child.childrenSize()
+	}
+	return childrenSize
+}
+
+// appendNode appends n and its descendants to data.
+func (n *QIXNode) appendNode(data []byte, byteOrder binary.ByteOrder) []byte {
+	data = appendUint32(data, byteOrder, uint32(n.childrenSize()))
+	for _, value := range []float64{n.Bounds.Min(0), n.Bounds.Min(1), n.Bounds.Max(0), n.Bounds.Max(1)} {
+		data = appendUint64(data, byteOrder, math.Float64bits(value))
+	}
+	data = appendUint32(data, byteOrder, uint32(len(n.ShapeIDs)))
+	for _, shapeID := range n.ShapeIDs {
+		data = appendUint32(data, byteOrder, uint32(shapeID))
+	}
+	data = appendUint32(data, byteOrder, uint32(len(n.Children)))
+	for _, child := range n.Children {
+		data = child.appendNode(data, byteOrder)
+	}
+	return data
+}
+
+// qixSplitBounds splits bounds into two overlapping halves along its longest
+// axis.
+func qixSplitBounds(bounds *geom.Bounds) (*geom.Bounds, *geom.Bounds) {
+	minX, minY, maxX, maxY := bounds.Min(0), bounds.Min(1), bounds.Max(0), bounds.Max(1)
+	if maxX-minX > maxY-minY {
+		rangeX := maxX - minX
+		return geom.NewBounds(geom.XY).Set(minX, minY, minX+rangeX*qixSplitRatio, maxY),
+			geom.NewBounds(geom.XY).Set(maxX-rangeX*qixSplitRatio, minY, maxX, maxY)
+	}
+	rangeY := maxY - minY
+	return geom.NewBounds(geom.XY).Set(minX, minY, maxX, minY+rangeY*qixSplitRatio),
+		geom.NewBounds(geom.XY).Set(minX, maxY-rangeY*qixSplitRatio, maxX, maxY)
+}
+
+// qixBoundsContain returns if outer contains inner in X and Y.
+func qixBoundsContain(outer, inner *geom.Bounds) bool {
+	return inner.Min(0) >= outer.Min(0) && inner.Max(0) <= outer.Max(0) &&
+		inner.Min(1) >= outer.Min(1) && inner.Max(1) <= outer.Max(1)
+}
+
+// appendUint32 appends value to data in byteOrder.
+func appendUint32(data []byte, byteOrder binary.ByteOrder, value uint32) []byte {
+	data = append(data, 0, 0, 0, 0)
+	byteOrder.PutUint32(data[len(data)-4:], value)
+	return data
+}
+
+// appendUint64 appends value to data in byteOrder.
+func appendUint64(data []byte, byteOrder binary.ByteOrder, value uint64) []byte {
+	data = append(data, 0, 0, 0, 0, 0, 0, 0, 0)
+	byteOrder.PutUint64(data[len(data)-8:], value)
+	return data
+}
@@ -0,0 +1,265 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-geom"
+)
+
+func FuzzReadQIX(f *testing.F) {
+	assert.NoError(f, addFuzzDataFromFS(f, os.DirFS("."), "testdata", ".qix"))
+
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		qix, err := ReadQIX(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		for range qix.Search(geom.NewBounds(geom.XY).Set(0, 0, 1, 1)) {
+		}
+	})
+}
+
+func TestReadQIX(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "poly.qix"))
+	assert.NoError(t, err)
+
+	qix, err := ReadQIX(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	assert.Equal[binary.ByteOrder](t, binary.LittleEndian, qix.ByteOrder)
+	assert.Equal(t, 1, qix.Version)
+	assert.Equal(t, 10, qix.NumShapes)
+	assert.Equal(t, 2, qix.MaxDepth)
+	assert.Equal(t, []int{0, 7, 8}, qix.Root.ShapeIDs)
+	assert.Equal(t, 2, len(qix.Root.Children))
+	assert.Equal(t, []int{6}, qix.Root.Children[0].ShapeIDs)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 9}, qix.Root.Children[1].ShapeIDs)
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, slices.Collect(qix.Search(qix.Root.Bounds)))
+	assert.Equal(t, []int{0, 6, 7, 8}, slices.Collect(qix.Search(geom.NewBounds(geom.XY).Set(481000, 4764500, 481500, 4765000))))
+	assert.Equal(t, []int(nil), slices.Collect(qix.Search(geom.NewBounds(geom.XY).Set(0, 0, 1, 1))))
+}
+
+func TestReadQIXInvalidSize(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "poly.qix"))
+	assert.NoError(t, err)
+	_, err = ReadQIX(bytes.NewReader(data), 1<<62)
+	assert.IsError(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestParseQIXErrors(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "poly.qix"))
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name        string
+		modify      func([]byte) []byte
+		expectedErr string
+	}{
+		{
+			name: "too_short",
+			modify: func(data []byte) []byte {
+				return data[:8]
+			},
+			expectedErr: "file too short",
+		},
+		{
+			name: "invalid_signature",
+			modify: func(data []byte) []byte {
+				data[0] = 'X'
+				return data
+			},
+			expectedErr: "invalid signature",
+		},
+		{
+			name: "invalid_byte_order",
+			modify: func(data []byte) []byte {
+				data[3] = 3
+				return data
+			},
+			expectedErr: "3: invalid byte order",
+		},
+		{
+			name: "invalid_shape_id",
+			modify: func(data []byte) []byte {
+				binary.LittleEndian.PutUint32(data[8:12], 5)
+				return data
+			},
+			expectedErr: "7: invalid shape ID",
+		},
+		{
+			name: "truncated",
+			modify: func(data []byte) []byte {
+				return data[:len(data)-4]
+			},
+			expectedErr: "6: invalid number of shapes",
+		},
+		{
+			name: "truncated_node",
+			modify: func(data []byte) []byte {
+				return data[:qixHeaderSize+qixNodeSize-1]
+			},
+			expectedErr: io.ErrUnexpectedEOF.Error(),
+		},
+		{
+			name: "trailing_data",
+			modify: func(data []byte) []byte {
+				return append(data, 0)
+			},
+			expectedErr: "trailing data",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseQIX(tc.modify(bytes.Clone(data)))
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestReaderSearch(t *testing.T) {
+	basename := filepath.Join("testdata", "poly")
+	shapefile, err := Read(basename, nil)
+	assert.NoError(t, err)
+
+	readerAts := make(map[string]io.ReaderAt)
+	sizes := make(map[string]int64)
+	for _, ext := range []string{".shp", ".shx"} {
+		data, err := os.ReadFile(basename + ext)
+		assert.NoError(t, err)
+		readerAts[ext] = bytes.NewReader(data)
+		sizes[ext] = int64(len(data))
+	}
+	readerWithoutQIX, err := NewReader(readerAts, sizes, nil)
+	assert.NoError(t, err)
+	assert.Zero(t, readerWithoutQIX.QIX())
+
+	reader, err := Open(basename, nil)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, reader.Close())
+	}()
+	assert.NotZero(t, reader.QIX())
+
+	for _, bounds := range []*geom.Bounds{
+		geom.NewBounds(geom.XY).Set(480400, 4764000, 481000, 4765000),
+		geom.NewBounds(geom.XY).Set(479000, 4765200, 479100, 4765300),
+		geom.NewBounds(geom.XY).Set(0, 0, 1, 1),
+		reader.SHPHeader().Bounds,
+	} {
+		var expected []int
+		for i, record := range shapefile.SHP.Records {
+			if bounds.Overlaps(geom.XY, record.Bounds) {
+				expected = append(expected, i)
+			}
+		}
+
+		assert.Equal(t, expected, collectSearch(t, reader.Search(bounds)))
+
+		assert.Equal(t, expected, collectSearch(t, readerWithoutQIX.Search(bounds)))
+	}
+}
+
+func TestReaderSearchError(t *testing.T) {
+	reader, err := NewReader(map[string]io.ReaderAt{}, map[string]int64{}, nil)
+	assert.NoError(t, err)
+	var indexes []int
+	var errs []error
+	for i, err := range reader.Search(geom.NewBounds(geom.XY).Set(0, 0, 1, 1)) {
+		indexes = append(indexes, i)
+		errs = append(errs, err)
+	}
+	assert.Equal(t, []int{-1}, indexes)
+	assert.Equal(t, 1, len(errs))
+	assert.EqualError(t, errs[0], "no .shp file")
+}
+
+// collectSearch returns the indexes returned by search, which must not return
+// an error.
+func collectSearch(t *testing.T, search iter.Seq2[int, error]) []int {
+	t.Helper()
+	var indexes []int
+	for i, err := range search {
+		assert.NoError(t, err)
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+func TestWriteQIX(t *testing.T) {
+	expected, err := os.ReadFile(filepath.Join("testdata", "poly.qix"))
+	assert.NoError(t, err)
+
+	shapefile, err := Read(filepath.Join("testdata", "poly"), nil)
+	assert.NoError(t, err)
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, WriteQIX(buffer, shapefile.SHP, nil))
+	assert.Equal(t, expected, buffer.Bytes())
+
+	buffer.Reset()
+	assert.NoError(t, WriteQIX(buffer, shapefile.SHP, &WriteQIXOptions{
+		MaxDepth:  1,
+		ByteOrder: binary.BigEndian,
+	}))
+	qix, err := ParseQIX(buffer.Bytes())
+	assert.NoError(t, err)
+	assert.Equal[binary.ByteOrder](t, binary.BigEndian, qix.ByteOrder)
+	assert.Equal(t, 1, qix.MaxDepth)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, qix.Root.ShapeIDs)
+	assert.Equal(t, 0, len(qix.Root.Children))
+
+	assert.EqualError(t, WriteQIX(io.Discard, shapefile.SHP, &WriteQIXOptions{
+		MaxDepth: qixMaxDepth + 1,
+	}), "65: invalid maximum depth")
+}
+
+func TestNewQIX(t *testing.T) {
+	shapefile, err := ReadZipFile(filepath.Join("testdata", "110m-admin-0-countries.zip"), nil)
+	assert.NoError(t, err)
+
+	qix, err := NewQIX(shapefile.SHP, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, qixDefaultMaxDepth(len(shapefile.SHP.Records)), qix.MaxDepth)
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, WriteQIX(buffer, shapefile.SHP, nil))
+	parsedQIX, err := ParseQIX(buffer.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, qix, parsedQIX)
+
+	for _, bounds := range []*geom.Bounds{
+		geom.NewBounds(geom.XY).Set(-10, 35, 30, 70),
+		geom.NewBounds(geom.XY).Set(100, -50, 180, 0),
+		geom.NewBounds(geom.XY).Set(-180, -90, 180, 90),
+	} {
+		candidates := slices.Collect(qix.Search(bounds))
+		for i, record := range shapefile.SHP.Records {
+			if bounds.Overlaps(geom.XY, record.Bounds) {
+				_, found := slices.BinarySearch(candidates, i)
+				assert.True(t, found)
+			}
+		}
+	}
+}
+
+func TestQIXDefaultMaxDepth(t *testing.T) {
+	for _, tc := range []struct {
+		numShapes int
+		expected  int
+	}{
+		{numShapes: 0, expected: 0},
+		{numShapes: 4, expected: 0},
+		{numShapes: 5, expected: 1},
+		{numShapes: 10, expected: 2},
+		{numShapes: 1000000, expected: 12},
+	} {
+		assert.Equal(t, tc.expected, qixDefaultMaxDepth(tc.numShapes))
+	}
+}
@@ -0,0 +1,473 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+
+	"github.com/twpayne/go-geom"
+	"golang.org/x/text/encoding"
+)
+
+// A Reader provides lazy, random access to the records of a Shapefile. The
+// .shx file is used to locate records in the .shp file, and the .dbf header is
+// used to locate records in the .dbf file, so only the requested records are
+// read. If there is a .qix or .sbn file then it is used to find the records
+// that intersect a bounding box. A Reader is safe for concurrent use if its
+// underlying io.ReaderAts are.
+type Reader struct {
+	shp              io.ReaderAt
+	shpSize          int64
+	shx              io.ReaderAt
+	dbf              io.ReaderAt
+	shpHeader        *SHxHeader
+	shxHeader        *SHxHeader
+	dbfHeader        *DBFHeader
+	fieldDescriptors []*DBFFieldDescriptor
+	dbfLayout        *dbfRecordLayout
+	encoding         encoding.Encoding
+	charset          string
+	options          *ReadShapefileOptions
+	numRecords       int
+	prj              *PRJ
+	cpg              *CPG
+	qix              *QIX
+	sbn              *SBN
+	closers          []io.Closer
+}
+
+// Open opens the Shapefile with basename for random access.
+func Open(basename string, options *ReadShapefileOptions) (*Reader, error) {
+	readerAts := make(map[string]io.ReaderAt)
+	sizes := make(map[string]int64)
+	var closers []io.Closer
+	closeAll := func() {
+		for _, closer := range closers {
+			closer.Close()
+		}
+	}
+
+	for _, ext := range []string{".cpg", ".dbf", ".dbt", ".fpt", ".prj", ".qix", ".sbn", ".shp", ".shx"} {
+		file, size, err := openWithSize(basename + ext)
+		if file != nil {
+			closers = append(closers, file)
+		}
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// Do nothing.
+		case err != nil:
+			closeAll()
+			return nil, fmt.Errorf("%s%s: %w", basename, ext, err)
+		default:
+			readerAts[ext] = file
+			sizes[ext] = size
+		}
+	}
+
+	reader, err := NewReader(readerAts, sizes, options)
+	if err != nil {
+		closeAll()
+		return nil, fmt.Errorf("%s: %w", basename, err)
+	}
+	reader.closers = closers
+	return reader, nil
+}
+
+// NewReader returns a new Reader that reads from readerAts, which are indexed
+// by extension, with sizes. A .shp file can only be read if there is a
+// corresponding .shx file.
+func NewReader(readerAts map[string]io.ReaderAt, sizes map[string]int64, options *ReadShapefileOptions) (*Reader, error) {
+	if options == nil {
+		options = &ReadShapefileOptions{}
+	}
+
+	var cpg *CPG
+	if readerAt, ok := readerAts[".cpg"]; ok {
+		var err error
+		cpg, err = ReadCPG(io.NewSectionReader(readerAt, 0, sizes[".cpg"]), sizes[".cpg"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadCPG: %w", err)
+		}
+	}
+
+	var dbt *DBT
+	if readerAt, ok := readerAts[".dbt"]; ok {
+		var err error
+		dbt, err = ReadDBT(io.NewSectionReader(readerAt, 0, sizes[".dbt"]), sizes[".dbt"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadDBT: %w", err)
+		}
+	}
+
+	var fpt *FPT
+	if readerAt, ok := readerAts[".fpt"]; ok {
+		var err error
+		fpt, err = ReadFPT(io.NewSectionReader(readerAt, 0, sizes[".fpt"]), sizes[".fpt"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadFPT: %w", err)
+		}
+	}
+
+	// Copy options so that the caller's options are not modified.
+	shapefileOptions := *options
+	shapefileOptions.DBF = options.DBF.withSiblingFiles(cpg, dbt, fpt)
+	options = &shapefileOptions
+
+	var prj *PRJ
+	if readerAt, ok := readerAts[".prj"]; ok {
+		var err error
+		prj, err = ReadPRJ(io.NewSectionReader(readerAt, 0, sizes[".prj"]), sizes[".prj"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadPRJ: %w", err)
+		}
+	}
+	prj, readSHPOptions, err := options.transformSHP(prj)
+	if err != nil {
+		return nil, err
+	}
+	options.SHP = readSHPOptions
+
+	var qix *QIX
+	if readerAt, ok := readerAts[".qix"]; ok {
+		var err error
+		qix, err = ReadQIX(io.NewSectionReader(readerAt, 0, sizes[".qix"]), sizes[".qix"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadQIX: %w", err)
+		}
+	}
+
+	var sbn *SBN
+	if readerAt, ok := readerAts[".sbn"]; ok {
+		var err error
+		sbn, err = ReadSBN(io.NewSectionReader(readerAt, 0, sizes[".sbn"]), sizes[".sbn"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadSBN: %w", err)
+		}
+	}
+
+	reader := &Reader{
+		options:    options,
+		numRecords: -1,
+		prj:        prj,
+		cpg:        cpg,
+		qix:        qix,
+		sbn:        sbn,
+	}
+
+	if readerAt, ok := readerAts[".shx"]; ok {
+		header, err := readSHxHeader(io.NewSectionReader(readerAt, 0, sizes[".shx"]), sizes[".shx"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadSHX: %w", err)
+		}
+		if err := options.SHP.transformSHxHeader(header); err != nil {
+			return nil, fmt.Errorf("ReadSHX: %w", err)
+		}
+		reader.shx = readerAt
+		reader.shxHeader = header
+		reader.numRecords = int((sizes[".shx"] - headerSize) / 8)
+	}
+
+	if readerAt, ok := readerAts[".shp"]; ok {
+		if reader.shx == nil {
+			return nil, errors.New("can't read .shp file without .shx file")
+		}
+		header, err := readSHxHeader(io.NewSectionReader(readerAt, 0, sizes[".shp"]), sizes[".shp"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadSHP: %w", err)
+		}
+		if err := options.SHP.transformSHxHeader(header); err != nil {
+			return nil, fmt.Errorf("ReadSHP: %w", err)
+		}
+		reader.shp = readerAt
+		reader.shpSize = sizes[".shp"]
+		reader.shpHeader = header
+	}
+
+	if readerAt, ok := readerAts[".dbf"]; ok {
+		sectionReader := io.NewSectionReader(readerAt, 0, sizes[".dbf"])
+		header, err := readDBFHeader(sectionReader, options.DBF)
+		if err != nil {
+			return nil, fmt.Errorf("ReadDBF: %w", err)
+		}
+		fieldDescriptors, err := readDBFFieldDescriptors(sectionReader, header)
+		if err != nil {
+			return nil, fmt.Errorf("ReadDBF: %w", err)
+		}
+		layout, err := newDBFRecordLayout(header, fieldDescriptors, options.DBF)
+		if err != nil {
+			return nil, fmt.Errorf("ReadDBF: %w", err)
+		}
+		enc, charset, err := dbfCharsetEncoding(header, fieldDescriptors, options.DBF, func(n int) ([]byte, error) {
+			data := make([]byte, n)
+			switch n, err := readerAt.ReadAt(data, int64(header.HeaderSize)); {
+			case errors.Is(err, io.EOF):
+				return data[:n], nil
+			case err != nil:
+				return nil, err
+			default:
+				return data, nil
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ReadDBF: %w", err)
+		}
+		if reader.numRecords != -1 && reader.numRecords != header.Records {
+			return nil, errors.New("inconsistent number of records")
+		}
+		reader.dbf = readerAt
+		reader.dbfHeader = header
+		reader.fieldDescriptors = layout.fieldDescriptors()
+		reader.dbfLayout = layout
+		reader.encoding = enc
+		reader.charset = charset
+		reader.numRecords = header.Records
+	}
+
+	if reader.numRecords == -1 {
+		reader.numRecords = 0
+	}
+
+	if qix != nil && qix.NumShapes != reader.numRecords {
+		return nil, errors.New("inconsistent number of records")
+	}
+	if sbn != nil && sbn.NumShapes != reader.numRecords {
+		return nil, errors.New("inconsistent number of records")
+	}
+
+	return reader, nil
+}
+
+// NumRecords returns the number of records in r.
+func (r *Reader) NumRecords() int {
+	return r.numRecords
+}
+
+// Record returns r's ith record's fields and geometry. The fields are nil if
+// the ith DBF record is deleted.
+func (r *Reader) Record(i int) (map[string]any, geom.T, error) {
+	var fields map[string]any
+	if r.dbf != nil {
+		record, err := r.DBFRecord(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		if record != nil {
+			fields = make(map[string]any, len(r.fieldDescriptors))
+			for j, fieldDescriptor := range r.fieldDescriptors {
+				fields[fieldDescriptor.Name] = record[j]
+			}
+		}
+	}
+	var g geom.T
+	if r.shp != nil {
+		shpRecord, err := r.SHPRecord(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		g = shpRecord.Geom
+	}
+	return fields, g, nil
+}
+
+// SHXRecord returns r's ith SHX record.
+func (r *Reader) SHXRecord(i int) (SHXRecord, error) {
+	if r.shx == nil {
+		return SHXRecord{}, errors.New("no .shx file")
+	}
+	if i < 0 || i >= r.numRecords {
+		return SHXRecord{}, fmt.Errorf("record %d: out of range", i)
+	}
+	data := make([]byte, 8)
+	if _, err := r.shx.ReadAt(data, headerSize+8*int64(i)); err != nil {
+		return SHXRecord{}, fmt.Errorf("record %d: %w", i, err)
+	}
+	return ParseSHXRecord(data), nil
+}
+
+// Search returns an iterator over the indexes of the records whose
+// geometries' bounds intersect bounds in X and Y, in increasing order. If
+// there is a .qix file then only the records in the quadtree nodes that
+// intersect bounds are considered, otherwise if there is a .sbn file then only
+// the records whose indexed bounds intersect bounds are considered, otherwise
+// all records are considered. Only the bounds of each considered record are
+// read from the .shp file, located with the .shx file. If an error occurs then
+// it is yielded with an index of -1 and iteration stops.
+func (r *Reader) Search(bounds *geom.Bounds) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		if r.shp == nil {
+			yield(-1, errors.New("no .shp file"))
+			return
+		}
+		var candidates iter.Seq[int]
+		switch {
+		case r.qix != nil:
+			candidates = r.qix.Search(bounds)
+		case r.sbn != nil:
+			candidates = r.sbn.Search(bounds)
+		default:
+			candidates = func(yield func(int) bool) {
+				for i := range r.numRecords {
+					if !yield(i) {
+						return
+					}
+				}
+			}
+		}
+		for i := range candidates {
+			intersects, err := r.shpRecordIntersects(i, bounds)
+			if err != nil {
+				yield(-1, err)
+				return
+			}
+			if intersects && !yield(i, nil) {
+				return
+			}
+		}
+	}
+}
+
+// shpRecordIntersects returns if the bounds of r's ith SHP record intersect
+// bounds in X and Y.
+func (r *Reader) shpRecordIntersects(i int, bounds *geom.Bounds) (bool, error) {
+	shxRecord, err := r.SHXRecord(i)
+	if err != nil {
+		return false, err
+	}
+	prefixData := make([]byte, min(shxRecord.ContentLength, shpRecordBoundsEnd))
+	if _, err := r.shp.ReadAt(prefixData, int64(shxRecord.Offset)+8); err != nil {
+		return false, fmt.Errorf("record %d: %w", i, err)
+	}
+	if len(prefixData) < 4 || ShapeType(binary.LittleEndian.Uint32(prefixData[:4])) == ShapeTypeNull {
+		return false, nil
+	}
+	return skippedSHPRecord(prefixData, i+1, shxRecord.ContentLength, bounds) == nil, nil
+}
+
+// SHPRecord returns r's ith SHP record.
+func (r *Reader) SHPRecord(i int) (*SHPRecord, error) {
+	if r.shp == nil {
+		return nil, errors.New("no .shp file")
+	}
+	shxRecord, err := r.SHXRecord(i)
+	if err != nil {
+		return nil, err
+	}
+	if r.options.SHP != nil && r.options.SHP.MaxRecordSize != 0 && shxRecord.ContentLength > r.options.SHP.MaxRecordSize {
+		return nil, fmt.Errorf("record %d: content length too large", i)
+	}
+	if shxRecord.Offset < headerSize || int64(shxRecord.Offset)+8+int64(shxRecord.ContentLength) > r.shpSize {
+		return nil, fmt.Errorf("record %d: invalid offset or content length", i)
+	}
+	data := make([]byte, 8+shxRecord.ContentLength)
+	if _, err := r.shp.ReadAt(data, int64(shxRecord.Offset)); err != nil {
+		return nil, fmt.Errorf("record %d: %w", i, err)
+	}
+	record, err := ReadSHPRecord(bytes.NewReader(data), r.options.SHP)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("record %d: %w", i, err)
+	case record.Number != i+1:
+		return nil, fmt.Errorf("record %d: invalid record number (expected %d)", i, record.Number)
+	default:
+		return record, nil
+	}
+}
+
+// DBFRecord returns r's ith DBF record. It returns nil if the record is
+// deleted, unless the ReadDeleted option is set.
+func (r *Reader) DBFRecord(i int) ([]any, error) {
+	if r.dbf == nil {
+		return nil, errors.New("no .dbf file")
+	}
+	if i < 0 || i >= r.numRecords {
+		return nil, fmt.Errorf("record %d: out of range", i)
+	}
+	recordData := make([]byte, r.dbfHeader.RecordSize)
+	offset := int64(r.dbfHeader.HeaderSize) + int64(i)*int64(r.dbfHeader.RecordSize)
+	if _, err := r.dbf.ReadAt(recordData, offset); err != nil {
+		return nil, fmt.Errorf("record %d: %w", i, err)
+	}
+	record, err := parseDBFRecord(recordData, r.dbfLayout, r.encoding.NewDecoder(), r.options.DBF)
+	if err != nil {
+		return nil, fmt.Errorf("record %d: %w", i, err)
+	}
+	return record, nil
+}
+
+// Deleted returns if r's ith DBF record is deleted.
+func (r *Reader) Deleted(i int) (bool, error) {
+	if r.dbf == nil {
+		return false, errors.New("no .dbf file")
+	}
+	if i < 0 || i >= r.numRecords {
+		return false, fmt.Errorf("record %d: out of range", i)
+	}
+	flag := make([]byte, 1)
+	offset := int64(r.dbfHeader.HeaderSize) + int64(i)*int64(r.dbfHeader.RecordSize)
+	if _, err := r.dbf.ReadAt(flag, offset); err != nil {
+		return false, fmt.Errorf("record %d: %w", i, err)
+	}
+	return flag[0] == '*', nil
+}
+
+// SHPHeader returns the header of the .shp file, or nil if there is no .shp
+// file.
+func (r *Reader) SHPHeader() *SHxHeader {
+	return r.shpHeader
+}
+
+// SHxHeader returns the header of the .shx file, or nil if there is no .shx
+// file.
+func (r *Reader) SHxHeader() *SHxHeader {
+	return r.shxHeader
+}
+
+// DBFHeader returns the header of the .dbf file, or nil if there is no .dbf
+// file.
+func (r *Reader) DBFHeader() *DBFHeader {
+	return r.dbfHeader
+}
+
+// DBFFieldDescriptors returns the field descriptors of the .dbf file.
+func (r *Reader) DBFFieldDescriptors() []*DBFFieldDescriptor {
+	return r.fieldDescriptors
+}
+
+// PRJ returns the .prj file, or nil if there is no .prj file.
+func (r *Reader) PRJ() *PRJ {
+	return r.prj
+}
+
+// QIX returns the .qix file, or nil if there is no .qix file.
+func (r *Reader) QIX() *QIX {
+	return r.qix
+}
+
+// SBN returns the .sbn file, or nil if there is no .sbn file.
+func (r *Reader) SBN() *SBN {
+	return r.sbn
+}
+
+// CPG returns the .cpg file, or nil if there is no .cpg file.
+func (r *Reader) CPG() *CPG {
+	return r.cpg
+}
+
+// DBFCharset returns the charset used to decode character fields in the .dbf
+// file, or the empty string if there is no .dbf file.
+func (r *Reader) DBFCharset() string {
+	return r.charset
+}
+
+// Close closes any files opened by Open.
+func (r *Reader) Close() error {
+	var err error
+	for _, closer := range r.closers {
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestOpen(t *testing.T) {
+	for _, basename := range []string{
+		"line",
+		"point",
+		"poly",
+		"polygon_hole",
+	} {
+		t.Run(basename, func(t *testing.T) {
+			expected, err := Read(filepath.Join("testdata", basename), nil)
+			assert.NoError(t, err)
+
+			reader, err := Open(filepath.Join("testdata", basename), nil)
+			assert.NoError(t, err)
+			defer func() {
+				assert.NoError(t, reader.Close())
+			}()
+
+			assert.Equal(t, expected.NumRecords(), reader.NumRecords())
+			assert.Equal(t, &expected.SHP.SHxHeader, reader.SHPHeader())
+			assert.Equal(t, &expected.SHX.SHxHeader, reader.SHxHeader())
+			assert.Equal(t, expected.PRJ, reader.PRJ())
+			if expected.DBF != nil {
+				assert.Equal(t, &expected.DBF.DBFHeader, reader.DBFHeader())
+				assert.Equal(t, expected.DBF.FieldDescriptors, reader.DBFFieldDescriptors())
+			}
+
+			for i := reader.NumRecords() - 1; i >= 0; i-- {
+				expectedFields, expectedGeom := expected.Record(i)
+				fields, g, err := reader.Record(i)
+				assert.NoError(t, err)
+				assert.Equal(t, expectedFields, fields)
+				assert.Equal(t, expectedGeom, g)
+
+				shpRecord, err := reader.SHPRecord(i)
+				assert.NoError(t, err)
+				assert.Equal(t, expected.SHP.Records[i], shpRecord)
+
+				shxRecord, err := reader.SHXRecord(i)
+				assert.NoError(t, err)
+				assert.Equal(t, expected.SHX.Records[i], shxRecord)
+			}
+
+			_, _, err = reader.Record(reader.NumRecords())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestOpenWithoutSHX(t *testing.T) {
+	_, err := Open(filepath.Join("testdata", "linem"), nil)
+	assert.EqualError(t, err, "testdata/linem: can't read .shp file without .shx file")
+}
+
+func TestReaderSHPRecordInvalidSHX(t *testing.T) {
+	shpData, err := os.ReadFile(filepath.Join("testdata", "poly.shp"))
+	assert.NoError(t, err)
+	shxData, err := os.ReadFile(filepath.Join("testdata", "poly.shx"))
+	assert.NoError(t, err)
+	binary.BigEndian.PutUint32(shxData[104:108], 0x7ffffff0)
+
+	reader, err := NewReader(map[string]io.ReaderAt{
+		".shp": bytes.NewReader(shpData),
+		".shx": bytes.NewReader(shxData),
+	}, map[string]int64{
+		".shp": int64(len(shpData)),
+		".shx": int64(len(shxData)),
+	}, nil)
+	assert.NoError(t, err)
+	_, err = reader.SHPRecord(0)
+	assert.EqualError(t, err, "record 0: invalid offset or content length")
+	_, err = reader.SHPRecord(1)
+	assert.NoError(t, err)
+}
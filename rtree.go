@@ -0,0 +1,232 @@
+package shapefile
+
+import (
+	"cmp"
+	"iter"
+	"math"
+	"slices"
+
+	"github.com/twpayne/go-geom"
+)
+
+// rtreeNodeCapacity is the maximum number of children of an RTree node.
+const rtreeNodeCapacity = 16
+
+// An RTree is an in-memory R-tree spatial index over the bounds of the records
+// of a SHP. It is bulk loaded with the Sort-Tile-Recursive (STR) algorithm and
+// is not updated if the records change. An RTree is safe for concurrent use.
+type RTree struct {
+	root *rtreeNode
+}
+
+// An rtreeNode is a node in an RTree. Leaf nodes are records and have no
+// children.
+type rtreeNode struct {
+	minX, minY, maxX, maxY float64
+	index                  int
+	children               []*rtreeNode
+}
+
+// An rtreeQueueItem is an item in an RTree nearest neighbor search queue.
+type rtreeQueueItem struct {
+	node     *rtreeNode
+	distance float64
+}
+
+// An rtreeQueue is a priority queue of RTree nodes ordered by increasing
+// distance. Internal nodes are ordered before records at the same distance
+// so that records at the same distance are ordered by index.
+type rtreeQueue []rtreeQueueItem
+
+// RTree returns a new RTree over the bounds of s's records. Null records are
+// not indexed.
+func (s *SHP) RTree() *RTree {
+	nodes := make([]*rtreeNode, 0, len(s.Records))
+	for i, record := range s.Records {
+		bounds := shpRecordBounds(record)
+		if bounds == nil {
+			continue
+		}
+		nodes = append(nodes, &rtreeNode{
+			minX:  bounds.Min(0),
+			minY:  bounds.Min(1),
+			maxX:  bounds.Max(0),
+			maxY:  bounds.Max(1),
+			index: i,
+		})
+	}
+	if len(nodes) == 0 {
+		return &RTree{}
+	}
+	for len(nodes) > 1 {
+		nodes = packRTreeNodes(nodes)
+	}
+	return &RTree{
+		root: nodes[0],
+	}
+}
+
+// RTree returns a new RTree over the bounds of s's records, or an empty RTree
+// if s has no .shp file. See SHP.RTree.
+func (s *Shapefile) RTree() *RTree {
+	if s.SHP == nil {
+		return &RTree{}
+	}
+	return s.SHP.RTree()
+}
+
+// Search returns an iterator over the indexes of the records whose bounds
+// intersect bounds in X and Y, in increasing order.
+func (t *RTree) Search(bounds *geom.Bounds) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		if t.root == nil {
+			return
+		}
+		indexes := t.root.appendIndexes(nil, bounds.Min(0), bounds.Min(1), bounds.Max(0), bounds.Max(1))
+		slices.Sort(indexes)
+		for _, index := range indexes {
+			if !yield(index) {
+				return
+			}
+		}
+	}
+}
+
+// Nearest returns the indexes of the k records whose bounds are nearest to
+// point in X and Y, in order of increasing distance. Records whose bounds
+// contain point have distance zero. Records at the same distance are ordered
+// by index.
+func (t *RTree) Nearest(point geom.Coord, k int) []int {
+	if t.root == nil || k <= 0 {
+		return nil
+	}
+	x, y := point.X(), point.Y()
+	indexes := make([]int, 0, k)
+	queue := &rtreeQueue{}
+	queue.push(rtreeQueueItem{
+		node:     t.root,
+		distance: t.root.distance(x, y),
+	})
+	for len(*queue) > 0 && len(indexes) < k {
+		item := queue.pop()
+		if item.node.children == nil {
+			indexes = append(indexes, item.node.index)
+			continue
+		}
+		for _, child := range item.node.children {
+			queue.push(rtreeQueueItem{
+				node:     child,
+				distance: child.distance(x, y),
+			})
+		}
+	}
+	return indexes
+}
+
+// packRTreeNodes packs nodes into parent nodes with the Sort-Tile-Recursive
+// algorithm. nodes are sorted by the X coordinate of their centers and split
+// into vertical slices, then each slice is sorted by the Y coordinate of their
+// centers and split into parent nodes.
+func packRTreeNodes(nodes []*rtreeNode) []*rtreeNode {
+	numParents := (len(nodes) + rtreeNodeCapacity - 1) / rtreeNodeCapacity
+	numSlices := int(math.Ceil(math.Sqrt(float64(numParents))))
+	sliceSize := numSlices * rtreeNodeCapacity
+
+	slices.SortStableFunc(nodes, func(a, b *rtreeNode) int {
+		return cmp.Compare(a.minX+a.maxX, b.minX+b.maxX)
+	})
+	parents := make([]*rtreeNode, 0, numParents)
+	for sliceNodes := range slices.Chunk(nodes, sliceSize) {
+		slices.SortStableFunc(sliceNodes, func(a, b *rtreeNode) int {
+			return cmp.Compare(a.minY+a.maxY, b.minY+b.maxY)
+		})
+		for children := range slices.Chunk(sliceNodes, rtreeNodeCapacity) {
+			parent := &rtreeNode{
+				minX:     math.Inf(1),
+				minY:     math.Inf(1),
+				maxX:     math.Inf(-1),
+				maxY:     math.Inf(-1),
+				children: children,
+			}
+			for _, child := range children {
+				parent.minX = min(parent.minX, child.minX)
+				parent.minY = min(parent.minY, child.minY)
+				parent.maxX = max(parent.maxX, child.maxX)
+				parent.maxY = max(parent.maxY, child.maxY)
+			}
+			parents = append(parents, parent)
+		}
+	}
+	return parents
+}
+
+// appendIndexes appends the indexes of the records in n and its descendants
+// whose bounds intersect the bounds from (minX, minY) to (maxX, maxY) to
+// indexes.
+func (n *rtreeNode) appendIndexes(indexes []int, minX, minY, maxX, maxY float64) []int {
+	if n.maxX < minX || n.minX > maxX || n.maxY < minY || n.minY > maxY {
+		return indexes
+	}
+	if n.children == nil {
+		return append(indexes, n.index)
+	}
+	for _, child := range n.children {
+		indexes = child.appendIndexes(indexes, minX, minY, maxX, maxY)
+	}
+	return indexes
+}
+
+// distance returns the squared distance from (x, y) to n's bounds.
+func (n *rtreeNode) distance(x, y float64) float64 {
+	dx := max(n.minX-x, 0, x-n.maxX)
+	dy := max(n.minY-y, 0, y-n.maxY)
+	return dx*dx + dy*dy
+}
+
+// push adds item to q.
+func (q *rtreeQueue) push(item rtreeQueueItem) {
+	*q = append(*q, item)
+	for i := len(*q) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if !q.less(i, parent) {
+			break
+		}
+		(*q)[i], (*q)[parent] = (*q)[parent], (*q)[i]
+		i = parent
+	}
+}
+
+// pop removes and returns the first item in q.
+func (q *rtreeQueue) pop() rtreeQueueItem {
+	item := (*q)[0]
+	last := len(*q) - 1
+	(*q)[0] = (*q)[last]
+	*q = (*q)[:last]
+	for i := 0; ; {
+		first := i
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < last && q.less(child, first) {
+				first = child
+			}
+		}
+		if first == i {
+			break
+		}
+		(*q)[i], (*q)[first] = (*q)[first], (*q)[i]
+		i = first
+	}
+	return item
+}
+
+// less returns if the ith item in q is before the jth item.
+func (q *rtreeQueue) less(i, j int) bool {
+	a, b := (*q)[i], (*q)[j]
+	switch {
+	case a.distance != b.distance:
+		return a.distance < b.distance
+	case (a.node.children == nil) != (b.node.children == nil):
+		return a.node.children != nil
+	default:
+		return a.node.index < b.node.index
+	}
+}
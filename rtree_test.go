@@ -0,0 +1,77 @@
+package shapefile
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-geom"
+)
+
+func TestRTree(t *testing.T) {
+	for _, filename := range []string{
+		"10m_populated_places_simple.zip",
+		"110m-admin-0-countries.zip",
+	} {
+		t.Run(filename, func(t *testing.T) {
+			shapefile, err := ReadZipFile(filepath.Join("testdata", filename), &ReadShapefileOptions{
+				DBF: &ReadDBFOptions{SkipBrokenFields: true},
+			})
+			assert.NoError(t, err)
+			rtree := shapefile.RTree()
+			records := shapefile.SHP.Records
+
+			r := rand.New(rand.NewPCG(1, 2))
+			for range 100 {
+				x, y := 360*r.Float64()-180, 180*r.Float64()-90
+				bounds := geom.NewBounds(geom.XY).Set(x, y, x+20*r.Float64(), y+20*r.Float64())
+
+				var expected []int
+				for i, record := range records {
+					if bounds.Overlaps(geom.XY, shpRecordBounds(record)) {
+						expected = append(expected, i)
+					}
+				}
+				actual := slices.Collect(rtree.Search(bounds))
+				assert.Equal(t, expected, actual)
+
+				indexes := make([]int, len(records))
+				distances := make([]float64, len(records))
+				for i, record := range records {
+					indexes[i] = i
+					bounds := shpRecordBounds(record)
+					dx := max(bounds.Min(0)-x, 0, x-bounds.Max(0))
+					dy := max(bounds.Min(1)-y, 0, y-bounds.Max(1))
+					distances[i] = dx*dx + dy*dy
+				}
+				slices.SortStableFunc(indexes, func(a, b int) int {
+					return cmp.Compare(distances[a], distances[b])
+				})
+				assert.Equal(t, indexes[:5], rtree.Nearest(geom.Coord{x, y}, 5))
+			}
+		})
+	}
+}
+
+func TestRTreeEmpty(t *testing.T) {
+	rtree := (&Shapefile{}).RTree()
+	assert.Equal(t, []int(nil), slices.Collect(rtree.Search(geom.NewBounds(geom.XY).Set(0, 0, 1, 1))))
+	assert.Equal(t, []int(nil), rtree.Nearest(geom.Coord{0, 0}, 1))
+}
+
+func TestRTreeSearchStop(t *testing.T) {
+	shapefile, err := ReadZipFile(filepath.Join("testdata", "110m-admin-0-countries.zip"), nil)
+	assert.NoError(t, err)
+	count := 0
+	for range shapefile.RTree().Search(geom.NewBounds(geom.XY).Set(-180, -90, 180, 90)) {
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, count)
+	assert.Equal(t, len(shapefile.SHP.Records), len(shapefile.RTree().Nearest(geom.Coord{0, 0}, 1000)))
+}
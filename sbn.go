@@ -0,0 +1,307 @@
+package shapefile
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"slices"
+
+	"github.com/twpayne/go-geom"
+)
+
+const (
+	sbnHeaderSize         = 100
+	sbnBinHeaderSize      = 8
+	sbnFeatureSize        = 8
+	sbnMaxFeaturesPerBin  = 100
+	sbnNodeDescriptorSize = 8
+	sbnMaxCoord           = 255
+)
+
+// An SBN is an ESRI .sbn spatial index. It is a binary tree whose nodes are
+// stored in breadth-first order, so the children of Nodes[i] are
+// Nodes[2*i+1] and Nodes[2*i+2]. The bounds of each feature are stored
+// relative to Bounds, scaled to integers between 0 and 255.
+//
+// The corresponding .sbx file only contains the offsets of the bins in the
+// .sbn file and is not needed to read it.
+//
+// See https://github.com/OSGeo/shapelib/blob/master/sbnsearch.c.
+type SBN struct {
+	NumShapes int
+	Bounds    *geom.Bounds
+	Nodes     []*SBNNode
+}
+
+// An SBNNode is a node in an SBN binary tree.
+type SBNNode struct {
+	Features      []SBNFeature
+	subtreeBounds *sbnBounds
+}
+
+// An SBNFeature is a feature in an SBN node.
+type SBNFeature struct {
+	ShapeID int // Zero-based record index.
+	MinX    uint8
+	MinY    uint8
+	MaxX    uint8
+	MaxY    uint8
+}
+
+// An sbnBounds is a bounding box in SBN coordinates.
+type sbnBounds struct {
+	minX, minY, maxX, maxY int
+}
+
+// ReadSBN reads an SBN from an io.Reader.
+func ReadSBN(r io.Reader, size int64) (*SBN, error) {
+	if size < sbnHeaderSize+sbnBinHeaderSize {
+		return nil, errors.New("file too short")
+	}
+	data, err := readAll(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSBN(data)
+}
+
+// ReadSBNZipFile reads an SBN from a *zip.File.
+func ReadSBNZipFile(zipFile *zip.File) (*SBN, error) {
+	readCloser, err := zipFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer readCloser.Close()
+	sbn, err := ReadSBN(readCloser, int64(zipFile.UncompressedSize64))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", zipFile.Name, err)
+	}
+	return sbn, nil
+}
+
+// ParseSBN parses an SBN from data.
+func ParseSBN(data []byte) (*SBN, error) {
+	if len(data) < sbnHeaderSize+sbnBinHeaderSize {
+		return nil, errors.New("file too short")
+	}
+	if headerFileCode := binary.BigEndian.Uint32(data[:4]); headerFileCode != fileCode {
+		return nil, errors.New("invalid file code")
+	}
+	numShapes := int(int32(binary.BigEndian.Uint32(data[28:32])))
+	if numShapes < 0 {
+		return nil, fmt.Errorf("%d: invalid number of shapes", numShapes)
+	}
+	minX := math.Float64frombits(binary.LittleEndian.Uint64(data[32:40]))
+	minY := math.Float64frombits(binary.LittleEndian.Uint64(data[40:48]))
+	maxX := math.Float64frombits(binary.LittleEndian.Uint64(data[48:56]))
+	maxY := math.Float64frombits(binary.LittleEndian.Uint64(data[56:64]))
+	if !(minX <= maxX) || !(minY <= maxY) {
+		return nil, errors.New("invalid bounds")
+	}
+
+	// The node descriptors are stored in the first bin, which has ID 1. Each
+	// node descriptor contains the ID of the node's first bin and the node's
+	// number of features.
+	if binID := binary.BigEndian.Uint32(data[100:104]); binID != 1 {
+		return nil, fmt.Errorf("%d: invalid bin ID", binID)
+	}
+	nodeDescriptorsSize := 2 * int(binary.BigEndian.Uint32(data[104:108]))
+	offset := sbnHeaderSize + sbnBinHeaderSize
+	if nodeDescriptorsSize%sbnNodeDescriptorSize != 0 || nodeDescriptorsSize > len(data)-offset {
+		return nil, errors.New("invalid node descriptors size")
+	}
+	numNodes := nodeDescriptorsSize / sbnNodeDescriptorSize
+	nodeDescriptorsData := data[offset : offset+nodeDescriptorsSize]
+	offset += nodeDescriptorsSize
+
+	// The features of each non-empty node are stored in one or more
+	// consecutive bins, in node order.
+	var bins [][]SBNFeature
+	for offset < len(data) {
+		if len(data)-offset < sbnBinHeaderSize {
+			return nil, io.ErrUnexpectedEOF
+		}
+		binID := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		if binID != len(bins)+2 {
+			return nil, fmt.Errorf("%d: invalid bin ID", binID)
+		}
+		binSize := 2 * int(binary.BigEndian.Uint32(data[offset+4:offset+8]))
+		if binSize == 0 || binSize%sbnFeatureSize != 0 || binSize > sbnMaxFeaturesPerBin*sbnFeatureSize {
+			return nil, fmt.Errorf("bin %d: invalid size", binID)
+		}
+		offset += sbnBinHeaderSize
+		if len(data)-offset < binSize {
+			return nil, io.ErrUnexpectedEOF
+		}
+		features := make([]SBNFeature, 0, binSize/sbnFeatureSize)
+		for ; binSize > 0; binSize -= sbnFeatureSize {
+			featureData := data[offset : offset+sbnFeatureSize]
+			shapeID := int(int32(binary.BigEndian.Uint32(featureData[4:8])))
+			if shapeID < 1 || shapeID > numShapes {
+				return nil, fmt.Errorf("bin %d: %d: invalid shape ID", binID, shapeID)
+			}
+			features = append(features, SBNFeature{
+				ShapeID: shapeID - 1,
+				MinX:    featureData[0],
+				MinY:    featureData[1],
+				MaxX:    featureData[2],
+				MaxY:    featureData[3],
+			})
+			offset += sbnFeatureSize
+		}
+		bins = append(bins, features)
+	}
+
+	nodes := make([]*SBNNode, numNodes)
+	nextBinIndex := 0
+	for i := range nodes {
+		nodeDescriptorData := nodeDescriptorsData[sbnNodeDescriptorSize*i : sbnNodeDescriptorSize*(i+1)]
+		firstBinID := int(int32(binary.BigEndian.Uint32(nodeDescriptorData[:4])))
+		numFeatures := int(int32(binary.BigEndian.Uint32(nodeDescriptorData[4:8])))
+		node := &SBNNode{}
+		nodes[i] = node
+		if firstBinID <= 0 {
+			continue
+		}
+		if numFeatures <= 0 || numFeatures > numShapes {
+			return nil, fmt.Errorf("node %d: %d: invalid number of features", i, numFeatures)
+		}
+		if binIndex := firstBinID - 2; binIndex != nextBinIndex {
+			return nil, fmt.Errorf("node %d: %d: invalid bin ID", i, firstBinID)
+		}
+		for len(node.Features) < numFeatures && nextBinIndex < len(bins) {
+			node.Features = append(node.Features, bins[nextBinIndex]...)
+			nextBinIndex++
+		}
+		if len(node.Features) != numFeatures {
+			return nil, fmt.Errorf("node %d: %d: invalid number of features", i, numFeatures)
+		}
+	}
+	if nextBinIndex != len(bins) {
+		return nil, errors.New("trailing data")
+	}
+
+	// Compute the bounds of each node's features and its descendants'
+	// features, from the leaves up, so searches can skip whole subtrees.
+	for i := len(nodes) - 1; i >= 0; i-- {
+		node := nodes[i]
+		for _, feature := range node.Features {
+			node.subtreeBounds = node.subtreeBounds.extend(&sbnBounds{
+				minX: int(feature.MinX),
+				minY: int(feature.MinY),
+				maxX: int(feature.MaxX),
+				maxY: int(feature.MaxY),
+			})
+		}
+		for _, j := range []int{2*i + 1, 2*i + 2} {
+			if j < len(nodes) {
+				node.subtreeBounds = node.subtreeBounds.extend(nodes[j].subtreeBounds)
+			}
+		}
+	}
+
+	return &SBN{
+		NumShapes: numShapes,
+		Bounds:    geom.NewBounds(geom.XY).Set(minX, minY, maxX, maxY),
+		Nodes:     nodes,
+	}, nil
+}
+
+// Search returns an iterator over the IDs of the shapes in s whose bounds, in
+// SBN coordinates, intersect bounds in X and Y, in increasing order. These are
+// the candidate records whose geometries might intersect bounds.
+func (s *SBN) Search(bounds *geom.Bounds) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		if len(s.Nodes) == 0 ||
+			bounds.Max(0) < s.Bounds.Min(0) || bounds.Min(0) > s.Bounds.Max(0) ||
+			bounds.Max(1) < s.Bounds.Min(1) || bounds.Min(1) > s.Bounds.Max(1) {
+			return
+		}
+		minX, maxX := sbnCoordRange(bounds.Min(0), bounds.Max(0), s.Bounds.Min(0), s.Bounds.Max(0))
+		minY, maxY := sbnCoordRange(bounds.Min(1), bounds.Max(1), s.Bounds.Min(1), s.Bounds.Max(1))
+		searchBounds := &sbnBounds{
+			minX: minX,
+			minY: minY,
+			maxX: maxX,
+			maxY: maxY,
+		}
+		shapeIDs := s.appendShapeIDs(nil, 0, searchBounds)
+		slices.Sort(shapeIDs)
+		for _, shapeID := range slices.Compact(shapeIDs) {
+			if !yield(shapeID) {
+				return
+			}
+		}
+	}
+}
+
+// appendShapeIDs appends the shape IDs of the features of the ith node of s
+// and its descendants whose bounds intersect bounds.
+func (s *SBN) appendShapeIDs(shapeIDs []int, i int, bounds *sbnBounds) []int {
+	if i >= len(s.Nodes) {
+		return shapeIDs
+	}
+	node := s.Nodes[i]
+	if !node.subtreeBounds.intersects(bounds) {
+		return shapeIDs
+	}
+	for _, feature := range node.Features {
+		if int(feature.MaxX) >= bounds.minX && int(feature.MinX) <= bounds.maxX &&
+			int(feature.MaxY) >= bounds.minY && int(feature.MinY) <= bounds.maxY {
+			shapeIDs = append(shapeIDs, feature.ShapeID)
+		}
+	}
+	shapeIDs = s.appendShapeIDs(shapeIDs, 2*i+1, bounds)
+	return s.appendShapeIDs(shapeIDs, 2*i+2, bounds)
+}
+
+// sbnCoordRange returns the range of SBN coordinates that covers the range
+// from minValue to maxValue within the range from minExtent to maxExtent. The
+// range is rounded outwards so that no features are missed.
+func sbnCoordRange(minValue, maxValue, minExtent, maxExtent float64) (int, int) {
+	extent := maxExtent - minExtent
+	if extent == 0 {
+		return 0, sbnMaxCoord
+	}
+	minCoord := 0
+	if minValue > minExtent {
+		minCoord = max(int(math.Floor((minValue-minExtent)/extent*sbnMaxCoord-0.005)), 0)
+	}
+	maxCoord := sbnMaxCoord
+	if maxValue < maxExtent {
+		maxCoord = min(int(math.Ceil((maxValue-minExtent)/extent*sbnMaxCoord+0.005)), sbnMaxCoord)
+	}
+	return minCoord, maxCoord
+}
+
+// extend returns the union of b and other, either of which may be nil.
+func (b *sbnBounds) extend(other *sbnBounds) *sbnBounds {
+	switch {
+	case other == nil:
+		return b
+	case b == nil:
+		return &sbnBounds{
+			minX: other.minX,
+			minY: other.minY,
+			maxX: other.maxX,
+			maxY: other.maxY,
+		}
+	default:
+		b.minX = min(b.minX, other.minX)
+		b.minY = min(b.minY, other.minY)
+		b.maxX = max(b.maxX, other.maxX)
+		b.maxY = max(b.maxY, other.maxY)
+		return b
+	}
+}
+
+// intersects returns if b intersects other. A nil b intersects nothing.
+func (b *sbnBounds) intersects(other *sbnBounds) bool {
+	return b != nil &&
+		b.maxX >= other.minX && b.minX <= other.maxX &&
+		b.maxY >= other.minY && b.minY <= other.maxY
+}
@@ -0,0 +1,168 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-geom"
+)
+
+func FuzzReadSBN(f *testing.F) {
+	assert.NoError(f, addFuzzDataFromFS(f, os.DirFS("."), "testdata", ".sbn"))
+
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		sbn, err := ReadSBN(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		for range sbn.Search(geom.NewBounds(geom.XY).Set(0, 0, 1, 1)) {
+		}
+	})
+}
+
+func TestReadSBN(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "poly.sbn"))
+	assert.NoError(t, err)
+
+	sbn, err := ReadSBN(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	assert.Equal(t, 10, sbn.NumShapes)
+	assert.Equal(t, geom.NewBounds(geom.XY).Set(478315.53125, 4762880.5, 481645.3125, 4765610.5), sbn.Bounds)
+	assert.Equal(t, 3, len(sbn.Nodes))
+	assert.Equal(t, 7, len(sbn.Nodes[0].Features))
+	assert.Equal(t, SBNFeature{ShapeID: 0, MinX: 0x65, MinY: 0xb8, MaxX: 0x9f, MaxY: 0xff}, sbn.Nodes[0].Features[0])
+	assert.Equal(t, 3, len(sbn.Nodes[1].Features))
+	assert.Equal(t, 0, len(sbn.Nodes[2].Features))
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, slices.Collect(sbn.Search(sbn.Bounds)))
+	assert.Equal(t, []int{3}, slices.Collect(sbn.Search(geom.NewBounds(geom.XY).Set(478400, 4764200, 478500, 4764300))))
+	assert.Equal(t, []int(nil), slices.Collect(sbn.Search(geom.NewBounds(geom.XY).Set(0, 0, 1, 1))))
+}
+
+func TestReadSBNInvalidSize(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "poly.sbn"))
+	assert.NoError(t, err)
+	_, err = ReadSBN(bytes.NewReader(data), 1<<62)
+	assert.IsError(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestParseSBNErrors(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "poly.sbn"))
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name        string
+		modify      func([]byte) []byte
+		expectedErr string
+	}{
+		{
+			name: "too_short",
+			modify: func(data []byte) []byte {
+				return data[:sbnHeaderSize]
+			},
+			expectedErr: "file too short",
+		},
+		{
+			name: "invalid_file_code",
+			modify: func(data []byte) []byte {
+				data[0] = 1
+				return data
+			},
+			expectedErr: "invalid file code",
+		},
+		{
+			name: "invalid_bounds",
+			modify: func(data []byte) []byte {
+				copy(data[32:40], data[48:56])
+				data[32]++
+				return data
+			},
+			expectedErr: "invalid bounds",
+		},
+		{
+			name: "invalid_shape_id",
+			modify: func(data []byte) []byte {
+				binary.BigEndian.PutUint32(data[28:32], 5)
+				return data
+			},
+			expectedErr: "bin 2: 6: invalid shape ID",
+		},
+		{
+			name: "invalid_bin_id",
+			modify: func(data []byte) []byte {
+				binary.BigEndian.PutUint32(data[132:136], 3)
+				return data
+			},
+			expectedErr: "3: invalid bin ID",
+		},
+		{
+			name: "invalid_number_of_features",
+			modify: func(data []byte) []byte {
+				binary.BigEndian.PutUint32(data[112:116], 8)
+				return data
+			},
+			expectedErr: "node 0: 8: invalid number of features",
+		},
+		{
+			name: "truncated",
+			modify: func(data []byte) []byte {
+				return data[:len(data)-4]
+			},
+			expectedErr: io.ErrUnexpectedEOF.Error(),
+		},
+		{
+			name: "trailing_data",
+			modify: func(data []byte) []byte {
+				return append(data, 0, 0, 0, 4, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 1)
+			},
+			expectedErr: "trailing data",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseSBN(tc.modify(bytes.Clone(data)))
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestReaderSearchSBN(t *testing.T) {
+	basename := filepath.Join("testdata", "poly")
+	shapefile, err := Read(basename, nil)
+	assert.NoError(t, err)
+
+	readerAts := make(map[string]io.ReaderAt)
+	sizes := make(map[string]int64)
+	for _, ext := range []string{".sbn", ".shp", ".shx"} {
+		data, err := os.ReadFile(basename + ext)
+		assert.NoError(t, err)
+		readerAts[ext] = bytes.NewReader(data)
+		sizes[ext] = int64(len(data))
+	}
+	reader, err := NewReader(readerAts, sizes, nil)
+	assert.NoError(t, err)
+	assert.Zero(t, reader.QIX())
+	assert.NotZero(t, reader.SBN())
+
+	for _, bounds := range []*geom.Bounds{
+		geom.NewBounds(geom.XY).Set(480400, 4764000, 481000, 4765000),
+		geom.NewBounds(geom.XY).Set(479000, 4765200, 479100, 4765300),
+		geom.NewBounds(geom.XY).Set(478400, 4764200, 478500, 4764300),
+		geom.NewBounds(geom.XY).Set(0, 0, 1, 1),
+		reader.SHPHeader().Bounds,
+	} {
+		var expected []int
+		for i, record := range shapefile.SHP.Records {
+			if bounds.Overlaps(geom.XY, record.Bounds) {
+				expected = append(expected, i)
+			}
+		}
+
+		assert.Equal(t, expected, collectSearch(t, reader.Search(bounds)))
+	}
+}
@@ -1,7 +1,5 @@
 package shapefile
 
-// FIXME provide lazy, random access to individual records, using SHX
-
 import (
 	"archive/zip"
 	"bufio"
@@ -13,9 +11,7 @@ import (
 	"strings"
 	"sync"
 
-	"golang.org/x/net/html/charset"
 	"golang.org/x/text/encoding"
-	"golang.org/x/text/encoding/charmap"
 )
 
 // bufioReadCloser ...
@@ -31,8 +27,11 @@ type Scanner struct {
 	scanSHX          *ScannerSHX
 	filePRJ          *PRJ
 	fileCPG          *CPG
+	fileDBT          io.Closer
+	fileFPT          io.Closer
 	scanRecords      int64
 	estimatedRecords int64
+	recordDBF        DBFRecord
 	err              error
 }
 
@@ -55,11 +54,11 @@ func ReadScanner(scanner *Scanner) (*Shapefile, error) {
 	}
 
 	if scanner.DBFHeader() != nil {
-		dbf = &DBF{DBFHeader: *scanner.DBFHeader(), FieldDescriptors: scanner.DBFFieldDescriptors()}
+		dbf = &DBF{DBFHeader: *scanner.DBFHeader(), FieldDescriptors: scanner.DBFFieldDescriptors(), Charset: scanner.DBFCharset()}
 	}
 
 	if scanner.Projection() != "" {
-		prj = &PRJ{Projection: scanner.Projection()}
+		prj = scanner.filePRJ
 	}
 
 	if scanner.Charset() != "" {
@@ -68,11 +67,15 @@ func ReadScanner(scanner *Scanner) (*Shapefile, error) {
 
 	for scanner.Next() {
 		recSHP, recSHX, recDBF := scanner.Scan()
+		if scanner.Error() != nil {
+			break
+		}
 		if shp != nil && recSHP != nil {
 			shp.Records = append(shp.Records, recSHP)
 		}
-		if dbf != nil && recDBF != nil {
+		if dbf != nil {
 			dbf.Records = append(dbf.Records, recDBF)
+			dbf.deleted = append(dbf.deleted, scanner.Deleted())
 		}
 		if shx != nil && recSHX != nil {
 			shx.Records = append(shx.Records, *recSHX)
@@ -112,6 +115,28 @@ func NewScannerFromBasename(basename string, options *ReadShapefileOptions) (*Sc
 		sizes[".dbf"] = dbfSize
 	}
 
+	dbtFile, dbtSize, err := openWithSize(basename + ".dbt")
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// Do nothing.
+	case err != nil:
+		return nil, fmt.Errorf("%s.dbt: %w", basename, err)
+	default:
+		readers[".dbt"] = dbtFile
+		sizes[".dbt"] = dbtSize
+	}
+
+	fptFile, fptSize, err := openWithSize(basename + ".fpt")
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// Do nothing.
+	case err != nil:
+		return nil, fmt.Errorf("%s.fpt: %w", basename, err)
+	default:
+		readers[".fpt"] = fptFile
+		sizes[".fpt"] = fptSize
+	}
+
 	prjFile, prjSize, err := openWithSize(basename + ".prj")
 	switch {
 	case errors.Is(err, os.ErrNotExist):
@@ -156,6 +181,31 @@ func NewScannerFromBasename(basename string, options *ReadShapefileOptions) (*Sc
 		sizes[".shp"] = shpSize
 	}
 
+	// Spatial indexes are only read if they are used to select records.
+	if options.SHP != nil && options.SHP.Bounds != nil {
+		qixFile, qixSize, err := openWithSize(basename + ".qix")
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// Do nothing.
+		case err != nil:
+			return nil, fmt.Errorf("%s.qix: %w", basename, err)
+		default:
+			readers[".qix"] = qixFile
+			sizes[".qix"] = qixSize
+		}
+
+		sbnFile, sbnSize, err := openWithSize(basename + ".sbn")
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// Do nothing.
+		case err != nil:
+			return nil, fmt.Errorf("%s.sbn: %w", basename, err)
+		default:
+			readers[".sbn"] = sbnFile
+			sizes[".sbn"] = sbnSize
+		}
+	}
+
 	scanner, err := NewScanner(readers, sizes, options)
 	if err != nil {
 		return nil, fmt.Errorf("NewScanner: %w", err)
@@ -191,8 +241,12 @@ func NewScannerFromZipFile(name string, options *ReadShapefileOptions) (*Scanner
 // NewScannerFromZipReader reads a *zip.Reader and create a scanner.
 func NewScannerFromZipReader(zipReader *zip.Reader, options *ReadShapefileOptions) (*Scanner, error) {
 	var dbfFiles []*zip.File
+	var dbtFiles []*zip.File
+	var fptFiles []*zip.File
 	var prjFiles []*zip.File
 	var cpgFiles []*zip.File
+	var qixFiles []*zip.File
+	var sbnFiles []*zip.File
 	var shxFiles []*zip.File
 	var shpFiles []*zip.File
 	for _, zipFile := range zipReader.File {
@@ -202,10 +256,18 @@ func NewScannerFromZipReader(zipReader *zip.Reader, options *ReadShapefileOption
 		switch strings.ToLower(path.Ext(zipFile.Name)) {
 		case ".dbf":
 			dbfFiles = append(dbfFiles, zipFile)
+		case ".dbt":
+			dbtFiles = append(dbtFiles, zipFile)
+		case ".fpt":
+			fptFiles = append(fptFiles, zipFile)
 		case ".prj":
 			prjFiles = append(prjFiles, zipFile)
 		case ".cpg":
 			cpgFiles = append(cpgFiles, zipFile)
+		case ".qix":
+			qixFiles = append(qixFiles, zipFile)
+		case ".sbn":
+			sbnFiles = append(sbnFiles, zipFile)
 		case ".shp":
 			shpFiles = append(shpFiles, zipFile)
 		case ".shx":
@@ -230,6 +292,34 @@ func NewScannerFromZipReader(zipReader *zip.Reader, options *ReadShapefileOption
 		return nil, errors.New("too many .dbf files")
 	}
 
+	switch len(dbtFiles) {
+	case 0:
+		// Do nothing.
+	case 1:
+		readCloser, err := dbtFiles[0].Open()
+		if err != nil {
+			return nil, err
+		}
+		readers[".dbt"] = readCloser
+		sizes[".dbt"] = int64(dbtFiles[0].UncompressedSize64)
+	default:
+		return nil, errors.New("too many .dbt files")
+	}
+
+	switch len(fptFiles) {
+	case 0:
+		// Do nothing.
+	case 1:
+		readCloser, err := fptFiles[0].Open()
+		if err != nil {
+			return nil, err
+		}
+		readers[".fpt"] = readCloser
+		sizes[".fpt"] = int64(fptFiles[0].UncompressedSize64)
+	default:
+		return nil, errors.New("too many .fpt files")
+	}
+
 	switch len(prjFiles) {
 	case 0:
 		// Do nothing.
@@ -286,6 +376,37 @@ func NewScannerFromZipReader(zipReader *zip.Reader, options *ReadShapefileOption
 		return nil, errors.New("too many .shx files")
 	}
 
+	// Spatial indexes are only read if they are used to select records.
+	if options != nil && options.SHP != nil && options.SHP.Bounds != nil {
+		switch len(qixFiles) {
+		case 0:
+			// Do nothing.
+		case 1:
+			readCloser, err := qixFiles[0].Open()
+			if err != nil {
+				return nil, err
+			}
+			readers[".qix"] = readCloser
+			sizes[".qix"] = int64(qixFiles[0].UncompressedSize64)
+		default:
+			return nil, errors.New("too many .qix files")
+		}
+
+		switch len(sbnFiles) {
+		case 0:
+			// Do nothing.
+		case 1:
+			readCloser, err := sbnFiles[0].Open()
+			if err != nil {
+				return nil, err
+			}
+			readers[".sbn"] = readCloser
+			sizes[".sbn"] = int64(sbnFiles[0].UncompressedSize64)
+		default:
+			return nil, errors.New("too many .sbn files")
+		}
+	}
+
 	scanner, err := NewScanner(readers, sizes, options)
 	if err != nil {
 		return nil, fmt.Errorf("NewScanner: %w", err)
@@ -310,16 +431,35 @@ func NewScanner(
 			return nil, fmt.Errorf("ReadCPG: %w", err)
 		}
 		cpg = scanner
-		switch {
-		case options == nil:
-			options = &ReadShapefileOptions{&ReadDBFOptions{Charset: scanner.Charset}, &ReadSHPOptions{}}
-		case options.DBF == nil:
-			options.DBF = &ReadDBFOptions{Charset: scanner.Charset}
-		default:
-			options.DBF.Charset = scanner.Charset
+	}
+
+	var dbt *DBT
+	var dbtCloser io.Closer
+	if reader, ok := readers[".dbt"]; ok {
+		var err error
+		dbt, err = ReadDBT(reader, sizes[".dbt"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadDBT: %w", err)
+		}
+		dbtCloser = reader
+	}
+
+	var fpt *FPT
+	var fptCloser io.Closer
+	if reader, ok := readers[".fpt"]; ok {
+		var err error
+		fpt, err = ReadFPT(reader, sizes[".fpt"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadFPT: %w", err)
 		}
+		fptCloser = reader
 	}
 
+	// Copy options so that the caller's options are not modified.
+	shapefileOptions := *options
+	shapefileOptions.DBF = options.DBF.withSiblingFiles(cpg, dbt, fpt)
+	options = &shapefileOptions
+
 	var prj *PRJ
 	if reader, ok := readers[".prj"]; ok {
 		scanner, err := ReadPRJ(reader, sizes[".prj"])
@@ -328,6 +468,32 @@ func NewScanner(
 		}
 		prj = scanner
 	}
+	prj, readSHPOptions, err := options.transformSHP(prj)
+	if err != nil {
+		return nil, err
+	}
+
+	// Spatial indexes are read completely, so their readers are closed
+	// immediately.
+	var qix *QIX
+	if reader, ok := readers[".qix"]; ok {
+		var err error
+		qix, err = ReadQIX(reader, sizes[".qix"])
+		if err := errors.Join(err, reader.Close()); err != nil {
+			return nil, fmt.Errorf("ReadQIX: %w", err)
+		}
+	}
+
+	var sbn *SBN
+	if reader, ok := readers[".sbn"]; ok {
+		var err error
+		sbn, err = ReadSBN(reader, sizes[".sbn"])
+		if err := errors.Join(err, reader.Close()); err != nil {
+			return nil, fmt.Errorf("ReadSBN: %w", err)
+		}
+	}
+
+	options.SHP = readSHPOptions.withSpatialIndex(qix, sbn)
 
 	var wg sync.WaitGroup
 	var scannerSHP *ScannerSHP
@@ -368,6 +534,10 @@ func NewScanner(
 				errSHX = fmt.Errorf("NewScannerSHX: %w", err)
 				return
 			}
+			if err := options.SHP.transformSHxHeader(scanner.header); err != nil {
+				errSHX = fmt.Errorf("NewScannerSHX: %w", err)
+				return
+			}
 			scannerSHX = scanner
 		}
 	}()
@@ -392,16 +562,25 @@ func NewScanner(
 		scanDBF:          scannerDBF,
 		filePRJ:          prj,
 		fileCPG:          cpg,
+		fileDBT:          dbtCloser,
+		fileFPT:          fptCloser,
 		estimatedRecords: estimatedRecords,
 	}, nil
 }
 
 // Scan Scanner records.
+//
+// If the .shp options have Bounds then records whose geometries do not
+// intersect Bounds are skipped without parsing their .dbf records.
 func (s *Scanner) Scan() (recordSHP *SHPRecord, recordSHX *SHXRecord, recordDBF DBFRecord) {
 	if s.err != nil {
 		return nil, nil, nil
 	}
 
+	if s.scanSHP != nil && s.scanSHP.options != nil && s.scanSHP.options.Bounds != nil {
+		return s.scanBounds()
+	}
+
 	var wg sync.WaitGroup
 	var errSHP, errSHX, errDBF error
 
@@ -447,9 +626,60 @@ func (s *Scanner) Scan() (recordSHP *SHPRecord, recordSHX *SHXRecord, recordDBF
 	}
 
 	s.scanRecords++
+	s.recordDBF = recordDBF
 	return recordSHP, recordSHX, recordDBF
 }
 
+// scanBounds scans records until it finds one whose geometry intersects the
+// bounds in the .shp options, skipping the .dbf records of the records in
+// between.
+func (s *Scanner) scanBounds() (*SHPRecord, *SHXRecord, DBFRecord) {
+	for {
+		recordSHP, err := s.scanSHP.Scan()
+		if err != nil {
+			s.err = fmt.Errorf("scanning SHP: %w", err)
+			return nil, nil, nil
+		}
+
+		var recordSHX *SHXRecord
+		if s.scanSHX != nil {
+			if recordSHX, err = s.scanSHX.Scan(); err != nil {
+				s.err = fmt.Errorf("scanning SHX: %w", err)
+				return nil, nil, nil
+			}
+		}
+
+		if recordSHP.Skipped {
+			if s.scanDBF != nil {
+				if err := s.scanDBF.skip(); err != nil {
+					s.err = fmt.Errorf("scanning DBF: %w", err)
+					return nil, nil, nil
+				}
+			}
+			s.scanRecords++
+			continue
+		}
+
+		var recordDBF DBFRecord
+		if s.scanDBF != nil {
+			if recordDBF, err = s.scanDBF.Scan(); err != nil {
+				s.err = fmt.Errorf("scanning DBF: %w", err)
+				return nil, nil, nil
+			}
+		}
+
+		s.scanRecords++
+		s.recordDBF = recordDBF
+		return recordSHP, recordSHX, recordDBF
+	}
+}
+
+// Deleted returns if the last record returned by Scan is deleted in the .dbf
+// file.
+func (s *Scanner) Deleted() bool {
+	return s.scanDBF != nil && s.scanDBF.deleted
+}
+
 func (s *Scanner) Next() bool {
 	return s.err == nil
 }
@@ -535,6 +765,12 @@ func (s *Scanner) Close() error {
 	if s.scanSHX != nil {
 		err = errors.Join(err, s.scanSHX.reader.Close())
 	}
+	if s.fileDBT != nil {
+		err = errors.Join(err, s.fileDBT.Close())
+	}
+	if s.fileFPT != nil {
+		err = errors.Join(err, s.fileFPT.Close())
+	}
 	return err
 }
 
@@ -574,6 +810,15 @@ func (s *Scanner) SHxHeader() *SHxHeader {
 	return nil
 }
 
+// DBFCharset returns the charset used to decode character fields in the .dbf
+// file, or the empty string if there is no .dbf file.
+func (s *Scanner) DBFCharset() string {
+	if s.scanDBF != nil {
+		return s.scanDBF.charset
+	}
+	return ""
+}
+
 func (s *Scanner) Charset() string {
 	if s.fileCPG != nil {
 		return s.fileCPG.Charset
@@ -605,6 +850,9 @@ func NewScannerSHP(reader io.ReadCloser, size int64, options *ReadSHPOptions) (*
 	if err != nil {
 		return nil, err
 	}
+	if err := options.transformSHxHeader(header); err != nil {
+		return nil, err
+	}
 	return &ScannerSHP{
 		reader:  bufioReadCloser{bufio.NewReader(reader), reader},
 		header:  header,
@@ -674,81 +922,58 @@ type ScannerDBF struct {
 	options          *ReadDBFOptions
 	header           *DBFHeader
 	fieldDescriptors []*DBFFieldDescriptor
+	layout           *dbfRecordLayout
 	decoder          *encoding.Decoder
+	charset          string
 	scanRecords      int
+	deleted          bool
 	err              error
 }
 
 func NewScannerDBF(reader io.ReadCloser, options *ReadDBFOptions) (*ScannerDBF, error) {
-	headerData := make([]byte, dbfHeaderLength)
-	if err := readFull(reader, headerData); err != nil {
+	header, err := readDBFHeader(reader, options)
+	if err != nil {
 		return nil, err
 	}
-	header, err := ParseDBFHeader(headerData, options)
+
+	fieldDescriptors, err := readDBFFieldDescriptors(reader, header)
 	if err != nil {
 		return nil, err
 	}
 
-	var fieldDescriptors []*DBFFieldDescriptor
-	for i := 0; ; i++ {
-		fieldDescriptorData := make([]byte, dbfFieldDescriptorSize)
-		if err := readFull(reader, fieldDescriptorData[:1]); err != nil {
-			return nil, err
-		}
-		if fieldDescriptorData[0] == '\x0d' {
-			break
-		}
-		if err := readFull(reader, fieldDescriptorData[1:]); err != nil {
-			return nil, err
-		}
-
-		name := string(TrimTrailingZeros(fieldDescriptorData[:11]))
-		fieldType := fieldDescriptorData[11]
-		if _, ok := knownFieldTypes[fieldType]; !ok {
-			return nil, fmt.Errorf("field %d: %d: invalid field type", i, fieldType)
-		}
-		length := int(fieldDescriptorData[16])
-		workAreaID := fieldDescriptorData[20]
-		setFields := fieldDescriptorData[23]
-
-		fieldDescriptor := &DBFFieldDescriptor{
-			Name:       name,
-			Type:       fieldType,
-			Length:     length,
-			WorkAreaID: workAreaID,
-			SetFields:  setFields,
-		}
-		fieldDescriptors = append(fieldDescriptors, fieldDescriptor)
+	layout, err := newDBFRecordLayout(header, fieldDescriptors, options)
+	if err != nil {
+		return nil, err
 	}
 
-	totalLength := 0
-	for _, fieldDescriptor := range fieldDescriptors {
-		totalLength += fieldDescriptor.Length
+	var bufioReader *bufio.Reader
+	enc, charset, err := dbfCharsetEncoding(header, fieldDescriptors, options, func(n int) ([]byte, error) {
+		bufioReader = bufio.NewReaderSize(reader, n)
+		return peekFull(bufioReader, n)
+	})
+	if err != nil {
+		return nil, err
 	}
-	if totalLength+1 != header.RecordSize {
-		return nil, errors.New("invalid total length of fields")
-	}
-
-	var decoder *encoding.Decoder
-	if options != nil && options.Charset != "" {
-		enc, _ := charset.Lookup(options.Charset)
-		if enc == nil {
-			return nil, fmt.Errorf("unknown charset '%s'", options.Charset)
-		}
-		decoder = enc.NewDecoder()
-	} else {
-		decoder = charmap.ISO8859_1.NewDecoder()
+	if bufioReader == nil {
+		bufioReader = bufio.NewReader(reader)
 	}
 
 	return &ScannerDBF{
-		reader:           bufioReadCloser{bufio.NewReader(reader), reader},
+		reader:           bufioReadCloser{bufioReader, reader},
 		options:          options,
 		header:           header,
-		fieldDescriptors: fieldDescriptors,
-		decoder:          decoder,
+		fieldDescriptors: layout.fieldDescriptors(),
+		layout:           layout,
+		decoder:          enc.NewDecoder(),
+		charset:          charset,
 	}, nil
 }
 
+// Charset returns the charset used to decode character fields.
+func (s *ScannerDBF) Charset() string {
+	return s.charset
+}
+
 func (s *ScannerDBF) Scan() (DBFRecord, error) {
 	if s.err != nil {
 		return nil, s.err
@@ -759,28 +984,32 @@ func (s *ScannerDBF) Scan() (DBFRecord, error) {
 		s.err = err
 		return nil, s.err
 	}
-	switch recordData[0] {
-	case ' ':
-		record := make([]any, 0, len(s.fieldDescriptors))
-		offset := 1
-		for _, fieldDescriptor := range s.fieldDescriptors {
-			fieldData := recordData[offset : offset+fieldDescriptor.Length]
-			offset += fieldDescriptor.Length
-			field, err := fieldDescriptor.ParseRecord(fieldData, s.decoder)
-			if err != nil && !s.options.SkipBrokenFields {
-				s.err = fmt.Errorf("field %s: %w", fieldDescriptor.Name, err)
-				return nil, s.err
-			}
-			record = append(record, field)
-		}
-		s.scanRecords++
-		return record, nil
-	case '*':
-		return nil, nil
-	default:
-		s.err = fmt.Errorf("%d: invalid record flag", recordData[0])
+	record, err := parseDBFRecord(recordData, s.layout, s.decoder, s.options)
+	if err != nil {
+		s.err = err
 		return nil, s.err
 	}
+	s.deleted = recordData[0] == '*'
+	s.scanRecords++
+	return record, nil
+}
+
+// skip skips the next record without parsing it.
+func (s *ScannerDBF) skip() error {
+	if s.err != nil {
+		return s.err
+	}
+	if _, err := s.reader.Discard(s.header.RecordSize); err != nil {
+		s.err = err
+		return s.err
+	}
+	s.scanRecords++
+	return nil
+}
+
+// Deleted returns if the last record returned by Scan is deleted.
+func (s *ScannerDBF) Deleted() bool {
+	return s.deleted
 }
 
 func (s *ScannerDBF) FieldDescriptors() []*DBFFieldDescriptor {
@@ -52,9 +52,13 @@ func TestReadScanner(t *testing.T) {
 			basename:   "multi_polygon",
 		},
 		{
-			skipReason:        "multipatch is not supported",
-			basename:          "multipatch",
-			expectedShapeType: ShapeTypeMultiPatch,
+			basename:           "multipatch",
+			hasDBF:             true,
+			expectedShapeType:  ShapeTypeMultiPatch,
+			expectedBounds:     geom.NewBounds(geom.XYZM).Set(0, 0, 0, math.Inf(1), 5, 5, 0, math.Inf(-1)),
+			expectedRecordsLen: 1,
+			expectedGeom0:      newGeomFromWKT(t, "GEOMETRYCOLLECTION ZM (POLYGON ZM ((0 0 0 -1E+39,0 0 3 -1E+39,5 0 0 -1E+39,0 0 0 -1E+39)),POLYGON ZM ((0 0 3 -1E+39,5 0 0 -1E+39,5 0 3 -1E+39,0 0 3 -1E+39)),POLYGON ZM ((5 0 0 -1E+39,5 0 3 -1E+39,5 5 0 -1E+39,5 0 0 -1E+39)),POLYGON ZM ((5 0 3 -1E+39,5 5 0 -1E+39,5 5 3 -1E+39,5 0 3 -1E+39)),POLYGON ZM ((5 5 0 -1E+39,5 5 3 -1E+39,0 5 0 -1E+39,5 5 0 -1E+39)),POLYGON ZM ((5 5 3 -1E+39,0 5 0 -1E+39,0 5 3 -1E+39,5 5 3 -1E+39)),POLYGON ZM ((0 5 0 -1E+39,0 5 3 -1E+39,0 0 0 -1E+39,0 5 0 -1E+39)),POLYGON ZM ((0 5 3 -1E+39,0 0 0 -1E+39,0 0 3 -1E+39,0 5 3 -1E+39)),POLYGON ZM ((2.5 2.5 5 -1E+39,0 0 3 -1E+39,5 0 3 -1E+39,2.5 2.5 5 -1E+39)),POLYGON ZM ((2.5 2.5 5 -1E+39,5 0 3 -1E+39,5 5 3 -1E+39,2.5 2.5 5 -1E+39)),POLYGON ZM ((2.5 2.5 5 -1E+39,5 5 3 -1E+39,0 5 3 -1E+39,2.5 2.5 5 -1E+39)),POLYGON ZM ((2.5 2.5 5 -1E+39,0 5 3 -1E+39,0 0 3 -1E+39,2.5 2.5 5 -1E+39)))"),
+			expectedDBFRecord0: []any{"house1"},
 		},
 		{
 			basename:           "multipoint",
@@ -160,6 +164,11 @@ func TestReadScanner(t *testing.T) {
 				if tc.hasDBF {
 					assert.Equal(t, shapefile.NumRecords(), tc.expectedRecordsLen)
 					assert.Equal(t, tc.expectedDBFRecord0, shapefile.DBF.Records[0])
+
+					expected, err := Read(path.Join("testdata", tc.basename), nil)
+					assert.NoError(t, err)
+					assert.NotZero(t, shapefile.DBF.Charset)
+					assert.Equal(t, expected.DBF.Charset, shapefile.DBF.Charset)
 				} else {
 					assert.Zero(t, shapefile.DBF)
 				}
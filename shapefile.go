@@ -1,12 +1,11 @@
-// Package shapefile reads ESRI Shapefiles.
+// Package shapefile reads and writes ESRI Shapefiles.
 //
 // See https://support.esri.com/en/white-paper/279.
 package shapefile
 
-// FIXME provide lazy, random access to individual records, using SHX
-
 import (
 	"archive/zip"
+	"bytes"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -45,27 +44,22 @@ const (
 	ShapeTypeMultiPatch  ShapeType = 31
 )
 
-var (
-	validShapeTypes = map[ShapeType]struct{}{
-		ShapeTypeNull:        {},
-		ShapeTypePoint:       {},
-		ShapeTypePolyLine:    {},
-		ShapeTypePolygon:     {},
-		ShapeTypeMultiPoint:  {},
-		ShapeTypePointM:      {},
-		ShapeTypePolyLineM:   {},
-		ShapeTypePolygonM:    {},
-		ShapeTypeMultiPointM: {},
-		ShapeTypePointZ:      {},
-		ShapeTypePolyLineZ:   {},
-		ShapeTypePolygonZ:    {},
-		ShapeTypeMultiPointZ: {},
-		ShapeTypeMultiPatch:  {},
-	}
-	unsupportedShapeTypes = map[ShapeType]struct{}{
-		ShapeTypeMultiPatch: {}, // FIXME
-	}
-)
+var validShapeTypes = map[ShapeType]struct{}{
+	ShapeTypeNull:        {},
+	ShapeTypePoint:       {},
+	ShapeTypePolyLine:    {},
+	ShapeTypePolygon:     {},
+	ShapeTypeMultiPoint:  {},
+	ShapeTypePointM:      {},
+	ShapeTypePolyLineM:   {},
+	ShapeTypePolygonM:    {},
+	ShapeTypeMultiPointM: {},
+	ShapeTypePointZ:      {},
+	ShapeTypePolyLineZ:   {},
+	ShapeTypePolygonZ:    {},
+	ShapeTypeMultiPointZ: {},
+	ShapeTypeMultiPatch:  {},
+}
 
 // A Shapefile is an ESRI Shapefile.
 type Shapefile struct {
@@ -79,7 +73,21 @@ type Shapefile struct {
 // ReadShapefileOptions are options to ReadFS.
 type ReadShapefileOptions struct {
 	DBF *ReadDBFOptions
+
+	// SHP are the options used to read the .shp file. If SHP.Bounds is not
+	// nil then the records that do not intersect SHP.Bounds are skipped: their
+	// SHPRecord.Skipped is true, their geometry is not decoded, and their
+	// .dbf values are not parsed and are nil. A .qix or .sbn spatial index, if
+	// present, is used to skip records without reading their .shp content.
 	SHP *ReadSHPOptions
+
+	// TargetPRJ, if not nil, is the coordinate reference system to transform
+	// geometries and bounds, including the bounds in .shp and .shx headers,
+	// to from the coordinate reference system in the .prj file, which is then
+	// required. The PRJ of the result is TargetPRJ. Bounds used to select
+	// records, such as SHP.Bounds, are in the .prj file's coordinate
+	// reference system. TargetPRJ and SHP.Transformer cannot both be set.
+	TargetPRJ *PRJ
 }
 
 // Read reads a Shapefile from basename.
@@ -106,32 +114,39 @@ func Read(basename string, options *ReadShapefileOptions) (*Shapefile, error) {
 		}
 	}
 
-	var dbf *DBF
-	dbfFile, dbfSize, err := openWithSize(basename + ".dbf")
-	if dbfFile != nil {
-		defer dbfFile.Close()
+	var dbt *DBT
+	dbtFile, dbtSize, err := openWithSize(basename + ".dbt")
+	if dbtFile != nil {
+		defer dbtFile.Close()
 	}
 	switch {
 	case errors.Is(err, fs.ErrNotExist):
 		// Do nothing.
 	case err != nil:
-		return nil, fmt.Errorf("%s.dbf: %w", basename, err)
+		return nil, fmt.Errorf("%s.dbt: %w", basename, err)
 	default:
 		var err error
-		var readDBFOptions *ReadDBFOptions
-		if options != nil {
-			readDBFOptions = options.DBF
-		}
-		if cpg != nil {
-			if readDBFOptions == nil {
-				readDBFOptions = &ReadDBFOptions{Charset: cpg.Charset}
-			} else {
-				readDBFOptions.Charset = cpg.Charset
-			}
+		dbt, err = ReadDBT(dbtFile, dbtSize)
+		if err != nil {
+			return nil, fmt.Errorf("%s.dbt: %w", basename, err)
 		}
-		dbf, err = ReadDBF(dbfFile, dbfSize, readDBFOptions)
+	}
+
+	var fpt *FPT
+	fptFile, fptSize, err := openWithSize(basename + ".fpt")
+	if fptFile != nil {
+		defer fptFile.Close()
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// Do nothing.
+	case err != nil:
+		return nil, fmt.Errorf("%s.fpt: %w", basename, err)
+	default:
+		var err error
+		fpt, err = ReadFPT(fptFile, fptSize)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%s.fpt: %w", basename, err)
 		}
 	}
 
@@ -152,6 +167,51 @@ func Read(basename string, options *ReadShapefileOptions) (*Shapefile, error) {
 			return nil, err
 		}
 	}
+	prj, readSHPOptions, err := options.transformSHP(prj)
+	if err != nil {
+		return nil, fmt.Errorf("%s.prj: %w", basename, err)
+	}
+
+	// Spatial indexes are only read if they are used to select records.
+	var qix *QIX
+	var sbn *SBN
+	if readSHPOptions != nil && readSHPOptions.Bounds != nil {
+		qixFile, qixSize, err := openWithSize(basename + ".qix")
+		if qixFile != nil {
+			defer qixFile.Close()
+		}
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// Do nothing.
+		case err != nil:
+			return nil, fmt.Errorf("%s.qix: %w", basename, err)
+		default:
+			var err error
+			qix, err = ReadQIX(qixFile, qixSize)
+			if err != nil {
+				return nil, fmt.Errorf("%s.qix: %w", basename, err)
+			}
+		}
+
+		sbnFile, sbnSize, err := openWithSize(basename + ".sbn")
+		if sbnFile != nil {
+			defer sbnFile.Close()
+		}
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// Do nothing.
+		case err != nil:
+			return nil, fmt.Errorf("%s.sbn: %w", basename, err)
+		default:
+			var err error
+			sbn, err = ReadSBN(sbnFile, sbnSize)
+			if err != nil {
+				return nil, fmt.Errorf("%s.sbn: %w", basename, err)
+			}
+		}
+
+		readSHPOptions = readSHPOptions.withSpatialIndex(qix, sbn)
+	}
 
 	var shx *SHX
 	shxFile, shxSize, err := openWithSize(basename + ".shx")
@@ -169,6 +229,9 @@ func Read(basename string, options *ReadShapefileOptions) (*Shapefile, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err := readSHPOptions.transformSHxHeader(&shx.SHxHeader); err != nil {
+			return nil, fmt.Errorf("%s.shx: %w", basename, err)
+		}
 	}
 
 	var shp *SHP
@@ -183,7 +246,30 @@ func Read(basename string, options *ReadShapefileOptions) (*Shapefile, error) {
 		return nil, fmt.Errorf("%s.shp: %w", basename, err)
 	default:
 		var err error
-		shp, err = ReadSHP(shpFile, shpSize, options.SHP)
+		shp, err = ReadSHP(shpFile, shpSize, readSHPOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var dbf *DBF
+	dbfFile, dbfSize, err := openWithSize(basename + ".dbf")
+	if dbfFile != nil {
+		defer dbfFile.Close()
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// Do nothing.
+	case err != nil:
+		return nil, fmt.Errorf("%s.dbf: %w", basename, err)
+	default:
+		var err error
+		var readDBFOptions *ReadDBFOptions
+		if options != nil {
+			readDBFOptions = options.DBF
+		}
+		readDBFOptions = readDBFOptions.withSiblingFiles(cpg, dbt, fpt).withSkippedSHPRecords(shp)
+		dbf, err = ReadDBF(dbfFile, dbfSize, readDBFOptions)
 		if err != nil {
 			return nil, err
 		}
@@ -191,7 +277,9 @@ func Read(basename string, options *ReadShapefileOptions) (*Shapefile, error) {
 
 	if dbf != nil && shp != nil && len(dbf.Records) != len(shp.Records) ||
 		dbf != nil && shx != nil && len(dbf.Records) != len(shx.Records) ||
-		shp != nil && shx != nil && len(shp.Records) != len(shx.Records) {
+		shp != nil && shx != nil && len(shp.Records) != len(shx.Records) ||
+		qix != nil && shp != nil && qix.NumShapes != len(shp.Records) ||
+		sbn != nil && shp != nil && sbn.NumShapes != len(shp.Records) {
 		return nil, errors.New("inconsistent number of records")
 	}
 
@@ -224,32 +312,39 @@ func ReadFS(fsys fs.FS, basename string, options *ReadShapefileOptions) (*Shapef
 		}
 	}
 
-	var dbf *DBF
-	switch dbfFile, err := fsys.Open(basename + ".dbf"); {
+	var dbt *DBT
+	switch dbtFile, err := fsys.Open(basename + ".dbt"); {
 	case errors.Is(err, fs.ErrNotExist):
 		// Do nothing.
 	case err != nil:
 		return nil, err
 	default:
-		defer dbfFile.Close()
-		fileInfo, err := dbfFile.Stat()
+		defer dbtFile.Close()
+		fileInfo, err := dbtFile.Stat()
 		if err != nil {
 			return nil, err
 		}
-		var readDBFOptions *ReadDBFOptions
-		if options != nil {
-			readDBFOptions = options.DBF
+		dbt, err = ReadDBT(dbtFile, fileInfo.Size())
+		if err != nil {
+			return nil, fmt.Errorf("%s.dbt: %w", basename, err)
 		}
-		if cpg != nil {
-			if readDBFOptions == nil {
-				readDBFOptions = &ReadDBFOptions{Charset: cpg.Charset}
-			} else {
-				readDBFOptions.Charset = cpg.Charset
-			}
+	}
+
+	var fpt *FPT
+	switch fptFile, err := fsys.Open(basename + ".fpt"); {
+	case errors.Is(err, fs.ErrNotExist):
+		// Do nothing.
+	case err != nil:
+		return nil, err
+	default:
+		defer fptFile.Close()
+		fileInfo, err := fptFile.Stat()
+		if err != nil {
+			return nil, err
 		}
-		dbf, err = ReadDBF(dbfFile, fileInfo.Size(), readDBFOptions)
+		fpt, err = ReadFPT(fptFile, fileInfo.Size())
 		if err != nil {
-			return nil, fmt.Errorf("%s.dbf: %w", basename, err)
+			return nil, fmt.Errorf("%s.fpt: %w", basename, err)
 		}
 	}
 
@@ -270,6 +365,51 @@ func ReadFS(fsys fs.FS, basename string, options *ReadShapefileOptions) (*Shapef
 			return nil, fmt.Errorf("%s.prj: %w", basename, err)
 		}
 	}
+	prj, readSHPOptions, err := options.transformSHP(prj)
+	if err != nil {
+		return nil, fmt.Errorf("%s.prj: %w", basename, err)
+	}
+
+	// Spatial indexes are only read if they are used to select records.
+	var qix *QIX
+	var sbn *SBN
+	if readSHPOptions != nil && readSHPOptions.Bounds != nil {
+		switch qixFile, err := fsys.Open(basename + ".qix"); {
+		case errors.Is(err, fs.ErrNotExist):
+			// Do nothing.
+		case err != nil:
+			return nil, err
+		default:
+			defer qixFile.Close()
+			fileInfo, err := qixFile.Stat()
+			if err != nil {
+				return nil, err
+			}
+			qix, err = ReadQIX(qixFile, fileInfo.Size())
+			if err != nil {
+				return nil, fmt.Errorf("%s.qix: %w", basename, err)
+			}
+		}
+
+		switch sbnFile, err := fsys.Open(basename + ".sbn"); {
+		case errors.Is(err, fs.ErrNotExist):
+			// Do nothing.
+		case err != nil:
+			return nil, err
+		default:
+			defer sbnFile.Close()
+			fileInfo, err := sbnFile.Stat()
+			if err != nil {
+				return nil, err
+			}
+			sbn, err = ReadSBN(sbnFile, fileInfo.Size())
+			if err != nil {
+				return nil, fmt.Errorf("%s.sbn: %w", basename, err)
+			}
+		}
+
+		readSHPOptions = readSHPOptions.withSpatialIndex(qix, sbn)
+	}
 
 	var shp *SHP
 	switch shpFile, err := fsys.Open(basename + ".shp"); {
@@ -283,14 +423,14 @@ func ReadFS(fsys fs.FS, basename string, options *ReadShapefileOptions) (*Shapef
 		if err != nil {
 			return nil, err
 		}
-		var readSHPOptions *ReadSHPOptions
-		if options != nil {
-			readSHPOptions = options.SHP
-		}
 		shp, err = ReadSHP(shpFile, fileInfo.Size(), readSHPOptions)
 		if err != nil {
 			return nil, fmt.Errorf("%s.shp: %w", basename, err)
 		}
+		if qix != nil && qix.NumShapes != len(shp.Records) ||
+			sbn != nil && sbn.NumShapes != len(shp.Records) {
+			return nil, errors.New("inconsistent number of records")
+		}
 	}
 
 	var shx *SHX
@@ -309,6 +449,32 @@ func ReadFS(fsys fs.FS, basename string, options *ReadShapefileOptions) (*Shapef
 		if err != nil {
 			return nil, fmt.Errorf("%s.shx: %w", basename, err)
 		}
+		if err := readSHPOptions.transformSHxHeader(&shx.SHxHeader); err != nil {
+			return nil, fmt.Errorf("%s.shx: %w", basename, err)
+		}
+	}
+
+	var dbf *DBF
+	switch dbfFile, err := fsys.Open(basename + ".dbf"); {
+	case errors.Is(err, fs.ErrNotExist):
+		// Do nothing.
+	case err != nil:
+		return nil, err
+	default:
+		defer dbfFile.Close()
+		fileInfo, err := dbfFile.Stat()
+		if err != nil {
+			return nil, err
+		}
+		var readDBFOptions *ReadDBFOptions
+		if options != nil {
+			readDBFOptions = options.DBF
+		}
+		readDBFOptions = readDBFOptions.withSiblingFiles(cpg, dbt, fpt).withSkippedSHPRecords(shp)
+		dbf, err = ReadDBF(dbfFile, fileInfo.Size(), readDBFOptions)
+		if err != nil {
+			return nil, fmt.Errorf("%s.dbf: %w", basename, err)
+		}
 	}
 
 	return &Shapefile{
@@ -347,8 +513,12 @@ func ReadZipFile(name string, options *ReadShapefileOptions) (*Shapefile, error)
 // ReadZipReader reads a Shapefile from a *zip.Reader.
 func ReadZipReader(zipReader *zip.Reader, options *ReadShapefileOptions) (*Shapefile, error) {
 	var dbfFiles []*zip.File
+	var dbtFiles []*zip.File
+	var fptFiles []*zip.File
 	var prjFiles []*zip.File
 	var cpgFiles []*zip.File
+	var qixFiles []*zip.File
+	var sbnFiles []*zip.File
 	var shxFiles []*zip.File
 	var shpFiles []*zip.File
 	for _, zipFile := range zipReader.File {
@@ -358,10 +528,18 @@ func ReadZipReader(zipReader *zip.Reader, options *ReadShapefileOptions) (*Shape
 		switch strings.ToLower(filepath.Ext(zipFile.Name)) {
 		case ".dbf":
 			dbfFiles = append(dbfFiles, zipFile)
+		case ".dbt":
+			dbtFiles = append(dbtFiles, zipFile)
+		case ".fpt":
+			fptFiles = append(fptFiles, zipFile)
 		case ".prj":
 			prjFiles = append(prjFiles, zipFile)
 		case ".cpg":
 			cpgFiles = append(cpgFiles, zipFile)
+		case ".qix":
+			qixFiles = append(qixFiles, zipFile)
+		case ".sbn":
+			sbnFiles = append(sbnFiles, zipFile)
 		case ".shp":
 			shpFiles = append(shpFiles, zipFile)
 		case ".shx":
@@ -382,29 +560,32 @@ func ReadZipReader(zipReader *zip.Reader, options *ReadShapefileOptions) (*Shape
 		return nil, errors.New("too many .cpg files")
 	}
 
-	var dbf *DBF
-	switch len(dbfFiles) {
+	var dbt *DBT
+	switch len(dbtFiles) {
 	case 0:
 		// Do nothing.
 	case 1:
-		var readDBFOptions *ReadDBFOptions
-		if options != nil {
-			readDBFOptions = options.DBF
-		}
-		if cpg != nil {
-			if readDBFOptions == nil {
-				readDBFOptions = &ReadDBFOptions{Charset: cpg.Charset}
-			} else {
-				readDBFOptions.Charset = cpg.Charset
-			}
+		var err error
+		dbt, err = ReadDBTZipFile(dbtFiles[0])
+		if err != nil {
+			return nil, err
 		}
+	default:
+		return nil, errors.New("too many .dbt files")
+	}
+
+	var fpt *FPT
+	switch len(fptFiles) {
+	case 0:
+		// Do nothing.
+	case 1:
 		var err error
-		dbf, err = ReadDBFZipFile(dbfFiles[0], readDBFOptions)
+		fpt, err = ReadFPTZipFile(fptFiles[0])
 		if err != nil {
 			return nil, err
 		}
 	default:
-		return nil, errors.New("too many .dbf files")
+		return nil, errors.New("too many .fpt files")
 	}
 
 	var prj *PRJ
@@ -420,16 +601,49 @@ func ReadZipReader(zipReader *zip.Reader, options *ReadShapefileOptions) (*Shape
 	default:
 		return nil, errors.New("too many .prj files")
 	}
+	prj, readSHPOptions, err := options.transformSHP(prj)
+	if err != nil {
+		return nil, err
+	}
+
+	// Spatial indexes are only read if they are used to select records.
+	var qix *QIX
+	var sbn *SBN
+	if readSHPOptions != nil && readSHPOptions.Bounds != nil {
+		switch len(qixFiles) {
+		case 0:
+			// Do nothing.
+		case 1:
+			var err error
+			qix, err = ReadQIXZipFile(qixFiles[0])
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.New("too many .qix files")
+		}
+
+		switch len(sbnFiles) {
+		case 0:
+			// Do nothing.
+		case 1:
+			var err error
+			sbn, err = ReadSBNZipFile(sbnFiles[0])
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.New("too many .sbn files")
+		}
+
+		readSHPOptions = readSHPOptions.withSpatialIndex(qix, sbn)
+	}
 
 	var shp *SHP
 	switch len(shpFiles) {
 	case 0:
 		// Do nothing.
 	case 1:
-		var readSHPOptions *ReadSHPOptions
-		if options != nil {
-			readSHPOptions = options.SHP
-		}
 		var err error
 		shp, err = ReadSHPZipFile(shpFiles[0], readSHPOptions)
 		if err != nil {
@@ -449,13 +663,37 @@ func ReadZipReader(zipReader *zip.Reader, options *ReadShapefileOptions) (*Shape
 		if err != nil {
 			return nil, err
 		}
+		if err := readSHPOptions.transformSHxHeader(&shx.SHxHeader); err != nil {
+			return nil, fmt.Errorf("%s: %w", shxFiles[0].Name, err)
+		}
 	default:
 		return nil, errors.New("too many .shx files")
 	}
 
+	var dbf *DBF
+	switch len(dbfFiles) {
+	case 0:
+		// Do nothing.
+	case 1:
+		var readDBFOptions *ReadDBFOptions
+		if options != nil {
+			readDBFOptions = options.DBF
+		}
+		readDBFOptions = readDBFOptions.withSiblingFiles(cpg, dbt, fpt).withSkippedSHPRecords(shp)
+		var err error
+		dbf, err = ReadDBFZipFile(dbfFiles[0], readDBFOptions)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("too many .dbf files")
+	}
+
 	if dbf != nil && shp != nil && len(dbf.Records) != len(shp.Records) ||
 		dbf != nil && shx != nil && len(dbf.Records) != len(shx.Records) ||
-		shp != nil && shx != nil && len(shp.Records) != len(shx.Records) {
+		shp != nil && shx != nil && len(shp.Records) != len(shx.Records) ||
+		qix != nil && shp != nil && qix.NumShapes != len(shp.Records) ||
+		sbn != nil && shp != nil && sbn.NumShapes != len(shp.Records) {
 		return nil, errors.New("inconsistent number of records")
 	}
 
@@ -468,6 +706,118 @@ func ReadZipReader(zipReader *zip.Reader, options *ReadShapefileOptions) (*Shape
 	}, nil
 }
 
+// WriteZipFile writes s to a .zip file called name. The members of the .zip
+// file are named after the base name of name.
+func WriteZipFile(name string, s *Shapefile) (err error) {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	zipWriter := zip.NewWriter(file)
+	basename := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	if err := WriteZipWriter(zipWriter, basename, s); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return zipWriter.Close()
+}
+
+// WriteZipWriter writes s to zipWriter with members named basename with the
+// extensions .shp, .shx, .dbf, .prj, and .cpg. Members are only written for
+// the components of s that are not nil, except that the .shx member is always
+// written with the .shp member, and a .cpg member is written if the .dbf
+// member is encoded with a charset other than the default. It does not close
+// zipWriter.
+//
+// Only C, D, F, L, and N fields can be written, so a Shapefile read from a
+// .dbf file with other field types, for example memo fields or dBase level 7
+// and Visual FoxPro types, cannot be written. In this case an error is returned
+// before any members are written.
+func WriteZipWriter(zipWriter *zip.Writer, basename string, s *Shapefile) error {
+	if s.DBF != nil {
+		if err := checkDBFFieldDescriptors(s.DBF.FieldDescriptors); err != nil {
+			return fmt.Errorf("%s.dbf: %w", basename, err)
+		}
+	}
+
+	cpg := s.CPG
+
+	if s.SHP != nil {
+		geoms := make([]geom.T, 0, len(s.SHP.Records))
+		for _, record := range s.SHP.Records {
+			geoms = append(geoms, record.Geom)
+		}
+		shpWriter, err := zipWriter.Create(basename + ".shp")
+		if err != nil {
+			return err
+		}
+		shxBuffer := &bytes.Buffer{}
+		if err := WriteSHP(shpWriter, shxBuffer, s.SHP.ShapeType, geoms); err != nil {
+			return fmt.Errorf("%s.shp: %w", basename, err)
+		}
+		shxWriter, err := zipWriter.Create(basename + ".shx")
+		if err != nil {
+			return err
+		}
+		if _, err := shxWriter.Write(shxBuffer.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if s.DBF != nil {
+		options := &WriteDBFOptions{
+			LanguageDriverID: s.DBF.LanguageDriverID,
+			LastUpdate:       s.DBF.LastUpdate,
+		}
+		switch {
+		case s.CPG != nil:
+			options.Charset = s.CPG.Charset
+		case s.DBF.Charset != "" && s.DBF.Charset != defaultCharset:
+			options.Charset = s.DBF.Charset
+			cpg = &CPG{Charset: s.DBF.Charset}
+		}
+		dbfWriter, err := zipWriter.Create(basename + ".dbf")
+		if err != nil {
+			return err
+		}
+		records := make([][]any, 0, len(s.DBF.Records))
+		for i, record := range s.DBF.Records {
+			if s.DBF.Deleted(i) {
+				record = nil
+			}
+			records = append(records, record)
+		}
+		if err := WriteDBF(dbfWriter, s.DBF.FieldDescriptors, records, options); err != nil {
+			return fmt.Errorf("%s.dbf: %w", basename, err)
+		}
+	}
+
+	if s.PRJ != nil {
+		prjWriter, err := zipWriter.Create(basename + ".prj")
+		if err != nil {
+			return err
+		}
+		if err := WritePRJ(prjWriter, s.PRJ); err != nil {
+			return fmt.Errorf("%s.prj: %w", basename, err)
+		}
+	}
+
+	if cpg != nil {
+		cpgWriter, err := zipWriter.Create(basename + ".cpg")
+		if err != nil {
+			return err
+		}
+		if err := WriteCPG(cpgWriter, cpg); err != nil {
+			return fmt.Errorf("%s.cpg: %w", basename, err)
+		}
+	}
+
+	return nil
+}
+
 // NumRecords returns the number of records in s.
 func (s *Shapefile) NumRecords() int {
 	switch {
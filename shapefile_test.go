@@ -2,6 +2,7 @@ package shapefile
 
 import (
 	"archive/zip"
+	"bytes"
 	"io"
 	"io/fs"
 	"math"
@@ -55,9 +56,13 @@ func TestReadFS(t *testing.T) {
 			basename:   "multi_polygon",
 		},
 		{
-			skipReason:        "multipatch is not supported",
-			basename:          "multipatch",
-			expectedShapeType: ShapeTypeMultiPatch,
+			basename:           "multipatch",
+			hasDBF:             true,
+			expectedShapeType:  ShapeTypeMultiPatch,
+			expectedBounds:     geom.NewBounds(geom.XYZM).Set(0, 0, 0, math.Inf(1), 5, 5, 0, math.Inf(-1)),
+			expectedRecordsLen: 1,
+			expectedGeom0:      newGeomFromWKT(t, "GEOMETRYCOLLECTION ZM (POLYGON ZM ((0 0 0 -1E+39,0 0 3 -1E+39,5 0 0 -1E+39,0 0 0 -1E+39)),POLYGON ZM ((0 0 3 -1E+39,5 0 0 -1E+39,5 0 3 -1E+39,0 0 3 -1E+39)),POLYGON ZM ((5 0 0 -1E+39,5 0 3 -1E+39,5 5 0 -1E+39,5 0 0 -1E+39)),POLYGON ZM ((5 0 3 -1E+39,5 5 0 -1E+39,5 5 3 -1E+39,5 0 3 -1E+39)),POLYGON ZM ((5 5 0 -1E+39,5 5 3 -1E+39,0 5 0 -1E+39,5 5 0 -1E+39)),POLYGON ZM ((5 5 3 -1E+39,0 5 0 -1E+39,0 5 3 -1E+39,5 5 3 -1E+39)),POLYGON ZM ((0 5 0 -1E+39,0 5 3 -1E+39,0 0 0 -1E+39,0 5 0 -1E+39)),POLYGON ZM ((0 5 3 -1E+39,0 0 0 -1E+39,0 0 3 -1E+39,0 5 3 -1E+39)),POLYGON ZM ((2.5 2.5 5 -1E+39,0 0 3 -1E+39,5 0 3 -1E+39,2.5 2.5 5 -1E+39)),POLYGON ZM ((2.5 2.5 5 -1E+39,5 0 3 -1E+39,5 5 3 -1E+39,2.5 2.5 5 -1E+39)),POLYGON ZM ((2.5 2.5 5 -1E+39,5 5 3 -1E+39,0 5 3 -1E+39,2.5 2.5 5 -1E+39)),POLYGON ZM ((2.5 2.5 5 -1E+39,0 5 3 -1E+39,0 0 3 -1E+39,2.5 2.5 5 -1E+39)))"),
+			expectedDBFRecord0: []any{"house1"},
 		},
 		{
 			basename:           "multipoint",
@@ -385,6 +390,68 @@ func TestReadFSAndZipFile(t *testing.T) {
 	}
 }
 
+func TestWriteZipFile(t *testing.T) {
+	for _, filename := range []string{
+		"testdata/110m-admin-0-countries.zip",
+		"testdata/Luftfahrthindernisse.zip",
+		"testdata/SZ.exe",
+	} {
+		t.Run(filename, func(t *testing.T) {
+			expected, err := ReadZipFile(filename, nil)
+			assert.NoError(t, err)
+
+			name := filepath.Join(t.TempDir(), "test.zip")
+			assert.NoError(t, WriteZipFile(name, expected))
+
+			actual, err := ReadZipFile(name, nil)
+			assert.NoError(t, err)
+
+			// A .cpg is written if the .dbf's charset is not the default.
+			expectedCPG := expected.CPG
+			if expectedCPG == nil && expected.DBF.Charset != defaultCharset {
+				expectedCPG = &CPG{Charset: expected.DBF.Charset}
+			}
+			assert.Equal(t, expectedCPG, actual.CPG)
+			assert.Equal(t, expected.PRJ, actual.PRJ)
+			assert.Equal(t, expected.DBF, actual.DBF)
+			assert.Equal(t, expected.SHP.ShapeType, actual.SHP.ShapeType)
+			assert.Equal(t, expected.SHP.Bounds, actual.SHP.Bounds)
+			assert.Equal(t, expected.SHP.Records, actual.SHP.Records)
+			assert.Equal(t, expected.SHX, actual.SHX)
+
+			zipReader, err := zip.OpenReader(name)
+			assert.NoError(t, err)
+			defer zipReader.Close()
+			var names []string
+			for _, zipFile := range zipReader.File {
+				names = append(names, zipFile.Name)
+			}
+			expectedNames := []string{"test.shp", "test.shx", "test.dbf"}
+			if expected.PRJ != nil {
+				expectedNames = append(expectedNames, "test.prj")
+			}
+			if expectedCPG != nil {
+				expectedNames = append(expectedNames, "test.cpg")
+			}
+			assert.Equal(t, expectedNames, names)
+		})
+	}
+}
+
+func TestWriteZipWriterUnsupportedFieldType(t *testing.T) {
+	shapefile, err := Read(filepath.Join("testdata", "memo3"), nil)
+	assert.NoError(t, err)
+
+	buffer := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buffer)
+	assert.EqualError(t, WriteZipWriter(zipWriter, "memo3", shapefile), "memo3.dbf: field NOTES: 77: unsupported field type")
+	assert.NoError(t, zipWriter.Close())
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(zipReader.File))
+}
+
 func TestShapefileRecords(t *testing.T) {
 	for _, tc := range []struct {
 		filename           string
@@ -9,6 +9,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"math"
+	"slices"
 
 	"github.com/twpayne/go-geom"
 )
@@ -20,15 +23,41 @@ type SHPRecord struct {
 	ShapeType     ShapeType
 	Bounds        *geom.Bounds
 	Geom          geom.T
+	PartTypes     []PartType
+	Skipped       bool // Whether the record was skipped because it does not intersect ReadSHPOptions.Bounds.
 }
 
+// A PartType is the type of a part of a MultiPatch.
+type PartType uint
+
+// Part types.
+const (
+	PartTypeTriangleStrip PartType = 0
+	PartTypeTriangleFan   PartType = 1
+	PartTypeOuterRing     PartType = 2
+	PartTypeInnerRing     PartType = 3
+	PartTypeFirstRing     PartType = 4
+	PartTypeRing          PartType = 5
+)
+
 // ReadSHPOptions are options for ReadSHP.
 type ReadSHPOptions struct {
 	MaxParts      int
 	MaxPoints     int
 	MaxRecordSize int
+	Bounds        *geom.Bounds // Only decode geometries that intersect Bounds, if not nil.
+	Transformer   *Transformer // Transform geometries and all bounds, if not nil.
+
+	// candidates, if not nil, contains the indexes of the records that a
+	// spatial index selects as candidates for Bounds. Other records are
+	// skipped without reading their content.
+	candidates map[int]struct{}
 }
 
+// shpRecordBoundsEnd is the offset of the end of the bounds in a record's
+// content.
+const shpRecordBoundsEnd = 4 + 8*4
+
 // A SHP is a .shp file.
 type SHP struct {
 	SHxHeader
@@ -42,6 +71,9 @@ func ReadSHP(r io.Reader, fileLength int64, options *ReadSHPOptions) (*SHP, erro
 	if err != nil {
 		return nil, err
 	}
+	if err := options.transformSHxHeader(header); err != nil {
+		return nil, err
+	}
 	var records []*SHPRecord
 RECORD:
 	for recordNumber := 1; ; recordNumber++ {
@@ -63,7 +95,32 @@ RECORD:
 }
 
 // ReadSHPRecord reads the next *SHPRecord from r.
+//
+// If options.Bounds is not nil then the geometry is only decoded if its
+// stored bounds intersect options.Bounds in X and Y. Otherwise, the rest of
+// the record is skipped and the returned record has Skipped set, a nil Geom,
+// and, for shape types with stored bounds, its X and Y bounds. Null shapes are
+// always skipped.
+//
+// When reading a Shapefile with a .qix or .sbn spatial index, records that the
+// index excludes are skipped without reading their content, and the returned
+// record only has a Number, a ContentLength, and Skipped set.
+//
+// If options.Transformer is not nil then the decoded geometry and its X and Y
+// bounds, or the X and Y bounds of a skipped record, are transformed.
 func ReadSHPRecord(r io.Reader, options *ReadSHPOptions) (*SHPRecord, error) {
+	record, err := readSHPRecord(r, options)
+	if err != nil || options == nil || options.Transformer == nil {
+		return record, err
+	}
+	if err := record.transform(options.Transformer); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// readSHPRecord reads the next *SHPRecord from r without transforming it.
+func readSHPRecord(r io.Reader, options *ReadSHPOptions) (*SHPRecord, error) {
 	recordHeaderData := make([]byte, 8)
 	if err := readFull(r, recordHeaderData); err != nil {
 		return nil, err
@@ -77,9 +134,49 @@ func ReadSHPRecord(r io.Reader, options *ReadSHPOptions) (*SHPRecord, error) {
 		return nil, errors.New("content length too large")
 	}
 
-	recordData := make([]byte, contentLength)
-	if err := readFull(r, recordData); err != nil {
-		return nil, err
+	if options != nil && options.candidates != nil {
+		if _, ok := options.candidates[recordNumber-1]; !ok {
+			switch _, err := io.CopyN(io.Discard, r, int64(contentLength)); {
+			case errors.Is(err, io.EOF):
+				return nil, io.ErrUnexpectedEOF
+			case err != nil:
+				return nil, err
+			}
+			return &SHPRecord{
+				Number:        recordNumber,
+				ContentLength: contentLength,
+				Skipped:       true,
+			}, nil
+		}
+	}
+
+	var recordData []byte
+	if options != nil && options.Bounds != nil {
+		prefixData := make([]byte, min(contentLength, shpRecordBoundsEnd))
+		if err := readFull(r, prefixData); err != nil {
+			return nil, err
+		}
+		if record := skippedSHPRecord(prefixData, recordNumber, contentLength, options.Bounds); record != nil {
+			switch _, err := io.CopyN(io.Discard, r, int64(contentLength-len(prefixData))); {
+			case errors.Is(err, io.EOF):
+				return nil, io.ErrUnexpectedEOF
+			case err != nil:
+				return nil, err
+			}
+			return record, nil
+		}
+		recordData = make([]byte, contentLength)
+		copy(recordData, prefixData)
+		if len(prefixData) < contentLength {
+			if err := readFull(r, recordData[len(prefixData):]); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		recordData = make([]byte, contentLength)
+		if err := readFull(r, recordData); err != nil {
+			return nil, err
+		}
 	}
 
 	byteSliceReader := newByteSliceReader(recordData)
@@ -98,6 +195,10 @@ func ReadSHPRecord(r io.Reader, options *ReadSHPOptions) (*SHPRecord, error) {
 		}, nil
 	}
 
+	if shapeType == ShapeTypeMultiPatch {
+		return readMultiPatchRecord(byteSliceReader, recordNumber, contentLength, options)
+	}
+
 	layout := geom.NoLayout
 	switch shapeType {
 	case ShapeTypeNull:
@@ -216,6 +317,89 @@ func ReadSHPRecord(r io.Reader, options *ReadSHPOptions) (*SHPRecord, error) {
 	}, nil
 }
 
+// withSpatialIndex returns a shallow copy of o that skips the records that qix,
+// or, if qix is nil, sbn, excludes for o.Bounds. It returns o if o.Bounds is
+// nil or both qix and sbn are nil. o is not modified.
+func (o *ReadSHPOptions) withSpatialIndex(qix *QIX, sbn *SBN) *ReadSHPOptions {
+	if o == nil || o.Bounds == nil {
+		return o
+	}
+	var shapeIDs iter.Seq[int]
+	switch {
+	case qix != nil:
+		shapeIDs = qix.Search(o.Bounds)
+	case sbn != nil:
+		shapeIDs = sbn.Search(o.Bounds)
+	default:
+		return o
+	}
+	options := *o
+	options.candidates = make(map[int]struct{})
+	for shapeID := range shapeIDs {
+		options.candidates[shapeID] = struct{}{}
+	}
+	return &options
+}
+
+// skippedSHPRecord returns the record with number and contentLength whose
+// content starts with prefixData if it does not intersect bounds, or nil if it
+// might intersect bounds and should be decoded. Null shapes do not intersect
+// any bounds.
+func skippedSHPRecord(prefixData []byte, number, contentLength int, bounds *geom.Bounds) *SHPRecord {
+	if len(prefixData) < 4 {
+		return nil
+	}
+	shapeType := ShapeType(binary.LittleEndian.Uint32(prefixData[:4]))
+	switch shapeType {
+	case ShapeTypeNull:
+		return &SHPRecord{
+			Number:        number,
+			ContentLength: contentLength,
+			ShapeType:     shapeType,
+			Skipped:       true,
+		}
+	case ShapeTypePoint, ShapeTypePointM, ShapeTypePointZ:
+		if len(prefixData) < 4+8*2 {
+			return nil
+		}
+		x := math.Float64frombits(binary.LittleEndian.Uint64(prefixData[4:12]))
+		y := math.Float64frombits(binary.LittleEndian.Uint64(prefixData[12:20]))
+		if bounds.OverlapsPoint(geom.XY, geom.Coord{x, y}) {
+			return nil
+		}
+		return &SHPRecord{
+			Number:        number,
+			ContentLength: contentLength,
+			ShapeType:     shapeType,
+			Skipped:       true,
+		}
+	case ShapeTypeMultiPoint, ShapeTypeMultiPointM, ShapeTypeMultiPointZ,
+		ShapeTypePolyLine, ShapeTypePolyLineM, ShapeTypePolyLineZ,
+		ShapeTypePolygon, ShapeTypePolygonM, ShapeTypePolygonZ,
+		ShapeTypeMultiPatch:
+		if len(prefixData) < shpRecordBoundsEnd {
+			return nil
+		}
+		minX := math.Float64frombits(binary.LittleEndian.Uint64(prefixData[4:12]))
+		minY := math.Float64frombits(binary.LittleEndian.Uint64(prefixData[12:20]))
+		maxX := math.Float64frombits(binary.LittleEndian.Uint64(prefixData[20:28]))
+		maxY := math.Float64frombits(binary.LittleEndian.Uint64(prefixData[28:36]))
+		recordBounds := geom.NewBounds(geom.XY).Set(minX, minY, maxX, maxY)
+		if bounds.Overlaps(geom.XY, recordBounds) {
+			return nil
+		}
+		return &SHPRecord{
+			Number:        number,
+			ContentLength: contentLength,
+			ShapeType:     shapeType,
+			Bounds:        recordBounds,
+			Skipped:       true,
+		}
+	default:
+		return nil
+	}
+}
+
 // ReadSHPZipFile reads a *SHP from a *zip.File.
 func ReadSHPZipFile(zipFile *zip.File, options *ReadSHPOptions) (*SHP, error) {
 	readCloser, err := zipFile.Open()
@@ -235,6 +419,465 @@ func (s *SHP) Record(i int) geom.T {
 	return s.Records[i].Geom
 }
 
+// A SHPWriter writes geometries to a .shp file and, optionally, the
+// corresponding .shx file. The headers are written when the SHPWriter is
+// closed.
+type SHPWriter struct {
+	shp       io.WriteSeeker
+	shx       io.WriteSeeker
+	shapeType ShapeType
+	bounds    *geom.Bounds
+	shpLength int64
+	shxLength int64
+	records   int
+	data      []byte
+}
+
+// NewSHPWriter returns a new SHPWriter that writes records with shapeType to
+// shp and, if shx is not nil, the index of the records to shx.
+func NewSHPWriter(shp, shx io.WriteSeeker, shapeType ShapeType) (*SHPWriter, error) {
+	if err := checkWriteShapeType(shapeType); err != nil {
+		return nil, err
+	}
+	w := &SHPWriter{
+		shp:       shp,
+		shx:       shx,
+		shapeType: shapeType,
+		bounds:    geom.NewBounds(shapeType.layout()),
+		shpLength: headerSize,
+		shxLength: headerSize,
+	}
+	if err := w.writeHeaders(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write writes g as the next record. A nil or empty g is written as a null
+// record.
+func (w *SHPWriter) Write(g geom.T) error {
+	data, err := w.encode(g)
+	if err != nil {
+		return err
+	}
+	return w.writeEncoded(g, data)
+}
+
+// Close writes the headers. It does not close the underlying writers.
+func (w *SHPWriter) Close() error {
+	if err := w.writeHeaders(); err != nil {
+		return err
+	}
+	if _, err := w.shp.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	if w.shx != nil {
+		if _, err := w.shx.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *SHPWriter) writeHeaders() error {
+	if _, err := w.shp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.shp.Write(appendSHxHeader(nil, w.shapeType, w.bounds, w.shpLength)); err != nil {
+		return err
+	}
+	if w.shx != nil {
+		if _, err := w.shx.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := w.shx.Write(appendSHxHeader(nil, w.shapeType, w.bounds, w.shxLength)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode returns g encoded as w's next record.
+func (w *SHPWriter) encode(g geom.T) ([]byte, error) {
+	data, err := appendSHPRecord(w.data[:0], w.records+1, w.shapeType, g)
+	if err != nil {
+		return nil, fmt.Errorf("record %d: %w", w.records+1, err)
+	}
+	w.data = data
+	return data, nil
+}
+
+// writeEncoded writes data, returned by encode for g.
+func (w *SHPWriter) writeEncoded(g geom.T, data []byte) error {
+	if _, err := w.shp.Write(data); err != nil {
+		return err
+	}
+	if w.shx != nil {
+		if _, err := w.shx.Write(appendSHXRecord(nil, int(w.shpLength), len(data)-8)); err != nil {
+			return err
+		}
+		w.shxLength += 8
+	}
+	if g != nil && !g.Empty() {
+		w.bounds.Extend(g)
+	}
+	w.shpLength += int64(len(data))
+	w.records++
+	return nil
+}
+
+// WriteSHP writes geoms as records with shapeType to w and, if shx is not nil,
+// the index of the records to shx. A nil or empty geometry is written as a
+// null record.
+func WriteSHP(w, shx io.Writer, shapeType ShapeType, geoms []geom.T) error {
+	if err := checkWriteShapeType(shapeType); err != nil {
+		return err
+	}
+
+	bounds := geom.NewBounds(shapeType.layout())
+	var recordsData []byte
+	shxRecordsData := make([]byte, 0, 8*len(geoms))
+	for i, g := range geoms {
+		offset := len(recordsData)
+		var err error
+		recordsData, err = appendSHPRecord(recordsData, i+1, shapeType, g)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i+1, err)
+		}
+		shxRecordsData = appendSHXRecord(shxRecordsData, headerSize+offset, len(recordsData)-offset-8)
+		if g != nil && !g.Empty() {
+			bounds.Extend(g)
+		}
+	}
+
+	shpLength := int64(headerSize + len(recordsData))
+	if _, err := w.Write(appendSHxHeader(nil, shapeType, bounds, shpLength)); err != nil {
+		return err
+	}
+	if _, err := w.Write(recordsData); err != nil {
+		return err
+	}
+
+	if shx != nil {
+		shxLength := int64(headerSize + len(shxRecordsData))
+		if _, err := shx.Write(appendSHxHeader(nil, shapeType, bounds, shxLength)); err != nil {
+			return err
+		}
+		if _, err := shx.Write(shxRecordsData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// layout returns the layout of geometries with shape type t.
+func (t ShapeType) layout() geom.Layout {
+	switch t {
+	case ShapeTypePoint, ShapeTypeMultiPoint, ShapeTypePolyLine, ShapeTypePolygon:
+		return geom.XY
+	case ShapeTypePointM, ShapeTypeMultiPointM, ShapeTypePolyLineM, ShapeTypePolygonM:
+		return geom.XYM
+	case ShapeTypePointZ, ShapeTypeMultiPointZ, ShapeTypePolyLineZ, ShapeTypePolygonZ:
+		return geom.XYZM
+	default:
+		return geom.NoLayout
+	}
+}
+
+// checkWriteShapeType returns an error if records with shapeType cannot be
+// written.
+func checkWriteShapeType(shapeType ShapeType) error {
+	if _, validShapeType := validShapeTypes[shapeType]; !validShapeType {
+		return errors.New("invalid shape type")
+	}
+	if shapeType == ShapeTypeMultiPatch {
+		return errors.New("unsupported shape type")
+	}
+	return nil
+}
+
+// appendSHPRecord appends the record with number for g with shapeType to data.
+func appendSHPRecord(data []byte, number int, shapeType ShapeType, g geom.T) ([]byte, error) {
+	start := len(data)
+	data = binary.BigEndian.AppendUint32(data, uint32(number))
+	data = append(data, 0, 0, 0, 0)
+
+	if g == nil || g.Empty() {
+		data = binary.LittleEndian.AppendUint32(data, uint32(ShapeTypeNull))
+		binary.BigEndian.PutUint32(data[start+4:start+8], uint32((len(data)-start-8)/2))
+		return data, nil
+	}
+
+	layout := shapeType.layout()
+	if g.Layout() != layout {
+		return nil, fmt.Errorf("%s: unsupported layout for shape type %d", g.Layout(), shapeType)
+	}
+
+	if _, ok := g.(*geom.GeometryCollection); ok {
+		return nil, fmt.Errorf("%T: unsupported geometry for shape type %d", g, shapeType)
+	}
+
+	flatCoords := g.FlatCoords()
+	var ends []int
+	switch shapeType {
+	case ShapeTypePoint, ShapeTypePointM, ShapeTypePointZ:
+		if _, ok := g.(*geom.Point); !ok {
+			return nil, fmt.Errorf("%T: unsupported geometry for shape type %d", g, shapeType)
+		}
+		data = binary.LittleEndian.AppendUint32(data, uint32(shapeType))
+		data = appendFloat64s(data, flatCoords...)
+		binary.BigEndian.PutUint32(data[start+4:start+8], uint32((len(data)-start-8)/2))
+		return data, nil
+	case ShapeTypeMultiPoint, ShapeTypeMultiPointM, ShapeTypeMultiPointZ:
+		if _, ok := g.(*geom.MultiPoint); !ok {
+			return nil, fmt.Errorf("%T: unsupported geometry for shape type %d", g, shapeType)
+		}
+	case ShapeTypePolyLine, ShapeTypePolyLineM, ShapeTypePolyLineZ:
+		switch g := g.(type) {
+		case *geom.LineString:
+			ends = []int{len(flatCoords)}
+		case *geom.MultiLineString:
+			ends = g.Ends()
+		default:
+			return nil, fmt.Errorf("%T: unsupported geometry for shape type %d", g, shapeType)
+		}
+	case ShapeTypePolygon, ShapeTypePolygonM, ShapeTypePolygonZ:
+		var endss [][]int
+		switch g := g.(type) {
+		case *geom.Polygon:
+			endss = [][]int{g.Ends()}
+		case *geom.MultiPolygon:
+			endss = g.Endss()
+		default:
+			return nil, fmt.Errorf("%T: unsupported geometry for shape type %d", g, shapeType)
+		}
+		flatCoords = orientRings(layout, flatCoords, endss)
+		for _, polygonEnds := range endss {
+			ends = append(ends, polygonEnds...)
+		}
+	default:
+		return nil, fmt.Errorf("%T: unsupported geometry for shape type %d", g, shapeType)
+	}
+
+	stride := layout.Stride()
+	numPoints := len(flatCoords) / stride
+	bounds := geom.NewBounds(layout).Extend(g)
+
+	data = binary.LittleEndian.AppendUint32(data, uint32(shapeType))
+	data = appendFloat64s(data, bounds.Min(0), bounds.Min(1), bounds.Max(0), bounds.Max(1))
+	switch shapeType {
+	case ShapeTypePolyLine, ShapeTypePolyLineM, ShapeTypePolyLineZ, ShapeTypePolygon, ShapeTypePolygonM, ShapeTypePolygonZ:
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(ends)))
+		data = binary.LittleEndian.AppendUint32(data, uint32(numPoints))
+		offset := 0
+		for _, end := range ends {
+			data = binary.LittleEndian.AppendUint32(data, uint32(offset/stride))
+			offset = end
+		}
+	default:
+		data = binary.LittleEndian.AppendUint32(data, uint32(numPoints))
+	}
+	for i := 0; i < len(flatCoords); i += stride {
+		data = appendFloat64s(data, flatCoords[i], flatCoords[i+1])
+	}
+	if layout == geom.XYZM {
+		data = appendFloat64s(data, bounds.Min(2), bounds.Max(2))
+		for i := 2; i < len(flatCoords); i += stride {
+			data = appendFloat64s(data, flatCoords[i])
+		}
+	}
+	if mIndex := layout.MIndex(); mIndex != -1 {
+		data = appendFloat64s(data, bounds.Min(mIndex), bounds.Max(mIndex))
+		for i := mIndex; i < len(flatCoords); i += stride {
+			data = appendFloat64s(data, flatCoords[i])
+		}
+	}
+
+	binary.BigEndian.PutUint32(data[start+4:start+8], uint32((len(data)-start-8)/2))
+	return data, nil
+}
+
+// orientRings returns flatCoords with the rings defined by endss oriented as
+// required by the Shapefile specification, that is with outer rings in
+// clockwise order and inner rings in anti-clockwise order. flatCoords is only
+// copied if a ring needs to be reversed.
+func orientRings(layout geom.Layout, flatCoords []float64, endss [][]int) []float64 {
+	stride := layout.Stride()
+	copied := false
+	offset := 0
+	for _, ends := range endss {
+		for i, end := range ends {
+			doubleArea := doubleArea(flatCoords, offset, end, stride)
+			if i == 0 && doubleArea > 0 || i != 0 && doubleArea < 0 {
+				if !copied {
+					flatCoords = append([]float64(nil), flatCoords...)
+					copied = true
+				}
+				reverseRing(flatCoords, offset, end, stride)
+			}
+			offset = end
+		}
+	}
+	return flatCoords
+}
+
+// reverseRing reverses the order of the coordinates from offset to end in
+// flatCoords.
+func reverseRing(flatCoords []float64, offset, end, stride int) {
+	for i, j := offset, end-stride; i < j; i, j = i+stride, j-stride {
+		for k := range stride {
+			flatCoords[i+k], flatCoords[j+k] = flatCoords[j+k], flatCoords[i+k]
+		}
+	}
+}
+
+func appendFloat64s(data []byte, xs ...float64) []byte {
+	for _, x := range xs {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(x))
+	}
+	return data
+}
+
+// readMultiPatchRecord reads a MultiPatch record from byteSliceReader. Each
+// part is converted into polygons: triangle strips and triangle fans into one
+// polygon per triangle, and rings into polygons with holes. The polygons are
+// returned in a geom.GeometryCollection and the original part types are
+// returned in the PartTypes field of the record.
+func readMultiPatchRecord(byteSliceReader *byteSliceReader, recordNumber, contentLength int, options *ReadSHPOptions) (*SHPRecord, error) {
+	minX, minY := byteSliceReader.readFloat64Pair()
+	maxX, maxY := byteSliceReader.readFloat64Pair()
+
+	numParts := byteSliceReader.readUint32()
+	if numParts == 0 {
+		return nil, errors.New("invalid number of parts")
+	}
+	if options != nil && options.MaxParts != 0 && numParts > options.MaxParts {
+		return nil, errors.New("too many parts")
+	}
+	numPoints := byteSliceReader.readUint32()
+	if options != nil && options.MaxPoints != 0 && numPoints > options.MaxPoints {
+		return nil, errors.New("too many points")
+	}
+
+	// The M values are optional.
+	expectedContentLength := 4 + 8*4 + 4 + 4 + 4*numParts + 4*numParts + 8*2*numPoints + 8*2 + 8*numPoints
+	var layout geom.Layout
+	switch contentLength {
+	case expectedContentLength:
+		layout = geom.XYZ
+	case expectedContentLength + 8*2 + 8*numPoints:
+		layout = geom.XYZM
+	default:
+		return nil, errors.New("invalid content length")
+	}
+
+	ends := byteSliceReader.readEnds(layout, numParts, numPoints)
+	partTypes := make([]PartType, 0, numParts)
+	for range numParts {
+		partTypes = append(partTypes, PartType(byteSliceReader.readUint32()))
+	}
+
+	flatCoords := make([]float64, layout.Stride()*numPoints)
+	byteSliceReader.readXYs(flatCoords, numPoints, layout)
+	minZ, maxZ := byteSliceReader.readFloat64Pair()
+	byteSliceReader.readOrdinates(flatCoords, numPoints, layout, layout.ZIndex())
+	var bounds *geom.Bounds
+	if layout == geom.XYZM {
+		minM, maxM := byteSliceReader.readFloat64Pair()
+		byteSliceReader.readOrdinates(flatCoords, numPoints, layout, layout.MIndex())
+		bounds = geom.NewBounds(geom.XYZM).Set(minX, minY, minZ, minM, maxX, maxY, maxZ, maxM)
+	} else {
+		bounds = geom.NewBounds(geom.XYZ).Set(minX, minY, minZ, maxX, maxY, maxZ)
+	}
+
+	if err := byteSliceReader.Err(); err != nil {
+		return nil, err
+	}
+
+	polygons, err := makeMultiPatchPolygons(layout, flatCoords, ends, partTypes)
+	if err != nil {
+		return nil, err
+	}
+	geometryCollection := geom.NewGeometryCollection()
+	if err := geometryCollection.SetLayout(layout); err != nil {
+		return nil, err
+	}
+	if err := geometryCollection.Push(polygons...); err != nil {
+		return nil, err
+	}
+
+	return &SHPRecord{
+		Number:        recordNumber,
+		ContentLength: contentLength,
+		ShapeType:     ShapeTypeMultiPatch,
+		Bounds:        bounds,
+		Geom:          geometryCollection,
+		PartTypes:     partTypes,
+	}, nil
+}
+
+// makeMultiPatchPolygons returns the polygons of the MultiPatch parts defined
+// by flatCoords, ends, and partTypes.
+//
+// Inner rings belong to the most recent outer ring, and rings belong to the
+// most recent first ring. Rings that do not follow a first ring are treated as
+// outer rings.
+func makeMultiPatchPolygons(layout geom.Layout, flatCoords []float64, ends []int, partTypes []PartType) ([]geom.T, error) {
+	stride := layout.Stride()
+	var polygons []geom.T
+	var polygon *geom.Polygon
+	var polygonPartType PartType
+	offset := 0
+	for i, end := range ends {
+		partFlatCoords := flatCoords[offset:end]
+		offset = end
+		switch partType := partTypes[i]; partType {
+		case PartTypeTriangleStrip, PartTypeTriangleFan:
+			numPoints := len(partFlatCoords) / stride
+			if numPoints < 3 {
+				return nil, fmt.Errorf("part %d: too few points in triangles", i)
+			}
+			for j := 2; j < numPoints; j++ {
+				first := partFlatCoords[:stride]
+				if partType == PartTypeTriangleStrip {
+					first = partFlatCoords[(j-2)*stride : (j-1)*stride]
+				}
+				triangleFlatCoords := make([]float64, 0, 4*stride)
+				triangleFlatCoords = append(triangleFlatCoords, first...)
+				triangleFlatCoords = append(triangleFlatCoords, partFlatCoords[(j-1)*stride:(j+1)*stride]...)
+				triangleFlatCoords = append(triangleFlatCoords, first...)
+				polygons = append(polygons, geom.NewPolygonFlat(layout, triangleFlatCoords, []int{4 * stride}))
+			}
+			polygon = nil
+		case PartTypeOuterRing, PartTypeFirstRing:
+			polygon = geom.NewPolygonFlat(layout, slices.Clone(partFlatCoords), []int{len(partFlatCoords)})
+			polygonPartType = partType
+			polygons = append(polygons, polygon)
+		case PartTypeInnerRing:
+			if polygon == nil || polygonPartType != PartTypeOuterRing {
+				return nil, fmt.Errorf("part %d: inner ring without outer ring", i)
+			}
+			if err := polygon.Push(geom.NewLinearRingFlat(layout, partFlatCoords)); err != nil {
+				return nil, err
+			}
+		case PartTypeRing:
+			if polygon == nil || polygonPartType != PartTypeFirstRing {
+				polygon = geom.NewPolygonFlat(layout, slices.Clone(partFlatCoords), []int{len(partFlatCoords)})
+				polygonPartType = partType
+				polygons = append(polygons, polygon)
+				continue
+			}
+			if err := polygon.Push(geom.NewLinearRingFlat(layout, partFlatCoords)); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("part %d: %d: invalid part type", i, partType)
+		}
+	}
+	return polygons, nil
+}
+
 // makeMultiPolygonEndss returns the multipolygon endss by inspecting the
 // orientation of the rings defined by flatCoords and ends. Each clockwise ring
 // defines the outer ring of a new polygon, and each anti-clockwise ring defines
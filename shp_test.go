@@ -3,6 +3,7 @@ package shapefile
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
@@ -83,6 +84,115 @@ func TestMakeMultiPolygonEndss(t *testing.T) {
 	}
 }
 
+func TestMakeMultiPatchPolygons(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		flatCoords  []float64
+		ends        []int
+		partTypes   []PartType
+		expected    []geom.T
+		expectedErr string
+	}{
+		{
+			name:       "triangle_strip",
+			flatCoords: []float64{0, 0, 0, 1, 1, 0, 1, 1},
+			ends:       []int{8},
+			partTypes:  []PartType{PartTypeTriangleStrip},
+			expected: []geom.T{
+				newGeomFromWKT(t, "POLYGON ((0 0,0 1,1 0,0 0))"),
+				newGeomFromWKT(t, "POLYGON ((0 1,1 0,1 1,0 1))"),
+			},
+		},
+		{
+			name:       "triangle_fan",
+			flatCoords: []float64{0, 0, 0, 1, 1, 1, 1, 0},
+			ends:       []int{8},
+			partTypes:  []PartType{PartTypeTriangleFan},
+			expected: []geom.T{
+				newGeomFromWKT(t, "POLYGON ((0 0,0 1,1 1,0 0))"),
+				newGeomFromWKT(t, "POLYGON ((0 0,1 1,1 0,0 0))"),
+			},
+		},
+		{
+			name: "outer_and_inner_rings",
+			flatCoords: []float64{
+				0, 0, 0, 4, 4, 4, 4, 0, 0, 0,
+				1, 1, 2, 1, 2, 2, 1, 1,
+				5, 5, 5, 6, 6, 6, 5, 5,
+			},
+			ends:      []int{10, 18, 26},
+			partTypes: []PartType{PartTypeOuterRing, PartTypeInnerRing, PartTypeOuterRing},
+			expected: []geom.T{
+				newGeomFromWKT(t, "POLYGON ((0 0,0 4,4 4,4 0,0 0),(1 1,2 1,2 2,1 1))"),
+				newGeomFromWKT(t, "POLYGON ((5 5,5 6,6 6,5 5))"),
+			},
+		},
+		{
+			name: "first_ring_and_rings",
+			flatCoords: []float64{
+				0, 0, 0, 4, 4, 4, 4, 0, 0, 0,
+				1, 1, 2, 1, 2, 2, 1, 1,
+				5, 5, 5, 6, 6, 6, 5, 5,
+			},
+			ends:      []int{10, 18, 26},
+			partTypes: []PartType{PartTypeFirstRing, PartTypeRing, PartTypeRing},
+			expected: []geom.T{
+				newGeomFromWKT(t, "POLYGON ((0 0,0 4,4 4,4 0,0 0),(1 1,2 1,2 2,1 1),(5 5,5 6,6 6,5 5))"),
+			},
+		},
+		{
+			name:       "rings",
+			flatCoords: []float64{0, 0, 0, 1, 1, 1, 0, 0, 5, 5, 5, 6, 6, 6, 5, 5},
+			ends:       []int{8, 16},
+			partTypes:  []PartType{PartTypeRing, PartTypeRing},
+			expected: []geom.T{
+				newGeomFromWKT(t, "POLYGON ((0 0,0 1,1 1,0 0))"),
+				newGeomFromWKT(t, "POLYGON ((5 5,5 6,6 6,5 5))"),
+			},
+		},
+		{
+			name:        "inner_ring_without_outer_ring",
+			flatCoords:  []float64{0, 0, 0, 1, 1, 1, 0, 0},
+			ends:        []int{8},
+			partTypes:   []PartType{PartTypeInnerRing},
+			expectedErr: "part 0: inner ring without outer ring",
+		},
+		{
+			name:        "too_few_points_in_triangles",
+			flatCoords:  []float64{0, 0, 0, 1},
+			ends:        []int{4},
+			partTypes:   []PartType{PartTypeTriangleStrip},
+			expectedErr: "part 0: too few points in triangles",
+		},
+		{
+			name:        "invalid_part_type",
+			flatCoords:  []float64{0, 0, 0, 1, 1, 1, 0, 0},
+			ends:        []int{8},
+			partTypes:   []PartType{6},
+			expectedErr: "part 0: 6: invalid part type",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := makeMultiPatchPolygons(geom.XY, tc.flatCoords, tc.ends, tc.partTypes)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReadSHPMultiPatch(t *testing.T) {
+	shapefile, err := Read(filepath.Join("testdata", "multipatch"), nil)
+	assert.NoError(t, err)
+	record := shapefile.SHP.Records[0]
+	assert.Equal(t, []PartType{PartTypeTriangleStrip, PartTypeTriangleFan}, record.PartTypes)
+	assert.Equal(t, geom.NewBounds(geom.XYZM).Set(0, 0, 0, -1e39, 5, 5, 5, -1e39), record.Bounds)
+	assert.Equal(t, 12, record.Geom.(*geom.GeometryCollection).NumGeoms())
+}
+
 func FuzzReadSHP(f *testing.F) {
 	assert.NoError(f, addFuzzDataFromFS(f, os.DirFS("."), "testdata", ".shp"))
 
@@ -95,3 +205,309 @@ func FuzzReadSHP(f *testing.F) {
 		})
 	})
 }
+
+func TestWriteSHP(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		shapeType      ShapeType
+		geoms          []geom.T
+		expectedGeoms  []geom.T
+		expectedBounds *geom.Bounds
+		expectedErr    string
+	}{
+		{
+			name:           "empty",
+			shapeType:      ShapeTypePoint,
+			expectedBounds: geom.NewBounds(geom.XY).Set(0, 0, 0, 0),
+		},
+		{
+			name:      "point",
+			shapeType: ShapeTypePoint,
+			geoms: []geom.T{
+				newGeomFromWKT(t, "POINT (1 2)"),
+				nil,
+				newGeomFromWKT(t, "POINT (3 4)"),
+			},
+			expectedBounds: geom.NewBounds(geom.XY).Set(1, 2, 3, 4),
+		},
+		{
+			name:      "pointm",
+			shapeType: ShapeTypePointM,
+			geoms: []geom.T{
+				newGeomFromWKT(t, "POINT M (1 2 3)"),
+			},
+			expectedBounds: geom.NewBounds(geom.XYM).Set(1, 2, 3, 1, 2, 3),
+		},
+		{
+			name:      "multipointz",
+			shapeType: ShapeTypeMultiPointZ,
+			geoms: []geom.T{
+				newGeomFromWKT(t, "MULTIPOINT ZM ((1 2 3 4),(5 6 7 8))"),
+			},
+			expectedBounds: geom.NewBounds(geom.XYZM).Set(1, 2, 3, 4, 5, 6, 7, 8),
+		},
+		{
+			name:      "linem",
+			shapeType: ShapeTypePolyLineM,
+			geoms: []geom.T{
+				newGeomFromWKT(t, "MULTILINESTRING M ((1 5 0,5 5 1,5 1 3),(3 2 4,2 6 5))"),
+				newGeomFromWKT(t, "LINESTRING M (0 0 0,1 1 1)"),
+			},
+			expectedGeoms: []geom.T{
+				newGeomFromWKT(t, "MULTILINESTRING M ((1 5 0,5 5 1,5 1 3),(3 2 4,2 6 5))"),
+				newGeomFromWKT(t, "MULTILINESTRING M ((0 0 0,1 1 1))"),
+			},
+			expectedBounds: geom.NewBounds(geom.XYM).Set(0, 0, 0, 5, 6, 5),
+		},
+		{
+			name:      "polygon",
+			shapeType: ShapeTypePolygon,
+			geoms: []geom.T{
+				newGeomFromWKT(t, "MULTIPOLYGON (((0 0,0 4,4 4,4 0,0 0),(1 1,2 1,2 2,1 2,1 1)),((5 5,5 6,6 6,6 5,5 5)))"),
+				newGeomFromWKT(t, "POLYGON ((0 0,4 0,4 4,0 4,0 0),(1 1,1 2,2 2,2 1,1 1))"),
+			},
+			expectedGeoms: []geom.T{
+				newGeomFromWKT(t, "MULTIPOLYGON (((0 0,0 4,4 4,4 0,0 0),(1 1,2 1,2 2,1 2,1 1)),((5 5,5 6,6 6,6 5,5 5)))"),
+				newGeomFromWKT(t, "MULTIPOLYGON (((0 0,0 4,4 4,4 0,0 0),(1 1,2 1,2 2,1 2,1 1)))"),
+			},
+			expectedBounds: geom.NewBounds(geom.XY).Set(0, 0, 6, 6),
+		},
+		{
+			name:      "invalid_layout",
+			shapeType: ShapeTypePoint,
+			geoms: []geom.T{
+				newGeomFromWKT(t, "POINT M (1 2 3)"),
+			},
+			expectedErr: "record 1: XYM: unsupported layout for shape type 1",
+		},
+		{
+			name:      "invalid_geometry",
+			shapeType: ShapeTypePolygon,
+			geoms: []geom.T{
+				newGeomFromWKT(t, "LINESTRING (0 0,1 1)"),
+			},
+			expectedErr: "record 1: *geom.LineString: unsupported geometry for shape type 5",
+		},
+		{
+			name:        "multipatch",
+			shapeType:   ShapeTypeMultiPatch,
+			expectedErr: "unsupported shape type",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			expectedGeoms := tc.expectedGeoms
+			if expectedGeoms == nil {
+				expectedGeoms = tc.geoms
+			}
+
+			testSHPAndSHX := func(t *testing.T, shpData, shxData []byte) {
+				t.Helper()
+				shp, err := ReadSHP(bytes.NewReader(shpData), int64(len(shpData)), nil)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.shapeType, shp.ShapeType)
+				assert.Equal(t, tc.expectedBounds, shp.Bounds)
+				assert.Equal(t, len(expectedGeoms), len(shp.Records))
+				for i, expectedGeom := range expectedGeoms {
+					assert.Equal(t, expectedGeom, shp.Record(i))
+				}
+
+				shx, err := ReadSHX(bytes.NewReader(shxData), int64(len(shxData)))
+				assert.NoError(t, err)
+				assert.Equal(t, tc.shapeType, shx.ShapeType)
+				assert.Equal(t, tc.expectedBounds, shx.Bounds)
+				assert.Equal(t, len(shp.Records), len(shx.Records))
+				offset := headerSize
+				for i, shxRecord := range shx.Records {
+					assert.Equal(t, SHXRecord{Offset: offset, ContentLength: shp.Records[i].ContentLength}, shxRecord)
+					offset += 8 + shxRecord.ContentLength
+				}
+			}
+
+			t.Run("WriteSHP", func(t *testing.T) {
+				shpBuffer := &bytes.Buffer{}
+				shxBuffer := &bytes.Buffer{}
+				err := WriteSHP(shpBuffer, shxBuffer, tc.shapeType, tc.geoms)
+				if tc.expectedErr != "" {
+					assert.EqualError(t, err, tc.expectedErr)
+					return
+				}
+				assert.NoError(t, err)
+				testSHPAndSHX(t, shpBuffer.Bytes(), shxBuffer.Bytes())
+			})
+
+			t.Run("SHPWriter", func(t *testing.T) {
+				tempDir := t.TempDir()
+				shpFile, err := os.Create(filepath.Join(tempDir, "test.shp"))
+				assert.NoError(t, err)
+				defer shpFile.Close()
+				shxFile, err := os.Create(filepath.Join(tempDir, "test.shx"))
+				assert.NoError(t, err)
+				defer shxFile.Close()
+
+				shpWriter, err := NewSHPWriter(shpFile, shxFile, tc.shapeType)
+				if tc.expectedErr != "" && err != nil {
+					assert.EqualError(t, err, tc.expectedErr)
+					return
+				}
+				assert.NoError(t, err)
+				for _, g := range tc.geoms {
+					if err := shpWriter.Write(g); err != nil {
+						assert.EqualError(t, err, tc.expectedErr)
+						return
+					}
+				}
+				assert.Zero(t, tc.expectedErr)
+				assert.NoError(t, shpWriter.Close())
+
+				shpData, err := os.ReadFile(shpFile.Name())
+				assert.NoError(t, err)
+				shxData, err := os.ReadFile(shxFile.Name())
+				assert.NoError(t, err)
+				testSHPAndSHX(t, shpData, shxData)
+			})
+		})
+	}
+}
+
+func TestReadSHPBounds(t *testing.T) {
+	bounds := geom.NewBounds(geom.XY).Set(480400, 4764000, 481000, 4765000)
+	expectedIndexes := []int{6, 7}
+
+	shapefile, err := Read(filepath.Join("testdata", "poly"), nil)
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name         string
+		exts         []string
+		spatialIndex bool
+	}{
+		{
+			name: "no_index",
+			exts: []string{".dbf", ".shp", ".shx"},
+		},
+		{
+			name:         "qix",
+			exts:         []string{".dbf", ".qix", ".shp", ".shx"},
+			spatialIndex: true,
+		},
+		{
+			name:         "sbn",
+			exts:         []string{".dbf", ".sbn", ".shp", ".shx"},
+			spatialIndex: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			for _, ext := range tc.exts {
+				data, err := os.ReadFile(filepath.Join("testdata", "poly"+ext))
+				assert.NoError(t, err)
+				assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "poly"+ext), data, 0o666))
+			}
+			basename := filepath.Join(tempDir, "poly")
+
+			options := &ReadShapefileOptions{
+				SHP: &ReadSHPOptions{
+					Bounds: bounds,
+				},
+			}
+
+			for _, read := range []func() (*Shapefile, error){
+				func() (*Shapefile, error) { return Read(basename, options) },
+				func() (*Shapefile, error) { return ReadFS(os.DirFS(tempDir), "poly", options) },
+			} {
+				filteredShapefile, err := read()
+				assert.NoError(t, err)
+				assert.Equal(t, len(shapefile.SHP.Records), len(filteredShapefile.SHP.Records))
+				var indexes []int
+				indexSkipped := 0
+				for i, record := range filteredShapefile.SHP.Records {
+					assert.Equal(t, shapefile.SHP.Records[i].Number, record.Number)
+					assert.Equal(t, shapefile.SHP.Records[i].ContentLength, record.ContentLength)
+					if !record.Skipped {
+						assert.Equal(t, shapefile.SHP.Records[i], record)
+						assert.Equal(t, shapefile.DBF.Records[i], filteredShapefile.DBF.Records[i])
+						indexes = append(indexes, i)
+						continue
+					}
+					assert.Zero(t, record.Geom)
+					assert.Zero(t, filteredShapefile.DBF.Records[i])
+					// Records that the spatial index excludes are skipped
+					// without reading their shape type or bounds.
+					if tc.spatialIndex && record.Bounds == nil {
+						assert.Zero(t, record.ShapeType)
+						indexSkipped++
+						continue
+					}
+					assert.Equal(t, shapefile.SHP.Records[i].ShapeType, record.ShapeType)
+					assert.Equal(t, shapefile.SHP.Records[i].Bounds.Min(0), record.Bounds.Min(0))
+					assert.Equal(t, shapefile.SHP.Records[i].Bounds.Max(1), record.Bounds.Max(1))
+				}
+				assert.Equal(t, expectedIndexes, indexes)
+				assert.Equal(t, tc.spatialIndex, indexSkipped > 0)
+			}
+
+			scanner, err := NewScannerFromBasename(basename, options)
+			assert.NoError(t, err)
+			defer func() {
+				assert.NoError(t, scanner.Close())
+			}()
+			scannedShapefile, err := ReadScanner(scanner)
+			assert.NoError(t, err)
+			assert.Equal(t, len(expectedIndexes), len(scannedShapefile.SHP.Records))
+			assert.Equal(t, len(expectedIndexes), len(scannedShapefile.DBF.Records))
+			assert.Equal(t, len(expectedIndexes), len(scannedShapefile.SHX.Records))
+			for i, index := range expectedIndexes {
+				assert.Equal(t, shapefile.SHP.Records[index], scannedShapefile.SHP.Records[i])
+				assert.Equal(t, shapefile.DBF.Records[index], scannedShapefile.DBF.Records[i])
+				assert.Equal(t, shapefile.SHX.Records[index], scannedShapefile.SHX.Records[i])
+			}
+			assert.Equal(t, int64(len(shapefile.SHP.Records)), scanner.ScannedRecords())
+		})
+	}
+}
+
+func TestReadSHPBoundsPoint(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		bounds       *geom.Bounds
+		expectedGeom bool
+	}{
+		{
+			name:         "inside",
+			bounds:       geom.NewBounds(geom.XY).Set(-180, -90, 180, 90),
+			expectedGeom: true,
+		},
+		{
+			name:   "outside",
+			bounds: geom.NewBounds(geom.XY).Set(1000, 1000, 2000, 2000),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			shapefile, err := Read(filepath.Join("testdata", "point"), &ReadShapefileOptions{
+				SHP: &ReadSHPOptions{
+					Bounds: tc.bounds,
+				},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedGeom, shapefile.SHP.Record(0) != nil)
+		})
+	}
+}
+
+func TestReadSHPRecordBoundsNull(t *testing.T) {
+	data := []byte{
+		0, 0, 0, 1, // Record number.
+		0, 0, 0, 2, // Content length.
+		0, 0, 0, 0, // Shape type.
+	}
+
+	record, err := ReadSHPRecord(bytes.NewReader(data), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &SHPRecord{Number: 1, ContentLength: 4, ShapeType: ShapeTypeNull}, record)
+
+	record, err = ReadSHPRecord(bytes.NewReader(data), &ReadSHPOptions{
+		Bounds: geom.NewBounds(geom.XY).Set(-180, -90, 180, 90),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &SHPRecord{Number: 1, ContentLength: 4, ShapeType: ShapeTypeNull, Skipped: true}, record)
+}
@@ -68,3 +68,11 @@ func ParseSHXRecord(data []byte) SHXRecord {
 		ContentLength: contentLength,
 	}
 }
+
+// appendSHXRecord appends the SHX record for a SHP record at offset with
+// contentLength to data.
+func appendSHXRecord(data []byte, offset, contentLength int) []byte {
+	data = binary.BigEndian.AppendUint32(data, uint32(offset/2))
+	data = binary.BigEndian.AppendUint32(data, uint32(contentLength/2))
+	return data
+}
@@ -46,9 +46,6 @@ func parseSHxHeader(data []byte, fileLength int64) (*SHxHeader, error) {
 	if _, validShapeType := validShapeTypes[shapeType]; !validShapeType {
 		return nil, errors.New("invalid shape type")
 	}
-	if _, unsupportedShapeType := unsupportedShapeTypes[shapeType]; unsupportedShapeType {
-		return nil, errors.New("unsupported shape type")
-	}
 
 	minX := math.Float64frombits(binary.LittleEndian.Uint64(data[36:44]))
 	minY := math.Float64frombits(binary.LittleEndian.Uint64(data[44:52]))
@@ -85,7 +82,7 @@ func parseSHxHeader(data []byte, fileLength int64) (*SHxHeader, error) {
 			maxM = math.Inf(-1)
 		}
 		bounds = geom.NewBounds(geom.XYM).Set(minX, minY, minM, maxX, maxY, maxM)
-	case ShapeTypePointZ, ShapeTypeMultiPointZ, ShapeTypePolyLineZ, ShapeTypePolygonZ:
+	case ShapeTypePointZ, ShapeTypeMultiPointZ, ShapeTypePolyLineZ, ShapeTypePolygonZ, ShapeTypeMultiPatch:
 		if NoData(minM) {
 			minM = math.Inf(1)
 		}
@@ -128,3 +125,46 @@ func readFull(r io.Reader, data []byte) error {
 		}
 	}
 }
+
+// readAll reads exactly size bytes from r. Unlike readFull, the returned slice
+// grows as data is read, so an untrusted size does not cause a large
+// allocation unless r really contains that much data.
+func readAll(r io.Reader, size int64) ([]byte, error) {
+	if size < 0 {
+		return nil, errors.New("invalid size")
+	}
+	data, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+// appendSHxHeader appends a .shp or .shx header to data.
+func appendSHxHeader(data []byte, shapeType ShapeType, bounds *geom.Bounds, fileLength int64) []byte {
+	data = binary.BigEndian.AppendUint32(data, fileCode)
+	data = append(data, make([]byte, 20)...)
+	data = binary.BigEndian.AppendUint32(data, uint32(fileLength/2))
+	data = binary.LittleEndian.AppendUint32(data, version)
+	data = binary.LittleEndian.AppendUint32(data, uint32(shapeType))
+
+	var minX, minY, maxX, maxY, minZ, maxZ, minM, maxM float64
+	if bounds != nil && !bounds.IsEmpty() {
+		minX, minY = bounds.Min(0), bounds.Min(1)
+		maxX, maxY = bounds.Max(0), bounds.Max(1)
+		switch bounds.Layout() {
+		case geom.XYM:
+			minM, maxM = bounds.Min(2), bounds.Max(2)
+		case geom.XYZM:
+			minZ, maxZ = bounds.Min(2), bounds.Max(2)
+			minM, maxM = bounds.Min(3), bounds.Max(3)
+		}
+	}
+	for _, x := range []float64{minX, minY, maxX, maxY, minZ, maxZ, minM, maxM} {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(x))
+	}
+	return data
+}
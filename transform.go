@@ -0,0 +1,689 @@
+package shapefile
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/twpayne/go-geom"
+)
+
+// A Transformer transforms coordinates from one coordinate reference system to
+// another. It supports geographic coordinate reference systems and projected
+// coordinate reference systems using the Transverse Mercator (including UTM),
+// Mercator, Web Mercator (Mercator_Auxiliary_Sphere), Lambert Conformal Conic,
+// and Albers projections. Coordinates are transformed between datums with
+// seven-parameter Helmert transformations through WGS 84, using the datums'
+// TOWGS84 parameters or, for common datums, built-in parameters. Heights are
+// not transformed. A Transformer is safe for concurrent use.
+type Transformer struct {
+	src        *transformCRS
+	dst        *transformCRS
+	datumShift bool
+}
+
+// A transformCRS is a coordinate reference system prepared for transforming
+// coordinates. Angles are in radians and distances are in meters, except for
+// falseEasting and falseNorthing which are in the linear unit.
+type transformCRS struct {
+	datumName       string
+	ellipsoid       *transformEllipsoid
+	toWGS84         *transformHelmert // nil if unknown.
+	primeMeridian   float64
+	angularUnit     float64
+	projection      *transformProjection // nil if geographic.
+	linearUnit      float64
+	centralMeridian float64
+	falseEasting    float64
+	falseNorthing   float64
+}
+
+// A transformEllipsoid is a reference ellipsoid.
+type transformEllipsoid struct {
+	a  float64 // Semi-major axis.
+	f  float64 // Flattening.
+	e2 float64 // Eccentricity squared.
+	e  float64 // Eccentricity.
+}
+
+// A transformHelmert is a seven-parameter Helmert transformation between
+// geocentric coordinates, using the position vector convention. Rotations are
+// in radians and the scale is a factor.
+type transformHelmert struct {
+	tx, ty, tz float64
+	rx, ry, rz float64
+	s          float64
+}
+
+// A transformProjection is a map projection. forward projects a longitude
+// relative to the central meridian and a latitude, in radians, to easting and
+// northing, in meters and without any false easting or false northing.
+// inverse is the inverse of forward.
+type transformProjection struct {
+	forward func(lambda, phi float64) (float64, float64)
+	inverse func(x, y float64) (float64, float64)
+}
+
+// NewTransformer returns a new Transformer that transforms coordinates from
+// src to dst. src and dst must have been parsed.
+func NewTransformer(src, dst *PRJ) (*Transformer, error) {
+	srcCRS, err := newTransformCRS(src)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	dstCRS, err := newTransformCRS(dst)
+	if err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+	transformer := &Transformer{
+		src: srcCRS,
+		dst: dstCRS,
+	}
+	if !srcCRS.sameDatum(dstCRS) {
+		for _, crs := range []*transformCRS{srcCRS, dstCRS} {
+			if crs.toWGS84 == nil {
+				return nil, fmt.Errorf("%s: unknown datum transformation to WGS 84", crs.datumName)
+			}
+		}
+		transformer.datumShift = true
+	}
+	return transformer, nil
+}
+
+// Transform returns (x, y) transformed. For geographic coordinate reference
+// systems x is the longitude and y is the latitude.
+func (t *Transformer) Transform(x, y float64) (float64, float64, error) {
+	lambda, phi := t.src.inverse(x, y)
+	if t.datumShift {
+		gx, gy, gz := t.src.ellipsoid.geocentric(lambda, phi)
+		gx, gy, gz = t.src.toWGS84.forward(gx, gy, gz)
+		gx, gy, gz = t.dst.toWGS84.inverse(gx, gy, gz)
+		lambda, phi = t.dst.ellipsoid.geodetic(gx, gy, gz)
+	}
+	transformedX, transformedY := t.dst.forward(lambda, phi)
+	if math.IsNaN(transformedX) || math.IsInf(transformedX, 0) || math.IsNaN(transformedY) || math.IsInf(transformedY, 0) {
+		return 0, 0, fmt.Errorf("(%g, %g): coordinate out of range", x, y)
+	}
+	return transformedX, transformedY, nil
+}
+
+// TransformGeom transforms the X and Y coordinates of g in place. Z and M
+// coordinates are not changed. If an error is returned then g may have been
+// partially transformed.
+func (t *Transformer) TransformGeom(g geom.T) error {
+	if geometryCollection, ok := g.(*geom.GeometryCollection); ok {
+		for _, g := range geometryCollection.Geoms() {
+			if err := t.TransformGeom(g); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	flatCoords := g.FlatCoords()
+	stride := g.Stride()
+	for i := 0; i+1 < len(flatCoords); i += stride {
+		x, y, err := t.Transform(flatCoords[i], flatCoords[i+1])
+		if err != nil {
+			return err
+		}
+		flatCoords[i], flatCoords[i+1] = x, y
+	}
+	return nil
+}
+
+// transformBoundsSegments is the number of segments that each edge of a
+// bounding rectangle is divided into when transforming bounds.
+const transformBoundsSegments = 16
+
+// transform transforms r's geometry with transformer and updates r's X and Y
+// bounds to match. If r has no geometry, for example because it was skipped,
+// then its X and Y bounds are transformed.
+func (r *SHPRecord) transform(transformer *Transformer) error {
+	if r.Geom == nil {
+		bounds, err := transformer.transformBounds(r.Bounds)
+		if err != nil {
+			return err
+		}
+		r.Bounds = bounds
+		return nil
+	}
+	if err := transformer.TransformGeom(r.Geom); err != nil {
+		return err
+	}
+	if r.Bounds == nil || r.Geom.Empty() {
+		return nil
+	}
+	r.Bounds = withXYBounds(r.Bounds, geom.NewBounds(geom.XY).Extend(r.Geom))
+	return nil
+}
+
+// transformBounds returns bounds with its X and Y bounds transformed by t. The
+// transformed bounds are the bounds of points sampled along the edges of the X
+// and Y bounding rectangle, as the rectangle is not, in general, transformed
+// to a rectangle. bounds is not modified.
+func (t *Transformer) transformBounds(bounds *geom.Bounds) (*geom.Bounds, error) {
+	if bounds == nil || bounds.IsEmpty() {
+		return bounds, nil
+	}
+	minX, minY := bounds.Min(0), bounds.Min(1)
+	maxX, maxY := bounds.Max(0), bounds.Max(1)
+	flatCoords := make([]float64, 0, 8*(transformBoundsSegments+1))
+	for i := range transformBoundsSegments + 1 {
+		f := float64(i) / transformBoundsSegments
+		x := minX + f*(maxX-minX)
+		y := minY + f*(maxY-minY)
+		flatCoords = append(flatCoords, x, minY, x, maxY, minX, y, maxX, y)
+	}
+	multiPoint := geom.NewMultiPointFlat(geom.XY, flatCoords)
+	if err := t.TransformGeom(multiPoint); err != nil {
+		return nil, err
+	}
+	return withXYBounds(bounds, geom.NewBounds(geom.XY).Extend(multiPoint)), nil
+}
+
+// transformSHP returns the PRJ and ReadSHPOptions to use when reading a
+// Shapefile whose .prj file is prj. If o.TargetPRJ is not nil then the
+// returned ReadSHPOptions are a copy of o.SHP that transform geometries and
+// bounds from prj to o.TargetPRJ and the returned PRJ is o.TargetPRJ. o is not
+// modified.
+func (o *ReadShapefileOptions) transformSHP(prj *PRJ) (*PRJ, *ReadSHPOptions, error) {
+	switch {
+	case o == nil:
+		return prj, nil, nil
+	case o.TargetPRJ == nil:
+		return prj, o.SHP, nil
+	case o.SHP != nil && o.SHP.Transformer != nil:
+		return nil, nil, errors.New("both TargetPRJ and SHP.Transformer are set")
+	case prj == nil:
+		return nil, nil, errors.New("no .prj file")
+	}
+	transformer, err := NewTransformer(prj, o.TargetPRJ)
+	if err != nil {
+		return nil, nil, err
+	}
+	var options ReadSHPOptions
+	if o.SHP != nil {
+		options = *o.SHP
+	}
+	options.Transformer = transformer
+	return o.TargetPRJ, &options, nil
+}
+
+// transformSHxHeader transforms header's bounds with o.Transformer, if o and
+// header are not nil and o.Transformer is not nil.
+func (o *ReadSHPOptions) transformSHxHeader(header *SHxHeader) error {
+	if o == nil || o.Transformer == nil || header == nil {
+		return nil
+	}
+	bounds, err := o.Transformer.transformBounds(header.Bounds)
+	if err != nil {
+		return err
+	}
+	header.Bounds = bounds
+	return nil
+}
+
+// newTransformCRS returns a new transformCRS for prj.
+func newTransformCRS(prj *PRJ) (*transformCRS, error) {
+	if prj == nil {
+		return nil, errors.New("no coordinate reference system")
+	}
+	geogCS := prj.geogCS()
+	switch {
+	case geogCS == nil:
+		return nil, errors.New("no coordinate reference system")
+	case geogCS.Datum == nil:
+		return nil, errors.New("GEOGCS: missing DATUM")
+	}
+	ellipsoid, err := newTransformEllipsoid(geogCS.Datum.Spheroid)
+	if err != nil {
+		return nil, err
+	}
+	toWGS84, err := newTransformHelmertToWGS84(geogCS.Datum)
+	if err != nil {
+		return nil, err
+	}
+	angularUnit := math.Pi / 180
+	if geogCS.AngularUnit != nil {
+		angularUnit = geogCS.AngularUnit.ConversionFactor
+	}
+	if !(angularUnit > 0) {
+		return nil, fmt.Errorf("%g: invalid angular unit", angularUnit)
+	}
+	var primeMeridian float64
+	if geogCS.PrimeMeridian != nil {
+		primeMeridian = geogCS.PrimeMeridian.Longitude * angularUnit
+	}
+	crs := &transformCRS{
+		datumName:     geogCS.Datum.Name,
+		ellipsoid:     ellipsoid,
+		toWGS84:       toWGS84,
+		primeMeridian: primeMeridian,
+		angularUnit:   angularUnit,
+	}
+	if prj.ProjCS == nil {
+		return crs, nil
+	}
+
+	crs.linearUnit = 1
+	if prj.ProjCS.LinearUnit != nil {
+		crs.linearUnit = prj.ProjCS.LinearUnit.ConversionFactor
+	}
+	if !(crs.linearUnit > 0) {
+		return nil, fmt.Errorf("%g: invalid linear unit", crs.linearUnit)
+	}
+	values := epsgParameterValues(prj.ProjCS.Parameters)
+	parameter := func(name string, defaultValue float64) float64 {
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return defaultValue
+	}
+	crs.centralMeridian = parameter("centralmeridian", 0) * angularUnit
+	crs.falseEasting = parameter("falseeasting", 0)
+	crs.falseNorthing = parameter("falsenorthing", 0)
+	phi0 := parameter("latitudeoforigin", 0) * angularUnit
+	phi1 := parameter("standardparallel1", phi0/angularUnit) * angularUnit
+	phi2 := parameter("standardparallel2", phi1/angularUnit) * angularUnit
+	k0 := parameter("scalefactor", 1)
+	switch normalizeEPSGProjection(prj.ProjCS.Projection) {
+	case "albers":
+		crs.projection, err = newAlbersProjection(ellipsoid, phi0, phi1, phi2)
+	case "lambertconformalconic":
+		crs.projection, err = newLambertConformalConicProjection(ellipsoid, phi0, phi1, phi2, k0)
+	case "mercator":
+		if _, ok := values["standardparallel1"]; ok {
+			k0 *= ellipsoid.m(phi1)
+		}
+		crs.projection = newMercatorProjection(ellipsoid, k0)
+	case "mercatorauxiliarysphere":
+		if auxiliarySphereType := parameter("auxiliaryspheretype", 0); auxiliarySphereType != 0 {
+			return nil, fmt.Errorf("%g: unsupported auxiliary sphere type", auxiliarySphereType)
+		}
+		crs.projection = newWebMercatorProjection(ellipsoid.a)
+	case "transversemercator":
+		crs.projection = newTransverseMercatorProjection(ellipsoid, phi0, k0)
+	default:
+		return nil, fmt.Errorf("%s: unsupported projection", prj.ProjCS.Projection)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return crs, nil
+}
+
+// inverse returns the longitude, relative to Greenwich, and latitude of (x,
+// y), in radians.
+func (c *transformCRS) inverse(x, y float64) (float64, float64) {
+	if c.projection == nil {
+		phi := y * c.angularUnit
+		if math.Abs(phi) > math.Pi/2*(1+1e-12) {
+			return math.NaN(), math.NaN()
+		}
+		return normalizeTransformLongitude(x*c.angularUnit + c.primeMeridian), phi
+	}
+	lambda, phi := c.projection.inverse((x-c.falseEasting)*c.linearUnit, (y-c.falseNorthing)*c.linearUnit)
+	return normalizeTransformLongitude(lambda + c.centralMeridian + c.primeMeridian), phi
+}
+
+// forward returns the coordinates of the longitude, relative to Greenwich,
+// and latitude, in radians.
+func (c *transformCRS) forward(lambda, phi float64) (float64, float64) {
+	if c.projection == nil {
+		return normalizeTransformLongitude(lambda-c.primeMeridian) / c.angularUnit, phi / c.angularUnit
+	}
+	x, y := c.projection.forward(normalizeTransformLongitude(lambda-c.primeMeridian-c.centralMeridian), phi)
+	return c.falseEasting + x/c.linearUnit, c.falseNorthing + y/c.linearUnit
+}
+
+// sameDatum returns if c and other have the same datum, so coordinates can be
+// transformed between them without a datum transformation.
+func (c *transformCRS) sameDatum(other *transformCRS) bool {
+	if !epsgValuesEqual(c.ellipsoid.a, other.ellipsoid.a) || !epsgValuesEqual(c.ellipsoid.f, other.ellipsoid.f) {
+		return false
+	}
+	if normalizeEPSGDatumName(c.datumName) == normalizeEPSGDatumName(other.datumName) {
+		return true
+	}
+	return c.toWGS84 != nil && other.toWGS84 != nil && *c.toWGS84 == *other.toWGS84
+}
+
+// newTransformEllipsoid returns a new transformEllipsoid for spheroid.
+func newTransformEllipsoid(spheroid *PRJSpheroid) (*transformEllipsoid, error) {
+	if spheroid == nil {
+		return nil, errors.New("DATUM: missing SPHEROID")
+	}
+	if !(spheroid.SemiMajorAxis > 0) {
+		return nil, fmt.Errorf("%g: invalid semi-major axis", spheroid.SemiMajorAxis)
+	}
+	var f float64
+	if spheroid.InverseFlattening != 0 {
+		f = 1 / spheroid.InverseFlattening
+	}
+	if !(0 <= f && f < 1) {
+		return nil, fmt.Errorf("%g: invalid inverse flattening", spheroid.InverseFlattening)
+	}
+	e2 := f * (2 - f)
+	return &transformEllipsoid{
+		a:  spheroid.SemiMajorAxis,
+		f:  f,
+		e2: e2,
+		e:  math.Sqrt(e2),
+	}, nil
+}
+
+// geocentric returns the geocentric coordinates of the point on the surface of
+// e with longitude lambda and latitude phi.
+func (e *transformEllipsoid) geocentric(lambda, phi float64) (float64, float64, float64) {
+	sinPhi, cosPhi := math.Sincos(phi)
+	sinLambda, cosLambda := math.Sincos(lambda)
+	n := e.a / math.Sqrt(1-e.e2*sinPhi*sinPhi)
+	return n * cosPhi * cosLambda, n * cosPhi * sinLambda, n * (1 - e.e2) * sinPhi
+}
+
+// geodetic returns the longitude and latitude of the geocentric coordinates
+// (x, y, z) on e.
+func (e *transformEllipsoid) geodetic(x, y, z float64) (float64, float64) {
+	p := math.Hypot(x, y)
+	phi := math.Atan2(z, p*(1-e.e2))
+	for range 16 {
+		sinPhi := math.Sin(phi)
+		n := e.a / math.Sqrt(1-e.e2*sinPhi*sinPhi)
+		nextPhi := math.Atan2(z+e.e2*n*sinPhi, p)
+		if math.Abs(nextPhi-phi) < 1e-15 {
+			return math.Atan2(y, x), nextPhi
+		}
+		phi = nextPhi
+	}
+	return math.Atan2(y, x), phi
+}
+
+// isometricLatitude returns the isometric latitude of phi on e.
+func (e *transformEllipsoid) isometricLatitude(phi float64) float64 {
+	sinPhi := math.Sin(phi)
+	return math.Atanh(sinPhi) - e.e*math.Atanh(e.e*sinPhi)
+}
+
+// latitude returns the latitude on e whose isometric latitude is psi.
+func (e *transformEllipsoid) latitude(psi float64) float64 {
+	phi := math.Atan(math.Sinh(psi))
+	for range 16 {
+		nextPhi := math.Atan(math.Sinh(psi + e.e*math.Atanh(e.e*math.Sin(phi))))
+		if math.Abs(nextPhi-phi) < 1e-15 {
+			return nextPhi
+		}
+		phi = nextPhi
+	}
+	return phi
+}
+
+// m returns the radius of the parallel at phi on e, divided by the semi-major
+// axis.
+func (e *transformEllipsoid) m(phi float64) float64 {
+	sinPhi, cosPhi := math.Sincos(phi)
+	return cosPhi / math.Sqrt(1-e.e2*sinPhi*sinPhi)
+}
+
+// q returns the authalic function of phi on e.
+func (e *transformEllipsoid) q(phi float64) float64 {
+	sinPhi := math.Sin(phi)
+	if e.e == 0 {
+		return 2 * sinPhi
+	}
+	return (1 - e.e2) * (sinPhi/(1-e.e2*sinPhi*sinPhi) + math.Atanh(e.e*sinPhi)/e.e)
+}
+
+// newTransformHelmertToWGS84 returns the transformation from datum to WGS 84,
+// from datum's TOWGS84 parameters or the built-in parameters for common
+// datums, or nil if it is unknown.
+func newTransformHelmertToWGS84(datum *PRJDatum) (*transformHelmert, error) {
+	if len(datum.ToWGS84) != 0 {
+		return newTransformHelmert(datum.ToWGS84)
+	}
+	datumName := normalizeEPSGDatumName(datum.Name)
+	for _, geogCS := range epsgGeogCSs {
+		if slices.ContainsFunc(geogCS.datumNames, func(name string) bool {
+			return normalizeEPSGDatumName(name) == datumName
+		}) {
+			return newTransformHelmert(geogCS.toWGS84)
+		}
+	}
+	return nil, nil
+}
+
+// newTransformHelmert returns a new transformHelmert from TOWGS84 parameters:
+// translations in meters, rotations in arc-seconds, and scale in parts per
+// million.
+func newTransformHelmert(parameters []float64) (*transformHelmert, error) {
+	switch len(parameters) {
+	case 3:
+		parameters = append(slices.Clone(parameters), 0, 0, 0, 0)
+	case 7:
+	default:
+		return nil, fmt.Errorf("%d: invalid number of TOWGS84 parameters", len(parameters))
+	}
+	arcSecond := math.Pi / (180 * 3600)
+	return &transformHelmert{
+		tx: parameters[0],
+		ty: parameters[1],
+		tz: parameters[2],
+		rx: parameters[3] * arcSecond,
+		ry: parameters[4] * arcSecond,
+		rz: parameters[5] * arcSecond,
+		s:  1 + parameters[6]*1e-6,
+	}, nil
+}
+
+// forward returns the geocentric coordinates (x, y, z) transformed by h.
+func (h *transformHelmert) forward(x, y, z float64) (float64, float64, float64) {
+	return h.tx + h.s*(x-h.rz*y+h.ry*z),
+		h.ty + h.s*(h.rz*x+y-h.rx*z),
+		h.tz + h.s*(-h.ry*x+h.rx*y+z)
+}
+
+// inverse returns the geocentric coordinates (x, y, z) transformed by the
+// inverse of h.
+func (h *transformHelmert) inverse(x, y, z float64) (float64, float64, float64) {
+	// The rotation matrix is I + K, where K is the cross product with r =
+	// (rx, ry, rz). Its inverse is (I - K + r r^T) / (1 + |r|^2).
+	x = (x - h.tx) / h.s
+	y = (y - h.ty) / h.s
+	z = (z - h.tz) / h.s
+	dot := h.rx*x + h.ry*y + h.rz*z
+	norm := 1 + h.rx*h.rx + h.ry*h.ry + h.rz*h.rz
+	return (x - (h.ry*z - h.rz*y) + h.rx*dot) / norm,
+		(y - (h.rz*x - h.rx*z) + h.ry*dot) / norm,
+		(z - (h.rx*y - h.ry*x) + h.rz*dot) / norm
+}
+
+// newAlbersProjection returns a new Albers Equal Area Conic projection on e
+// with latitude of origin phi0 and standard parallels phi1 and phi2.
+//
+// See Snyder, Map Projections: A Working Manual, pages 101-102.
+func newAlbersProjection(e *transformEllipsoid, phi0, phi1, phi2 float64) (*transformProjection, error) {
+	m1, m2 := e.m(phi1), e.m(phi2)
+	q1, q2 := e.q(phi1), e.q(phi2)
+	n := math.Sin(phi1)
+	if math.Abs(phi1-phi2) > 1e-10 {
+		n = (m1*m1 - m2*m2) / (q2 - q1)
+	}
+	if math.Abs(n) < 1e-10 {
+		return nil, errors.New("invalid standard parallels")
+	}
+	c := m1*m1 + n*q1
+	rho := func(phi float64) float64 {
+		return e.a * math.Sqrt(c-n*e.q(phi)) / n
+	}
+	rho0 := rho(phi0)
+	qp := e.q(math.Pi / 2)
+	return &transformProjection{
+		forward: func(lambda, phi float64) (float64, float64) {
+			theta := n * lambda
+			rho := rho(phi)
+			return rho * math.Sin(theta), rho0 - rho*math.Cos(theta)
+		},
+		inverse: func(x, y float64) (float64, float64) {
+			dy := rho0 - y
+			if n < 0 {
+				x, dy = -x, -dy
+			}
+			rho := math.Hypot(x, dy)
+			q := (c - rho*rho*n*n/(e.a*e.a)) / n
+			lambda := math.Atan2(x, dy) / n
+			switch {
+			case math.Abs(q) >= qp:
+				return lambda, math.Copysign(math.Pi/2, q)
+			case e.e == 0:
+				return lambda, math.Asin(q / 2)
+			}
+			phi := math.Asin(q / 2)
+			for range 16 {
+				sinPhi, cosPhi := math.Sincos(phi)
+				w := 1 - e.e2*sinPhi*sinPhi
+				deltaPhi := w * w / (2 * cosPhi) * (q/(1-e.e2) - sinPhi/w - math.Atanh(e.e*sinPhi)/e.e)
+				phi += deltaPhi
+				if math.Abs(deltaPhi) < 1e-15 {
+					break
+				}
+			}
+			return lambda, phi
+		},
+	}, nil
+}
+
+// newLambertConformalConicProjection returns a new Lambert Conformal Conic
+// projection on e with latitude of origin phi0, standard parallels phi1 and
+// phi2, and scale factor k0.
+//
+// See Snyder, Map Projections: A Working Manual, pages 107-109.
+func newLambertConformalConicProjection(e *transformEllipsoid, phi0, phi1, phi2, k0 float64) (*transformProjection, error) {
+	psi1, psi2 := e.isometricLatitude(phi1), e.isometricLatitude(phi2)
+	n := math.Sin(phi1)
+	if math.Abs(phi1-phi2) > 1e-10 {
+		n = (math.Log(e.m(phi1)) - math.Log(e.m(phi2))) / (psi2 - psi1)
+	}
+	if math.Abs(n) < 1e-10 {
+		return nil, errors.New("invalid standard parallels")
+	}
+	af := k0 * e.a * e.m(phi1) / n * math.Exp(n*psi1)
+	rho := func(phi float64) float64 {
+		return af * math.Exp(-n*e.isometricLatitude(phi))
+	}
+	rho0 := rho(phi0)
+	return &transformProjection{
+		forward: func(lambda, phi float64) (float64, float64) {
+			theta := n * lambda
+			rho := rho(phi)
+			return rho * math.Sin(theta), rho0 - rho*math.Cos(theta)
+		},
+		inverse: func(x, y float64) (float64, float64) {
+			dy := rho0 - y
+			if n < 0 {
+				x, dy = -x, -dy
+			}
+			rho := math.Hypot(x, dy)
+			lambda := math.Atan2(x, dy) / n
+			if rho == 0 {
+				return lambda, math.Copysign(math.Pi/2, n)
+			}
+			return lambda, e.latitude(-math.Log(rho/math.Abs(af)) / n)
+		},
+	}, nil
+}
+
+// newMercatorProjection returns a new Mercator projection on e with scale
+// factor k0.
+func newMercatorProjection(e *transformEllipsoid, k0 float64) *transformProjection {
+	ka := k0 * e.a
+	return &transformProjection{
+		forward: func(lambda, phi float64) (float64, float64) {
+			return ka * lambda, ka * e.isometricLatitude(phi)
+		},
+		inverse: func(x, y float64) (float64, float64) {
+			return x / ka, e.latitude(y / ka)
+		},
+	}
+}
+
+// newTransverseMercatorProjection returns a new Transverse Mercator
+// projection on e with latitude of origin phi0 and scale factor k0. It uses
+// Krüger's series to third order in the third flattening, which is accurate to
+// better than a millimeter within 3,000 km of the central meridian. The
+// latitude is computed exactly from the conformal latitude.
+//
+// See https://en.wikipedia.org/wiki/Universal_Transverse_Mercator_coordinate_system.
+func newTransverseMercatorProjection(e *transformEllipsoid, phi0, k0 float64) *transformProjection {
+	n := e.f / (2 - e.f)
+	n2, n3 := n*n, n*n*n
+	ka := k0 * e.a / (1 + n) * (1 + n2/4 + n2*n2/64)
+	alpha := [3]float64{n/2 - 2*n2/3 + 5*n3/16, 13*n2/48 - 3*n3/5, 61 * n3 / 240}
+	beta := [3]float64{n/2 - 2*n2/3 + 37*n3/96, n2/48 + n3/15, 17 * n3 / 480}
+	project := func(lambda, phi float64) (float64, float64) {
+		t := math.Sinh(e.isometricLatitude(phi))
+		xiPrime := math.Atan2(t, math.Cos(lambda))
+		etaPrime := math.Atanh(math.Sin(lambda) / math.Sqrt(1+t*t))
+		xi, eta := xiPrime, etaPrime
+		for j, alpha := range alpha {
+			k := float64(2 * (j + 1))
+			xi += alpha * math.Sin(k*xiPrime) * math.Cosh(k*etaPrime)
+			eta += alpha * math.Cos(k*xiPrime) * math.Sinh(k*etaPrime)
+		}
+		return ka * eta, ka * xi
+	}
+	_, y0 := project(0, phi0)
+	return &transformProjection{
+		forward: func(lambda, phi float64) (float64, float64) {
+			x, y := project(lambda, phi)
+			return x, y - y0
+		},
+		inverse: func(x, y float64) (float64, float64) {
+			xi, eta := (y+y0)/ka, x/ka
+			xiPrime, etaPrime := xi, eta
+			for j, beta := range beta {
+				k := float64(2 * (j + 1))
+				xiPrime -= beta * math.Sin(k*xi) * math.Cosh(k*eta)
+				etaPrime -= beta * math.Cos(k*xi) * math.Sinh(k*eta)
+			}
+			chi := math.Asin(math.Sin(xiPrime) / math.Cosh(etaPrime))
+			return math.Atan2(math.Sinh(etaPrime), math.Cos(xiPrime)), e.latitude(math.Atanh(math.Sin(chi)))
+		},
+	}
+}
+
+// newWebMercatorProjection returns a new spherical Mercator projection on a
+// sphere with radius a, as used by Web Mercator.
+func newWebMercatorProjection(a float64) *transformProjection {
+	return &transformProjection{
+		forward: func(lambda, phi float64) (float64, float64) {
+			return a * lambda, a * math.Atanh(math.Sin(phi))
+		},
+		inverse: func(x, y float64) (float64, float64) {
+			return x / a, math.Atan(math.Sinh(y / a))
+		},
+	}
+}
+
+// normalizeTransformLongitude returns lambda normalized to the range -pi to
+// pi. Longitudes within rounding error of -pi or pi are not changed.
+func normalizeTransformLongitude(lambda float64) float64 {
+	if math.Abs(lambda) > math.Pi+1e-12 {
+		lambda = math.Remainder(lambda, 2*math.Pi)
+	}
+	return lambda
+}
+
+// withXYBounds returns a copy of bounds with its X and Y bounds replaced by
+// xyBounds.
+func withXYBounds(bounds, xyBounds *geom.Bounds) *geom.Bounds {
+	layout := bounds.Layout()
+	stride := layout.Stride()
+	args := make([]float64, 2*stride)
+	for i := range stride {
+		args[i] = bounds.Min(i)
+		args[stride+i] = bounds.Max(i)
+	}
+	args[0], args[1] = xyBounds.Min(0), xyBounds.Min(1)
+	args[stride], args[stride+1] = xyBounds.Max(0), xyBounds.Max(1)
+	return geom.NewBounds(layout).Set(args...)
+}
@@ -0,0 +1,432 @@
+package shapefile
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-geom"
+)
+
+const testNAD27GeogCS = `GEOGCS["GCS_North_American_1927",DATUM["D_North_American_1927",SPHEROID["Clarke_1866",6378206.4,294.9786982]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+
+func TestTransformer(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		src       *PRJ
+		dst       *PRJ
+		x         float64
+		y         float64
+		expectedX float64
+		expectedY float64
+		tolerance float64
+	}{
+		{
+			name:      "wgs84_web_mercator",
+			src:       newTestPRJFromEPSG(t, 4326),
+			dst:       newTestPRJFromEPSG(t, 3857),
+			x:         10,
+			y:         50,
+			expectedX: 1113194.9079327357,
+			expectedY: 6446275.841017158,
+			tolerance: 1e-6,
+		},
+		{
+			name:      "wgs84_web_mercator_antimeridian",
+			src:       newTestPRJFromEPSG(t, 4326),
+			dst:       newTestPRJFromEPSG(t, 3857),
+			x:         180,
+			y:         0,
+			expectedX: 20037508.342789244,
+			expectedY: 0,
+			tolerance: 1e-6,
+		},
+		{
+			name:      "wgs84_utm_32n",
+			src:       newTestPRJFromEPSG(t, 4326),
+			dst:       newTestPRJFromEPSG(t, 32632),
+			x:         9,
+			y:         45,
+			expectedX: 500000,
+			expectedY: 4982950.400,
+			tolerance: 1e-3,
+		},
+		{
+			// See the worked example in Ordnance Survey, A guide to
+			// coordinate systems in Great Britain, annex C.
+			name:      "osgb36_british_national_grid",
+			src:       newTestPRJFromEPSG(t, 4277),
+			dst:       newTestPRJFromEPSG(t, 27700),
+			x:         1 + 43.0/60 + 4.5177/3600,
+			y:         52 + 39.0/60 + 27.2531/3600,
+			expectedX: 651409.903,
+			expectedY: 313177.270,
+			tolerance: 1e-3,
+		},
+		{
+			// See Snyder, Map Projections: A Working Manual, page 296.
+			name:      "lambert_conformal_conic",
+			src:       newTestPRJ(t, testNAD27GeogCS),
+			dst:       newTestPRJ(t, `PROJCS["Snyder_LCC",`+testNAD27GeogCS+`,PROJECTION["Lambert_Conformal_Conic"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",-96.0],PARAMETER["Standard_Parallel_1",33.0],PARAMETER["Standard_Parallel_2",45.0],PARAMETER["Latitude_Of_Origin",23.0],UNIT["Meter",1.0]]`),
+			x:         -75,
+			y:         35,
+			expectedX: 1894410.9,
+			expectedY: 1564649.5,
+			tolerance: 0.1,
+		},
+		{
+			// See Snyder, Map Projections: A Working Manual, page 292.
+			name:      "albers",
+			src:       newTestPRJ(t, testNAD27GeogCS),
+			dst:       newTestPRJ(t, `PROJCS["Snyder_Albers",`+testNAD27GeogCS+`,PROJECTION["Albers"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",-96.0],PARAMETER["Standard_Parallel_1",29.5],PARAMETER["Standard_Parallel_2",45.5],PARAMETER["Latitude_Of_Origin",23.0],UNIT["Meter",1.0]]`),
+			x:         -75,
+			y:         35,
+			expectedX: 1885472.7,
+			expectedY: 1535925.0,
+			tolerance: 0.1,
+		},
+		{
+			// See the worked example in Ordnance Survey, A guide to
+			// coordinate systems in Great Britain, annex C, whose ETRS89
+			// coordinates are transformed with OSTN15. The Helmert
+			// transformation is accurate to about five meters.
+			name:      "wgs84_osgb36",
+			src:       newTestPRJFromEPSG(t, 4326),
+			dst:       newTestPRJFromEPSG(t, 4277),
+			x:         1 + 42.0/60 + 57.8663/3600,
+			y:         52 + 39.0/60 + 28.8282/3600,
+			expectedX: 1 + 43.0/60 + 4.5177/3600,
+			expectedY: 52 + 39.0/60 + 27.2531/3600,
+			tolerance: 1e-4,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			transformer, err := NewTransformer(tc.src, tc.dst)
+			assert.NoError(t, err)
+			x, y, err := transformer.Transform(tc.x, tc.y)
+			assert.NoError(t, err)
+			assertTransformedCoord(t, tc.expectedX, tc.expectedY, x, y, tc.tolerance)
+
+			inverseTransformer, err := NewTransformer(tc.dst, tc.src)
+			assert.NoError(t, err)
+			inverseX, inverseY, err := inverseTransformer.Transform(x, y)
+			assert.NoError(t, err)
+			assertTransformedCoord(t, tc.x, tc.y, inverseX, inverseY, 1e-7)
+		})
+	}
+}
+
+func TestTransformerRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  *PRJ
+		dst  *PRJ
+		x    float64
+		y    float64
+	}{
+		{
+			name: "wgs84_utm_56s",
+			src:  newTestPRJFromEPSG(t, 4326),
+			dst:  newTestPRJFromEPSG(t, 32756),
+			x:    151.2,
+			y:    -33.9,
+		},
+		{
+			name: "wgs84_gda94_mga_55",
+			src:  newTestPRJFromEPSG(t, 4326),
+			dst:  newTestPRJFromEPSG(t, 28355),
+			x:    145,
+			y:    -37.8,
+		},
+		{
+			name: "web_mercator_dhdn_gauss_kruger",
+			src:  newTestPRJFromEPSG(t, 3857),
+			dst:  newTestPRJFromEPSG(t, 31467),
+			x:    1113194.9,
+			y:    6446275.8,
+		},
+		{
+			name: "ed50_utm_31n_lcc_europe",
+			src:  newTestPRJFromEPSG(t, 23031),
+			dst:  newTestPRJFromEPSG(t, 3034),
+			x:    448251,
+			y:    5411932,
+		},
+		{
+			name: "nad27_conus_albers",
+			src:  newTestPRJFromEPSG(t, 4267),
+			dst:  newTestPRJFromEPSG(t, 5070),
+			x:    -105,
+			y:    40,
+		},
+		{
+			name: "wgs84_lcc_southern",
+			src:  newTestPRJFromEPSG(t, 4326),
+			dst:  newTestPRJ(t, `PROJCS["Southern_LCC",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic"],PARAMETER["False_Easting",1000000.0],PARAMETER["False_Northing",1000000.0],PARAMETER["Central_Meridian",135.0],PARAMETER["Standard_Parallel_1",-18.0],PARAMETER["Standard_Parallel_2",-36.0],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`),
+			x:    140,
+			y:    -25,
+		},
+		{
+			name: "wgs84_world_mercator_feet",
+			src:  newTestPRJFromEPSG(t, 4326),
+			dst:  newTestPRJ(t, `PROJCS["World_Mercator_Feet",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Mercator"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",0.0],PARAMETER["Standard_Parallel_1",0.0],UNIT["Foot",0.3048]]`),
+			x:    -70,
+			y:    60,
+		},
+		{
+			name: "paris_prime_meridian",
+			src:  newTestPRJ(t, `GEOGCS["NTF (Paris)",DATUM["Nouvelle_Triangulation_Francaise_Paris",SPHEROID["Clarke 1880 (IGN)",6378249.2,293.4660212936269],TOWGS84[-168,-60,320,0,0,0,0]],PRIMEM["Paris",2.5969213],UNIT["grad",0.01570796326794897]]`),
+			dst:  newTestPRJFromEPSG(t, 4326),
+			x:    0,
+			y:    54,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			transformer, err := NewTransformer(tc.src, tc.dst)
+			assert.NoError(t, err)
+			x, y, err := transformer.Transform(tc.x, tc.y)
+			assert.NoError(t, err)
+			assert.False(t, x == tc.x && y == tc.y)
+
+			inverseTransformer, err := NewTransformer(tc.dst, tc.src)
+			assert.NoError(t, err)
+			inverseX, inverseY, err := inverseTransformer.Transform(x, y)
+			assert.NoError(t, err)
+			// Heights are not transformed, so round trips through datum
+			// transformations are only accurate to about a centimeter.
+			tolerance := 1e-2
+			if tc.src.IsGeographic() {
+				tolerance = 1e-7
+			}
+			assertTransformedCoord(t, tc.x, tc.y, inverseX, inverseY, tolerance)
+		})
+	}
+}
+
+func TestTransformerPrimeMeridian(t *testing.T) {
+	parisGeogCS := `GEOGCS["NTF (Paris)",DATUM["Nouvelle_Triangulation_Francaise_Paris",SPHEROID["Clarke 1880 (IGN)",6378249.2,293.4660212936269]],PRIMEM["Paris",2.5969213],UNIT["grad",0.01570796326794897]]`
+	greenwichGeogCS := `GEOGCS["NTF",DATUM["Nouvelle_Triangulation_Francaise_Paris",SPHEROID["Clarke 1880 (IGN)",6378249.2,293.4660212936269]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]]`
+	transformer, err := NewTransformer(newTestPRJ(t, parisGeogCS), newTestPRJ(t, greenwichGeogCS))
+	assert.NoError(t, err)
+	x, y, err := transformer.Transform(0, 50)
+	assert.NoError(t, err)
+	assertTransformedCoord(t, 2.3372291, 45, x, y, 1e-7)
+}
+
+func TestNewTransformerErrors(t *testing.T) {
+	unknownDatumGeogCS := `GEOGCS["Unknown",DATUM["D_Unknown",SPHEROID["Unknown",6378000.0,300.0]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+	for _, tc := range []struct {
+		name             string
+		src              *PRJ
+		dst              *PRJ
+		expectedErrorMsg string
+	}{
+		{
+			name:             "nil",
+			dst:              newTestPRJFromEPSG(t, 4326),
+			expectedErrorMsg: "source: no coordinate reference system",
+		},
+		{
+			name:             "unparsed",
+			src:              newTestPRJFromEPSG(t, 4326),
+			dst:              &PRJ{Projection: "invalid"},
+			expectedErrorMsg: "target: no coordinate reference system",
+		},
+		{
+			name:             "unsupported_projection",
+			src:              newTestPRJFromEPSG(t, 3035),
+			dst:              newTestPRJFromEPSG(t, 4326),
+			expectedErrorMsg: "source: Lambert_Azimuthal_Equal_Area: unsupported projection",
+		},
+		{
+			name:             "unknown_datum",
+			src:              newTestPRJ(t, unknownDatumGeogCS),
+			dst:              newTestPRJFromEPSG(t, 4326),
+			expectedErrorMsg: "D_Unknown: unknown datum transformation to WGS 84",
+		},
+		{
+			name:             "invalid_towgs84",
+			src:              newTestPRJ(t, `GEOGCS["Unknown",DATUM["D_Unknown",SPHEROID["Unknown",6378000.0,300.0],TOWGS84[1,2]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`),
+			dst:              newTestPRJFromEPSG(t, 4326),
+			expectedErrorMsg: "source: 2: invalid number of TOWGS84 parameters",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTransformer(tc.src, tc.dst)
+			assert.EqualError(t, err, tc.expectedErrorMsg)
+		})
+	}
+
+	// The same unknown datum does not need a datum transformation.
+	_, err := NewTransformer(newTestPRJ(t, unknownDatumGeogCS), newTestPRJ(t, unknownDatumGeogCS))
+	assert.NoError(t, err)
+}
+
+func TestTransformerOutOfRange(t *testing.T) {
+	transformer, err := NewTransformer(newTestPRJFromEPSG(t, 4326), newTestPRJFromEPSG(t, 3857))
+	assert.NoError(t, err)
+	_, _, err = transformer.Transform(0, 90)
+	assert.EqualError(t, err, "(0, 90): coordinate out of range")
+	_, _, err = transformer.Transform(0, 91)
+	assert.EqualError(t, err, "(0, 91): coordinate out of range")
+}
+
+func TestTransformerTransformGeom(t *testing.T) {
+	transformer, err := NewTransformer(newTestPRJFromEPSG(t, 4326), newTestPRJFromEPSG(t, 3857))
+	assert.NoError(t, err)
+	g := geom.NewGeometryCollection().MustPush(
+		geom.NewPointFlat(geom.XYZ, []float64{10, 50, 100}),
+		geom.NewLineStringFlat(geom.XYM, []float64{0, 0, 1, 180, 0, 2}),
+	)
+	assert.NoError(t, transformer.TransformGeom(g))
+	point, ok := g.Geom(0).(*geom.Point)
+	assert.True(t, ok)
+	assertTransformedCoord(t, 1113194.9079327357, 6446275.841017158, point.X(), point.Y(), 1e-6)
+	assert.Equal(t, 100, point.Z())
+	lineString, ok := g.Geom(1).(*geom.LineString)
+	assert.True(t, ok)
+	assertTransformedCoord(t, 0, 0, lineString.Coord(0).X(), lineString.Coord(0).Y(), 1e-6)
+	assertTransformedCoord(t, 20037508.342789244, 0, lineString.Coord(1).X(), lineString.Coord(1).Y(), 1e-6)
+	assert.Equal(t, []float64{1, 2}, []float64{lineString.FlatCoords()[2], lineString.FlatCoords()[5]})
+}
+
+func TestReadTargetPRJ(t *testing.T) {
+	targetPRJ := newTestPRJFromEPSG(t, 4326)
+	basename := filepath.Join("testdata", "poly")
+
+	expected, err := Read(basename, nil)
+	assert.NoError(t, err)
+	transformer, err := NewTransformer(expected.PRJ, targetPRJ)
+	assert.NoError(t, err)
+	for _, record := range expected.SHP.Records {
+		assert.NoError(t, record.transform(transformer))
+	}
+
+	options := &ReadShapefileOptions{
+		SHP:       &ReadSHPOptions{},
+		TargetPRJ: targetPRJ,
+	}
+	actual, err := Read(basename, options)
+	assert.NoError(t, err)
+	assert.Zero(t, options.SHP.Transformer)
+	assert.Equal(t, targetPRJ, actual.PRJ)
+	expectedHeaderBounds, err := transformer.transformBounds(expected.SHP.Bounds)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedHeaderBounds, actual.SHP.Bounds)
+	assert.Equal(t, expectedHeaderBounds, actual.SHX.Bounds)
+	assert.Equal(t, len(expected.SHP.Records), len(actual.SHP.Records))
+	for i, record := range actual.SHP.Records {
+		assert.Equal(t, expected.SHP.Records[i].Geom.FlatCoords(), record.Geom.FlatCoords())
+		assert.Equal(t, expected.SHP.Records[i].Bounds, record.Bounds)
+		assert.True(t, record.Bounds.Min(0) >= -180 && record.Bounds.Max(0) <= 180)
+		assert.True(t, record.Bounds.Min(1) >= -90 && record.Bounds.Max(1) <= 90)
+	}
+
+	reader, err := Open(basename, &ReadShapefileOptions{
+		TargetPRJ: targetPRJ,
+	})
+	assert.NoError(t, err)
+	defer reader.Close()
+	assert.Equal(t, targetPRJ, reader.PRJ())
+	assert.Equal(t, expectedHeaderBounds, reader.SHPHeader().Bounds)
+	assert.Equal(t, expectedHeaderBounds, reader.SHxHeader().Bounds)
+	record, err := reader.SHPRecord(0)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.SHP.Records[0].Geom.FlatCoords(), record.Geom.FlatCoords())
+	assert.Equal(t, expected.SHP.Records[0].Bounds, record.Bounds)
+
+	scanner, err := NewScannerFromBasename(basename, &ReadShapefileOptions{
+		TargetPRJ: targetPRJ,
+	})
+	assert.NoError(t, err)
+	scanned, err := ReadScanner(scanner)
+	assert.NoError(t, err)
+	assert.Equal(t, targetPRJ, scanned.PRJ)
+	assert.Equal(t, expectedHeaderBounds, scanned.SHP.Bounds)
+	assert.Equal(t, expectedHeaderBounds, scanned.SHX.Bounds)
+	assert.Equal(t, expected.SHP.Records[0].Geom.FlatCoords(), scanned.SHP.Records[0].Geom.FlatCoords())
+
+	_, err = Read(filepath.Join("testdata", "polygon_hole"), &ReadShapefileOptions{
+		TargetPRJ: targetPRJ,
+	})
+	assert.EqualError(t, err, "testdata/polygon_hole.prj: no .prj file")
+
+	_, err = Read(basename, &ReadShapefileOptions{
+		SHP: &ReadSHPOptions{
+			Transformer: transformer,
+		},
+		TargetPRJ: targetPRJ,
+	})
+	assert.EqualError(t, err, "testdata/poly.prj: both TargetPRJ and SHP.Transformer are set")
+}
+
+func TestReadSHPTransformerSkippedRecords(t *testing.T) {
+	expected, err := Read(filepath.Join("testdata", "poly"), nil)
+	assert.NoError(t, err)
+	transformer, err := NewTransformer(expected.PRJ, newTestPRJFromEPSG(t, 4326))
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join("testdata", "poly.shp"))
+	assert.NoError(t, err)
+	shp, err := ReadSHP(bytes.NewReader(data), int64(len(data)), &ReadSHPOptions{
+		Bounds:      expected.SHP.Records[0].Bounds,
+		Transformer: transformer,
+	})
+	assert.NoError(t, err)
+	var skipped int
+	for i, record := range shp.Records {
+		if record.Geom != nil {
+			continue
+		}
+		skipped++
+		expectedBounds, err := transformer.transformBounds(expected.SHP.Records[i].Bounds)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedBounds, record.Bounds)
+	}
+	assert.NotZero(t, skipped)
+}
+
+func TestTransformerTransformBounds(t *testing.T) {
+	expected, err := Read(filepath.Join("testdata", "poly"), nil)
+	assert.NoError(t, err)
+	transformer, err := NewTransformer(expected.PRJ, newTestPRJFromEPSG(t, 4326))
+	assert.NoError(t, err)
+
+	bounds, err := transformer.transformBounds(expected.SHP.Bounds)
+	assert.NoError(t, err)
+	assert.Equal(t, geom.XY, bounds.Layout())
+	for _, record := range expected.SHP.Records {
+		assert.NoError(t, record.transform(transformer))
+		assert.True(t, bounds.Min(0) <= record.Bounds.Min(0) && record.Bounds.Max(0) <= bounds.Max(0))
+		assert.True(t, bounds.Min(1) <= record.Bounds.Min(1) && record.Bounds.Max(1) <= bounds.Max(1))
+	}
+
+	emptyBounds := geom.NewBounds(geom.XYZM)
+	transformedEmptyBounds, err := transformer.transformBounds(emptyBounds)
+	assert.NoError(t, err)
+	assert.Equal(t, emptyBounds, transformedEmptyBounds)
+}
+
+func assertTransformedCoord(t *testing.T, expectedX, expectedY, actualX, actualY, tolerance float64) {
+	t.Helper()
+	if math.Abs(actualX-expectedX) > tolerance || math.Abs(actualY-expectedY) > tolerance {
+		t.Errorf("expected (%.10g, %.10g), got (%.10g, %.10g)", expectedX, expectedY, actualX, actualY)
+	}
+}
+
+func newTestPRJ(t *testing.T, projection string) *PRJ {
+	t.Helper()
+	prj, err := ParsePRJ(projection)
+	assert.NoError(t, err)
+	return prj
+}
+
+func newTestPRJFromEPSG(t *testing.T, code int) *PRJ {
+	t.Helper()
+	prj, err := PRJFromEPSG(code)
+	assert.NoError(t, err)
+	return prj
+}
@@ -0,0 +1,245 @@
+package shapefile
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/twpayne/go-geom"
+)
+
+// UnmarshalRecord unmarshals the ith record into dst, which must be a pointer
+// to a struct.
+//
+// Struct fields are matched to DBF fields by the name in their `dbf:"..."`
+// tag, or, if they do not have a name in their tag, by their name, ignoring
+// case. Struct fields with the tag `dbf:"-"` are ignored. Integer and floating
+// point values are converted to the type of the struct field, strings are
+// unmarshalled into struct fields that implement encoding.TextUnmarshaler, and
+// null values set pointer struct fields to nil and other struct fields to
+// their zero value. If the record is deleted then dst is not modified.
+func (d *DBF) UnmarshalRecord(i int, dst any) error {
+	return unmarshalRecord(d.FieldDescriptors, d.Records[i], dst)
+}
+
+// UnmarshalRecord unmarshals s's ith record's fields into dst. See
+// DBF.UnmarshalRecord.
+func (s *Shapefile) UnmarshalRecord(i int, dst any) error {
+	if s.DBF == nil {
+		return errors.New("no .dbf file")
+	}
+	return s.DBF.UnmarshalRecord(i, dst)
+}
+
+// UnmarshalRecord unmarshals the fields of the last record returned by Scan
+// into dst. See DBF.UnmarshalRecord.
+func (s *Scanner) UnmarshalRecord(dst any) error {
+	if s.scanDBF == nil {
+		return errors.New("no .dbf file")
+	}
+	return unmarshalRecord(s.scanDBF.fieldDescriptors, s.recordDBF, dst)
+}
+
+// RecordsOf returns an iterator over the records in s, with their fields
+// unmarshalled into values of type T, which must be a struct type. See
+// DBF.UnmarshalRecord. Records whose .dbf values are nil, because they are
+// deleted or were skipped when reading, are skipped. If s has no .dbf file
+// then every record is yielded with a zero T. If a record cannot be
+// unmarshalled then iteration stops and errFunc, if not nil, is called with
+// the error.
+func RecordsOf[T any](s *Shapefile, errFunc func(error)) iter.Seq2[T, geom.T] {
+	if errFunc == nil {
+		errFunc = func(error) {}
+	}
+	return func(yield func(T, geom.T) bool) {
+		var fieldDescriptors []*DBFFieldDescriptor
+		if s.DBF != nil {
+			fieldDescriptors = s.DBF.FieldDescriptors
+		}
+		structFieldIndexes, err := dbfStructFieldIndexes(reflect.TypeFor[T](), fieldDescriptors)
+		if err != nil {
+			errFunc(err)
+			return
+		}
+		for i := range s.NumRecords() {
+			var value T
+			if s.DBF != nil {
+				if s.DBF.Records[i] == nil {
+					continue
+				}
+				if err := unmarshalStructFields(structFieldIndexes, fieldDescriptors, s.DBF.Records[i], reflect.ValueOf(&value).Elem()); err != nil {
+					errFunc(fmt.Errorf("record %d: %w", i, err))
+					return
+				}
+			}
+			var g geom.T
+			if s.SHP != nil {
+				g = s.SHP.Record(i)
+			}
+			if !yield(value, g) {
+				return
+			}
+		}
+	}
+}
+
+// unmarshalRecord unmarshals record, described by fieldDescriptors, into
+// dst.
+func unmarshalRecord(fieldDescriptors []*DBFFieldDescriptor, record []any, dst any) error {
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Pointer || dstValue.IsNil() {
+		return fmt.Errorf("%T: not a non-nil pointer", dst)
+	}
+	structValue := dstValue.Elem()
+	structFieldIndexes, err := dbfStructFieldIndexes(structValue.Type(), fieldDescriptors)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return nil
+	}
+	return unmarshalStructFields(structFieldIndexes, fieldDescriptors, record, structValue)
+}
+
+// dbfStructFieldIndexes returns the index of the struct field of structType
+// that each of fieldDescriptors is unmarshalled into, or -1 if there is no
+// such field.
+func dbfStructFieldIndexes(structType reflect.Type, fieldDescriptors []*DBFFieldDescriptor) ([]int, error) {
+	structFields, err := dbfStructFields(structType)
+	if err != nil {
+		return nil, err
+	}
+	structFieldIndexes := make([]int, len(fieldDescriptors))
+	for i, fieldDescriptor := range fieldDescriptors {
+		structFieldIndexes[i] = -1
+		for _, structField := range structFields {
+			if structField.tagged && structField.name == fieldDescriptor.Name ||
+				!structField.tagged && strings.EqualFold(structField.name, fieldDescriptor.Name) {
+				structFieldIndexes[i] = structField.index
+				break
+			}
+		}
+	}
+	return structFieldIndexes, nil
+}
+
+// unmarshalStructFields unmarshals record into the fields of structValue.
+func unmarshalStructFields(structFieldIndexes []int, fieldDescriptors []*DBFFieldDescriptor, record []any, structValue reflect.Value) error {
+	for i, structFieldIndex := range structFieldIndexes {
+		if structFieldIndex == -1 {
+			continue
+		}
+		if err := unmarshalValue(record[i], structValue.Field(structFieldIndex)); err != nil {
+			return fmt.Errorf("field %s: %w", fieldDescriptors[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// unmarshalValue sets dst to value, converting it if needed.
+func unmarshalValue(value any, dst reflect.Value) error {
+	if value == nil {
+		dst.SetZero()
+		return nil
+	}
+
+	if dst.Kind() == reflect.Pointer {
+		elem := reflect.New(dst.Type().Elem())
+		if err := unmarshalValue(value, elem.Elem()); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	}
+
+	valueValue := reflect.ValueOf(value)
+	if valueValue.Type().AssignableTo(dst.Type()) {
+		dst.Set(valueValue)
+		return nil
+	}
+
+	if dst.Type() == dbfCurrencyType {
+		switch valueValue.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dst.SetInt(10000 * valueValue.Int())
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dst.SetInt(int64(math.Round(10000 * valueValue.Float())))
+			return nil
+		}
+	}
+
+	if currency, ok := value.(DBFCurrency); ok {
+		switch dst.Kind() {
+		case reflect.Float32, reflect.Float64:
+			value = float64(currency) / 10000
+		default:
+			value = currency.String()
+		}
+		valueValue = reflect.ValueOf(value)
+	}
+
+	if valueValue.Kind() == reflect.String && dst.CanAddr() {
+		if textUnmarshaler, ok := dst.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return textUnmarshaler.UnmarshalText([]byte(valueValue.String()))
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch valueValue.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if i := valueValue.Int(); !dst.OverflowInt(i) {
+				dst.SetInt(i)
+				return nil
+			}
+			return fmt.Errorf("%v: overflows %s", value, dst.Type())
+		case reflect.Float32, reflect.Float64:
+			if f := valueValue.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && !dst.OverflowInt(int64(f)) {
+				dst.SetInt(int64(f))
+				return nil
+			}
+			return fmt.Errorf("%v: cannot convert to %s", value, dst.Type())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch valueValue.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if i := valueValue.Int(); i >= 0 && !dst.OverflowUint(uint64(i)) {
+				dst.SetUint(uint64(i))
+				return nil
+			}
+			return fmt.Errorf("%v: overflows %s", value, dst.Type())
+		case reflect.Float32, reflect.Float64:
+			if f := valueValue.Float(); f == math.Trunc(f) && f >= 0 && f < math.MaxUint64 && !dst.OverflowUint(uint64(f)) {
+				dst.SetUint(uint64(f))
+				return nil
+			}
+			return fmt.Errorf("%v: cannot convert to %s", value, dst.Type())
+		}
+	case reflect.Float32, reflect.Float64:
+		switch valueValue.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dst.SetFloat(float64(valueValue.Int()))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dst.SetFloat(valueValue.Float())
+			return nil
+		}
+	case reflect.String:
+		if valueValue.Kind() == reflect.String {
+			dst.SetString(valueValue.String())
+			return nil
+		}
+	case reflect.Bool:
+		if valueValue.Kind() == reflect.Bool {
+			dst.SetBool(valueValue.Bool())
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot unmarshal %T into %s", value, dst.Type())
+}
@@ -0,0 +1,287 @@
+package shapefile
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-geom"
+)
+
+type testPRFEDEA int
+
+func (p *testPRFEDEA) UnmarshalText(text []byte) error {
+	value, err := strconv.Atoi(string(text))
+	if err != nil {
+		return err
+	}
+	*p = testPRFEDEA(value)
+	return nil
+}
+
+type testPoly struct {
+	Area    float64
+	EasID   int `dbf:"EAS_ID"`
+	PRFEDEA testPRFEDEA
+	Ignored string `dbf:"-"`
+}
+
+func TestUnmarshalRecord(t *testing.T) {
+	shapefile, err := Read(filepath.Join("testdata", "poly"), nil)
+	assert.NoError(t, err)
+
+	var poly testPoly
+	assert.NoError(t, shapefile.UnmarshalRecord(0, &poly))
+	assert.Equal(t, testPoly{Area: 215229.266, EasID: 168, PRFEDEA: 35043411}, poly)
+
+	scanner, err := NewScannerFromBasename(filepath.Join("testdata", "poly"), nil)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, scanner.Close())
+	}()
+	for i := range shapefile.NumRecords() {
+		scanner.Scan()
+		assert.NoError(t, scanner.Error())
+		var expected, actual testPoly
+		assert.NoError(t, shapefile.UnmarshalRecord(i, &expected))
+		assert.NoError(t, scanner.UnmarshalRecord(&actual))
+		assert.Equal(t, expected, actual)
+	}
+
+	i := 0
+	for poly, g := range RecordsOf[testPoly](shapefile, func(err error) {
+		t.Fatal(err)
+	}) {
+		var expected testPoly
+		assert.NoError(t, shapefile.UnmarshalRecord(i, &expected))
+		assert.Equal(t, expected, poly)
+		assert.Equal(t, shapefile.SHP.Record(i), g)
+		i++
+	}
+	assert.Equal(t, shapefile.NumRecords(), i)
+}
+
+func TestDBFUnmarshalRecord(t *testing.T) {
+	dbf := &DBF{
+		FieldDescriptors: []*DBFFieldDescriptor{
+			{Name: "NAME", Type: 'C', Length: 10},
+			{Name: "COUNT", Type: 'N', Length: 5},
+			{Name: "PRICE", Type: 'Y', Length: 8},
+			{Name: "DATE", Type: 'D', Length: 8},
+			{Name: "VALID", Type: 'L', Length: 1},
+		},
+		Records: [][]any{
+			{"one", 1, DBFCurrency(12345), time.Date(2024, time.February, 3, 0, 0, 0, 0, time.UTC), true},
+			{"two", nil, nil, nil, nil},
+			nil,
+			{"three", 1.5, nil, nil, nil},
+			{"four", -1, nil, nil, nil},
+		},
+	}
+
+	type record struct {
+		Name  string
+		Count *int
+		Price float64
+		Date  time.Time
+		Valid *bool
+	}
+
+	for _, tc := range []struct {
+		name        string
+		index       int
+		dst         any
+		expected    any
+		expectedErr string
+	}{
+		{
+			name:  "values",
+			index: 0,
+			dst:   &record{},
+			expected: &record{
+				Name:  "one",
+				Count: ptr(1),
+				Price: 1.2345,
+				Date:  time.Date(2024, time.February, 3, 0, 0, 0, 0, time.UTC),
+				Valid: ptr(true),
+			},
+		},
+		{
+			name:  "nulls",
+			index: 1,
+			dst: &record{
+				Count: ptr(2),
+				Price: 3,
+				Valid: ptr(false),
+			},
+			expected: &record{
+				Name: "two",
+			},
+		},
+		{
+			name:  "deleted",
+			index: 2,
+			dst: &record{
+				Name: "unchanged",
+			},
+			expected: &record{
+				Name: "unchanged",
+			},
+		},
+		{
+			name:  "incompatible_type",
+			index: 0,
+			dst: &struct {
+				Count []byte
+			}{},
+			expectedErr: "field COUNT: cannot unmarshal int into []uint8",
+		},
+		{
+			name:  "int_to_float_and_currency_to_string",
+			index: 0,
+			dst: &struct {
+				Count float32
+				Price string
+			}{},
+			expected: &struct {
+				Count float32
+				Price string
+			}{
+				Count: 1,
+				Price: "1.2345",
+			},
+		},
+		{
+			name:  "non_integral_float_to_int",
+			index: 3,
+			dst: &struct {
+				Count int
+			}{},
+			expectedErr: "field COUNT: 1.5: cannot convert to int",
+		},
+		{
+			name:  "negative_to_uint",
+			index: 4,
+			dst: &struct {
+				Count uint
+			}{},
+			expectedErr: "field COUNT: -1: overflows uint",
+		},
+		{
+			name:        "not_a_pointer",
+			index:       0,
+			dst:         record{},
+			expectedErr: "shapefile.record: not a non-nil pointer",
+		},
+		{
+			name:        "not_a_struct",
+			index:       0,
+			dst:         ptr(0),
+			expectedErr: "int: not a struct",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := dbf.UnmarshalRecord(tc.index, tc.dst)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, tc.dst)
+		})
+	}
+}
+
+func TestRecordsOfError(t *testing.T) {
+	shapefile := &Shapefile{
+		DBF: &DBF{
+			FieldDescriptors: []*DBFFieldDescriptor{
+				{Name: "NAME", Type: 'C', Length: 10},
+			},
+			Records: [][]any{
+				{"one"},
+			},
+		},
+	}
+	var err error
+	for range RecordsOf[struct{ Name testPRFEDEA }](shapefile, func(e error) {
+		err = e
+	}) {
+		t.Fatal("unexpected record")
+	}
+	var numError *strconv.NumError
+	assert.True(t, errors.As(err, &numError))
+	assert.Contains(t, err.Error(), "record 0: ")
+}
+
+func TestRecordsOfDeleted(t *testing.T) {
+	shapefile := &Shapefile{
+		DBF: &DBF{
+			FieldDescriptors: []*DBFFieldDescriptor{
+				{Name: "NAME", Type: 'C', Length: 10},
+			},
+			Records: [][]any{
+				{"one"},
+				nil,
+				{"three"},
+			},
+		},
+	}
+	var names []string
+	for record := range RecordsOf[struct{ Name string }](shapefile, func(err error) {
+		t.Fatal(err)
+	}) {
+		names = append(names, record.Name)
+	}
+	assert.Equal(t, []string{"one", "three"}, names)
+}
+
+func TestRecordsOfNilErrFunc(t *testing.T) {
+	shapefile := &Shapefile{
+		DBF: &DBF{
+			FieldDescriptors: []*DBFFieldDescriptor{
+				{Name: "NAME", Type: 'C', Length: 10},
+			},
+			Records: [][]any{
+				{"one"},
+			},
+		},
+	}
+	for range RecordsOf[struct{ Name testPRFEDEA }](shapefile, nil) {
+		t.Fatal("unexpected record")
+	}
+	for range RecordsOf[int](shapefile, nil) {
+		t.Fatal("unexpected record")
+	}
+}
+
+func TestRecordsOfNotAStruct(t *testing.T) {
+	shapefile, err := Read(filepath.Join("testdata", "poly"), nil)
+	assert.NoError(t, err)
+	for range RecordsOf[int](shapefile, func(e error) {
+		err = e
+	}) {
+		t.Fatal("unexpected record")
+	}
+	assert.EqualError(t, err, "int: not a struct")
+}
+
+func TestRecordsOfWithoutDBF(t *testing.T) {
+	shapefile, err := Read(filepath.Join("testdata", "point"), nil)
+	assert.NoError(t, err)
+	var geoms []geom.T
+	for record, g := range RecordsOf[struct{ Name string }](shapefile, func(err error) {
+		t.Fatal(err)
+	}) {
+		assert.Zero(t, record)
+		geoms = append(geoms, g)
+	}
+	assert.Equal(t, []geom.T{shapefile.SHP.Record(0)}, geoms)
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,217 @@
+package shapefile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/twpayne/go-geom"
+)
+
+// defaultCharset is the charset used when no charset is specified.
+const defaultCharset = "ISO-8859-1"
+
+// WriteShapefileOptions are options to NewWriter.
+type WriteShapefileOptions struct {
+	ShapeType        ShapeType
+	FieldDescriptors []*DBFFieldDescriptor
+	DBF              *WriteDBFOptions
+	PRJ              *PRJ
+}
+
+// A Writer writes a Shapefile record by record, writing the .shp, .shx, and
+// .dbf files in lockstep.
+type Writer struct {
+	shpWriter        *SHPWriter
+	dbfWriter        *DBFWriter
+	fieldDescriptors []*DBFFieldDescriptor
+	fieldIndexes     map[string]int
+	closers          []io.Closer
+	records          int
+	err              error
+}
+
+// NewWriterFromBasename creates the files of a Shapefile with basename and
+// returns a new Writer that writes to them. The .prj file is only created if
+// options.PRJ is not nil.
+func NewWriterFromBasename(basename string, options *WriteShapefileOptions) (*Writer, error) {
+	if options == nil {
+		options = &WriteShapefileOptions{}
+	}
+
+	exts := []string{".shp", ".shx", ".dbf", ".cpg"}
+	if options.PRJ != nil {
+		exts = append(exts, ".prj")
+	}
+
+	writers := make(map[string]io.WriteSeeker)
+	var closers []io.Closer
+	for _, ext := range exts {
+		file, err := os.Create(basename + ext)
+		if err != nil {
+			for _, closer := range closers {
+				closer.Close()
+			}
+			return nil, fmt.Errorf("%s%s: %w", basename, ext, err)
+		}
+		writers[ext] = file
+		closers = append(closers, file)
+	}
+
+	writer, err := NewWriter(writers, options)
+	if err != nil {
+		for _, closer := range closers {
+			closer.Close()
+		}
+		return nil, err
+	}
+	writer.closers = closers
+	return writer, nil
+}
+
+// NewWriter returns a new Writer that writes to writers, which are indexed by
+// extension. Any of the .shp, .shx, .dbf, .cpg, and .prj writers may be
+// omitted.
+func NewWriter(writers map[string]io.WriteSeeker, options *WriteShapefileOptions) (*Writer, error) {
+	if options == nil {
+		options = &WriteShapefileOptions{}
+	}
+
+	if writer, ok := writers[".cpg"]; ok {
+		charset := defaultCharset
+		if options.DBF != nil && options.DBF.Charset != "" {
+			charset = options.DBF.Charset
+		}
+		if err := WriteCPG(writer, &CPG{Charset: charset}); err != nil {
+			return nil, fmt.Errorf("WriteCPG: %w", err)
+		}
+	}
+
+	if writer, ok := writers[".prj"]; ok && options.PRJ != nil {
+		if err := WritePRJ(writer, options.PRJ); err != nil {
+			return nil, fmt.Errorf("WritePRJ: %w", err)
+		}
+	}
+
+	var shpWriter *SHPWriter
+	if writer, ok := writers[".shp"]; ok {
+		var err error
+		shpWriter, err = NewSHPWriter(writer, writers[".shx"], options.ShapeType)
+		if err != nil {
+			return nil, fmt.Errorf("NewSHPWriter: %w", err)
+		}
+	} else if _, ok := writers[".shx"]; ok {
+		return nil, errors.New("can't write .shx file without .shp file")
+	}
+
+	var dbfWriter *DBFWriter
+	if writer, ok := writers[".dbf"]; ok {
+		var err error
+		dbfWriter, err = NewDBFWriter(writer, options.FieldDescriptors, options.DBF)
+		if err != nil {
+			return nil, fmt.Errorf("NewDBFWriter: %w", err)
+		}
+	}
+
+	fieldIndexes := make(map[string]int, len(options.FieldDescriptors))
+	for i, fieldDescriptor := range options.FieldDescriptors {
+		fieldIndexes[fieldDescriptor.Name] = i
+	}
+
+	return &Writer{
+		shpWriter:        shpWriter,
+		dbfWriter:        dbfWriter,
+		fieldDescriptors: options.FieldDescriptors,
+		fieldIndexes:     fieldIndexes,
+	}, nil
+}
+
+// Write writes a record with fields and g. Fields missing from fields are
+// written as null values. If the record cannot be encoded, for example because
+// a value or g is invalid, then an error is returned, nothing is written, and
+// the Writer can still be used. If an error occurs while writing then all
+// subsequent calls to Write return the same error.
+func (w *Writer) Write(fields map[string]any, g geom.T) error {
+	if w.err != nil {
+		return w.err
+	}
+	shpData, dbfData, err := w.encode(fields, g)
+	if err != nil {
+		return err
+	}
+	if err := w.writeEncoded(g, shpData, dbfData); err != nil {
+		w.err = err
+		return err
+	}
+	w.records++
+	return nil
+}
+
+// encode returns the encoded .shp record and .dbf record with fields and g.
+// Both are encoded before either is written, so an invalid record does not
+// leave the files out of step.
+func (w *Writer) encode(fields map[string]any, g geom.T) ([]byte, []byte, error) {
+	var dbfData []byte
+	if w.dbfWriter != nil {
+		record := make([]any, len(w.fieldDescriptors))
+		for name, value := range fields {
+			i, ok := w.fieldIndexes[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("record %d: %s: unknown field", w.records+1, name)
+			}
+			record[i] = value
+		}
+		var err error
+		dbfData, err = w.dbfWriter.encode(record)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	var shpData []byte
+	if w.shpWriter != nil {
+		var err error
+		shpData, err = w.shpWriter.encode(g)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return shpData, dbfData, nil
+}
+
+// writeEncoded writes the encoded .shp record shpData of g and the encoded
+// .dbf record dbfData.
+func (w *Writer) writeEncoded(g geom.T, shpData, dbfData []byte) error {
+	if w.shpWriter != nil {
+		if err := w.shpWriter.writeEncoded(g, shpData); err != nil {
+			return err
+		}
+	}
+	if w.dbfWriter != nil {
+		if err := w.dbfWriter.writeEncoded(dbfData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WrittenRecords returns the number of records written.
+func (w *Writer) WrittenRecords() int {
+	return w.records
+}
+
+// Close writes the headers and closes any files created by
+// NewWriterFromBasename.
+func (w *Writer) Close() error {
+	var err error
+	if w.shpWriter != nil {
+		err = errors.Join(err, w.shpWriter.Close())
+	}
+	if w.dbfWriter != nil {
+		err = errors.Join(err, w.dbfWriter.Close())
+	}
+	for _, closer := range w.closers {
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package shapefile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-geom"
+)
+
+func TestWriter(t *testing.T) {
+	basename := filepath.Join(t.TempDir(), "test")
+	fieldDescriptors := []*DBFFieldDescriptor{
+		{Name: "NAME", Type: 'C', Length: 16},
+		{Name: "POP", Type: 'N', Length: 9},
+		{Name: "FOUNDED", Type: 'D', Length: 8},
+	}
+	writer, err := NewWriterFromBasename(basename, &WriteShapefileOptions{
+		ShapeType:        ShapeTypePoint,
+		FieldDescriptors: fieldDescriptors,
+		DBF: &WriteDBFOptions{
+			Charset: "UTF-8",
+		},
+		PRJ: &PRJ{
+			Projection: `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`,
+		},
+	})
+	assert.NoError(t, err)
+
+	founded := time.Date(1291, time.August, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, writer.Write(map[string]any{"NAME": "Zürich", "POP": 421878}, newGeomFromWKT(t, "POINT (8.54 47.37)")))
+	assert.NoError(t, writer.Write(map[string]any{"NAME": "Schwyz", "FOUNDED": founded}, newGeomFromWKT(t, "POINT (8.65 47.02)")))
+	assert.NoError(t, writer.Write(nil, nil))
+	assert.EqualError(t, writer.Write(map[string]any{"UNKNOWN": 1}, nil), "record 4: UNKNOWN: unknown field")
+	assert.Equal(t, 3, writer.WrittenRecords())
+	assert.NoError(t, writer.Close())
+
+	shapefile, err := Read(basename, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, shapefile.NumRecords())
+	assert.Equal(t, "utf-8", shapefile.CPG.Charset)
+	assert.True(t, shapefile.PRJ != nil)
+	assert.Equal(t, ShapeTypePoint, shapefile.SHP.ShapeType)
+	assert.Equal(t, geom.NewBounds(geom.XY).Set(8.54, 47.02, 8.65, 47.37), shapefile.SHP.Bounds)
+	assert.Equal(t, fieldDescriptors, shapefile.DBF.FieldDescriptors)
+	assert.Equal(t, 3, len(shapefile.SHX.Records))
+
+	fields, g := shapefile.Record(0)
+	assert.Equal(t, map[string]any{"NAME": "Zürich", "POP": 421878, "FOUNDED": nil}, fields)
+	assert.Equal(t, newGeomFromWKT(t, "POINT (8.54 47.37)"), g)
+
+	fields, g = shapefile.Record(1)
+	assert.Equal(t, map[string]any{"NAME": "Schwyz", "POP": nil, "FOUNDED": founded}, fields)
+	assert.Equal(t, newGeomFromWKT(t, "POINT (8.65 47.02)"), g)
+
+	fields, g = shapefile.Record(2)
+	assert.Equal(t, map[string]any{"NAME": "", "POP": nil, "FOUNDED": nil}, fields)
+	assert.Zero(t, g)
+}
+
+func TestWriterInvalidGeometry(t *testing.T) {
+	basename := filepath.Join(t.TempDir(), "test")
+	writer, err := NewWriterFromBasename(basename, &WriteShapefileOptions{
+		ShapeType: ShapeTypePoint,
+		FieldDescriptors: []*DBFFieldDescriptor{
+			{Name: "NAME", Type: 'C', Length: 16},
+		},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(map[string]any{"NAME": "a"}, newGeomFromWKT(t, "POINT (1 2)")))
+	assert.Error(t, writer.Write(map[string]any{"NAME": "b"}, newGeomFromWKT(t, "LINESTRING (1 2, 3 4)")))
+	assert.NoError(t, writer.Write(map[string]any{"NAME": "c"}, newGeomFromWKT(t, "POINT (3 4)")))
+	assert.Equal(t, 2, writer.WrittenRecords())
+	assert.NoError(t, writer.Close())
+
+	shapefile, err := Read(basename, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(shapefile.DBF.Records))
+	assert.Equal(t, 2, len(shapefile.SHP.Records))
+	assert.Equal(t, 2, len(shapefile.SHX.Records))
+	fields, g := shapefile.Record(1)
+	assert.Equal(t, map[string]any{"NAME": "c"}, fields)
+	assert.Equal(t, newGeomFromWKT(t, "POINT (3 4)"), g)
+}
+
+func TestWriterWriteError(t *testing.T) {
+	dir := t.TempDir()
+	writers := make(map[string]io.WriteSeeker)
+	for _, ext := range []string{".shp", ".shx", ".dbf"} {
+		file, err := os.Create(filepath.Join(dir, "test"+ext))
+		assert.NoError(t, err)
+		defer file.Close()
+		writers[ext] = file
+	}
+	dbfWriter := &failingWriteSeeker{WriteSeeker: writers[".dbf"]}
+	writers[".dbf"] = dbfWriter
+	writer, err := NewWriter(writers, &WriteShapefileOptions{
+		ShapeType: ShapeTypePoint,
+		FieldDescriptors: []*DBFFieldDescriptor{
+			{Name: "NAME", Type: 'C', Length: 16},
+		},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(map[string]any{"NAME": "a"}, newGeomFromWKT(t, "POINT (1 2)")))
+	dbfWriter.err = errors.New("write error")
+	assert.EqualError(t, writer.Write(map[string]any{"NAME": "b"}, newGeomFromWKT(t, "POINT (3 4)")), "write error")
+	dbfWriter.err = nil
+	assert.EqualError(t, writer.Write(map[string]any{"NAME": "c"}, newGeomFromWKT(t, "POINT (5 6)")), "write error")
+	assert.Equal(t, 1, writer.WrittenRecords())
+}
+
+// A failingWriteSeeker is an io.WriteSeeker whose writes fail with err, if err
+// is not nil.
+type failingWriteSeeker struct {
+	io.WriteSeeker
+	err error
+}
+
+func (w *failingWriteSeeker) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.WriteSeeker.Write(p)
+}